@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/events"
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/d-shames3/chirpy/internal/validate"
+)
+
+const adminUsage = `usage:
+  chirpy admin create -email EMAIL [-password PASSWORD] [-role admin|moderator|user]
+  chirpy admin set-role -email EMAIL -role admin|moderator|user
+
+create reads the password from the first line of stdin when -password is
+not given, so it stays out of shell history.`
+
+func runAdmin(ctx context.Context, s store.Store, args []string, stdin io.Reader) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+
+	fs := flag.NewFlagSet("chirpy admin "+args[0], flag.ContinueOnError)
+	email := fs.String("email", "", "email address of the account")
+	var roleName, password string
+	if args[0] == "create" {
+		fs.StringVar(&roleName, "role", string(rbac.RoleAdmin), "role to give the account")
+		fs.StringVar(&password, "password", "", "password for the new account")
+	} else {
+		fs.StringVar(&roleName, "role", "", "role to give the account")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *email == "" || fs.NArg() > 0 {
+		return errors.New(adminUsage)
+	}
+	role, err := rbac.ParseRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		if password == "" {
+			line, err := bufio.NewReader(stdin).ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+		user, err := createAccount(ctx, s, *email, password, role)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created %s %s (%s)\n", role, user.Email, user.ID)
+		return nil
+	case "set-role":
+		user, err := s.Users().GetUser(ctx, *email)
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("no user with email %q", *email)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := s.Users().UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: user.ID, Role: string(role)}); err != nil {
+			return err
+		}
+		fmt.Printf("%s is now %s\n", user.Email, role)
+		return nil
+	default:
+		return errors.New(adminUsage)
+	}
+}
+
+// createAccount applies the same rules as registering through the API, then
+// gives the new user role.
+func createAccount(ctx context.Context, s store.Store, email, password string, role rbac.Role) (database.User, error) {
+	if fields := validate.Struct(userParams{Email: email, Password: password}); len(fields) > 0 {
+		var msgs []string
+		for _, f := range fields {
+			msgs = append(msgs, f.Message)
+		}
+		return database.User{}, errors.New(strings.Join(msgs, "; "))
+	}
+
+	hashedPassword, err := auth.HashPassword(ctx, password)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	var user database.User
+	err = s.WithTx(ctx, func(tx store.Store) error {
+		user, err = tx.Users().CreateUser(ctx, database.CreateUserParams{Email: email, HashedPassword: hashedPassword})
+		if err != nil {
+			return err
+		}
+		user, err = tx.Users().UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: user.ID, Role: string(role)})
+		if err != nil {
+			return err
+		}
+		payload := userCreatedEvent{ID: user.ID, Email: user.Email}
+		return events.Record(ctx, tx.Outbox(), events.AggregateUser, user.ID, events.UserCreated, payload)
+	})
+	if errors.Is(err, store.ErrDuplicate) {
+		return database.User{}, fmt.Errorf("email %q is already registered; use `chirpy admin set-role` to change its role", email)
+	}
+	return user, err
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+var (
+	errMissingToken = apierror.Unauthenticated("missing access token")
+	errNoPermission = apierror.Forbidden("your role does not allow this")
+)
+
+func errInsufficientScope(scope string) *apierror.Error {
+	return apierror.New(http.StatusForbidden, apierror.CodeInsufficientScope,
+		"this token is missing the "+scope+" scope")
+}
+
+// authenticate resolves the caller from the request's credentials: the
+// Authorization header, or else a browser session's access cookie. It
+// returns ok=false and no error when none were sent; credentials that were
+// sent but don't check out are an error.
+func (cfg *apiConfig) authenticate(r *http.Request) (p auth.Principal, ok bool, err error) {
+	var token string
+	if r.Header.Get("Authorization") == "" {
+		token, ok, err = sessionToken(r, accessCookie)
+		if !ok {
+			return auth.Principal{}, false, err
+		}
+	} else {
+		token, err = auth.GetBearerToken(r.Header)
+		if err != nil {
+			return auth.Principal{}, false, apierror.Unauthenticated(err.Error())
+		}
+		if auth.IsPersonalAccessToken(token) {
+			return cfg.authenticatePersonalAccessToken(r, token)
+		}
+	}
+
+	access, err := auth.ParseJWT(token, cfg.serverSecret)
+	if err != nil {
+		return auth.Principal{}, false, errInvalidToken.Wrap(err)
+	}
+	p, err = cfg.principalFor(r, access.UserID)
+	p.Scopes = access.Scopes
+	p.SessionID = access.SessionID
+	return p, err == nil, err
+}
+
+func (cfg *apiConfig) authenticatePersonalAccessToken(r *http.Request, token string) (auth.Principal, bool, error) {
+	pat, err := cfg.store.PersonalAccessTokens().GetPersonalAccessTokenByHash(r.Context(), auth.HashToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return auth.Principal{}, false, errInvalidToken.Wrap(err)
+	}
+	if err != nil {
+		return auth.Principal{}, false, err
+	}
+	if pat.ExpiresAt.Valid && time.Now().UTC().After(pat.ExpiresAt.Time) {
+		return auth.Principal{}, false, errInvalidToken
+	}
+
+	p, err := cfg.principalFor(r, pat.UserID)
+	if err != nil {
+		return auth.Principal{}, false, err
+	}
+	p.Scopes = strings.Fields(pat.Scopes)
+
+	if err := cfg.store.PersonalAccessTokens().TouchPersonalAccessToken(r.Context(), pat.ID); err != nil {
+		slog.WarnContext(r.Context(), "recording personal access token use", "token_id", pat.ID, "error", err)
+	}
+	return p, true, nil
+}
+
+// principalFor builds the principal for a user a credential was issued to.
+func (cfg *apiConfig) principalFor(r *http.Request, userID uuid.UUID) (auth.Principal, error) {
+	// Tokens outlive deleted, banned and suspended accounts, so check the
+	// user is still in good standing.
+	user, err := cfg.store.Users().GetUserByID(r.Context(), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return auth.Principal{}, errInvalidToken.Wrap(err)
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if err := checkStanding(user); err != nil {
+		return auth.Principal{}, err
+	}
+
+	p := auth.Principal{UserID: user.ID, Tier: auth.TierFree, Role: rbac.Role(user.Role)}
+	if user.IsChirpyRed {
+		p.Tier = auth.TierRed
+	}
+	return p, nil
+}
+
+// requireAuth rejects requests without valid credentials granting scope and
+// makes the principal available to next through auth.PrincipalFrom.
+func (cfg *apiConfig) requireAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.withAuth(true, scope, next)
+}
+
+// optionalAuth lets anonymous requests through but still rejects invalid
+// credentials, or ones without scope, so a client with an expired token
+// finds out.
+func (cfg *apiConfig) optionalAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.withAuth(false, scope, next)
+}
+
+// requirePermission is requireAuth plus a check that the caller's role
+// grants perm.
+func (cfg *apiConfig) requirePermission(perm rbac.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.requireAuth(auth.ScopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if !principal(r).Role.Can(perm) {
+			respondWithError(w, r, errNoPermission)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// withAuth authenticates the request behind authPolicy, so clients trying
+// bad tokens are limited by IP before any credentials are looked up.
+func (cfg *apiConfig) withAuth(required bool, scope string, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.rateLimit(authPolicy, func(w http.ResponseWriter, r *http.Request) {
+		p, ok, err := cfg.authenticate(r)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		if !ok {
+			if required {
+				respondWithError(w, r, errMissingToken)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		logUser(r, p.UserID)
+		if !p.HasScope(scope) {
+			respondWithError(w, r, errInsufficientScope(scope))
+			return
+		}
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+}
+
+// principal returns the caller of a handler wrapped in requireAuth.
+func principal(r *http.Request) auth.Principal {
+	p, _ := auth.PrincipalFrom(r.Context())
+	return p
+}
+
+// viewerID returns the caller of a handler wrapped in optionalAuth, if they
+// authenticated.
+func viewerID(r *http.Request) uuid.NullUUID {
+	p, ok := auth.PrincipalFrom(r.Context())
+	return uuid.NullUUID{UUID: p.UserID, Valid: ok}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/google/uuid"
+)
+
+func TestRequireAuth(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := t.Context()
+	user := ts.newUser(t, "user@example.com", rbac.RoleUser)
+	banned := ts.newUser(t, "banned@example.com", rbac.RoleUser)
+	if _, err := ts.store.Moderation().BanUser(ctx, banned.ID); err != nil {
+		t.Fatal(err)
+	}
+	chirp, err := ts.store.Chirps().CreateChirp(ctx, database.CreateChirpParams{UserID: user.ID, Body: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pat, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.store.PersonalAccessTokens().CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID:    user.ID,
+		Name:      "reader",
+		TokenHash: auth.HashToken(pat),
+		Scopes:    auth.ScopeChirpsRead,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownPAT, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSecret, err := auth.MakeJWT(user.ID, "another-secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := auth.MakeJWT(user.ID, testSecret, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		{
+			name:       "missingToken",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   apierror.CodeUnauthenticated,
+		},
+		{
+			name:       "malformedJWT",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			token:      "not-a-jwt",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   apierror.CodeUnauthenticated,
+		},
+		{
+			name:       "jwtSignedWithAnotherSecret",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			token:      otherSecret,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   apierror.CodeUnauthenticated,
+		},
+		{
+			name:       "expiredJWT",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			token:      expired,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   apierror.CodeUnauthenticated,
+		},
+		{
+			name:       "jwt",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			token:      ts.token(t, user.ID),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "jwtMissingScope",
+			method:     http.MethodPut,
+			path:       "/api/users",
+			token:      ts.token(t, user.ID, auth.ScopeChirpsRead),
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeInsufficientScope,
+		},
+		{
+			name:       "jwtForDeletedUser",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			token:      ts.token(t, uuid.New()),
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   apierror.CodeUnauthenticated,
+		},
+		{
+			name:       "jwtForBannedUser",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			token:      ts.token(t, banned.ID),
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeAccountBanned,
+		},
+		{
+			name:       "personalAccessToken",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			token:      pat,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "personalAccessTokenMissingScope",
+			method:     http.MethodPost,
+			path:       "/api/chirps",
+			token:      pat,
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeInsufficientScope,
+		},
+		{
+			name:       "personalAccessTokenManagingTokens",
+			method:     http.MethodGet,
+			path:       "/api/users/me/tokens",
+			token:      pat,
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeInsufficientScope,
+		},
+		{
+			name:       "unknownPersonalAccessToken",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			token:      unknownPAT,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   apierror.CodeUnauthenticated,
+		},
+		{
+			name:       "optionalAuthAnonymous",
+			method:     http.MethodGet,
+			path:       "/api/chirps/" + chirp.ID.String(),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "optionalAuthInvalidToken",
+			method:     http.MethodGet,
+			path:       "/api/chirps/" + chirp.ID.String(),
+			token:      "not-a-jwt",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   apierror.CodeUnauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(t, tt.method, tt.path, tt.token, "")
+			if tt.wantCode == "" {
+				checkStatus(t, w, tt.wantStatus)
+				return
+			}
+			checkProblem(t, w, tt.wantStatus, tt.wantCode)
+		})
+	}
+
+	t.Run("personalAccessTokenUseRecorded", func(t *testing.T) {
+		pats, err := ts.store.PersonalAccessTokens().ListPersonalAccessTokens(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pats) != 1 || !pats[0].LastUsedAt.Valid {
+			t.Errorf("tokens = %+v, want one with last_used_at set", pats)
+		}
+	})
+}
+
+func TestRequirePermission(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.newUser(t, "user@example.com", rbac.RoleUser)
+	moderator := ts.newUser(t, "moderator@example.com", rbac.RoleModerator)
+	admin := ts.newUser(t, "admin@example.com", rbac.RoleAdmin)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		{
+			name:       "userDenied",
+			path:       "/admin/reports",
+			token:      ts.token(t, user.ID),
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeForbidden,
+		},
+		{
+			name:       "moderatorAllowed",
+			path:       "/admin/reports",
+			token:      ts.token(t, moderator.ID),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "moderatorDeniedAdminPermission",
+			path:       "/admin/jobs",
+			token:      ts.token(t, moderator.ID),
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeForbidden,
+		},
+		{
+			name:       "adminWithoutAdminScope",
+			path:       "/admin/reports",
+			token:      ts.token(t, admin.ID, auth.ScopeChirpsRead),
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeInsufficientScope,
+		},
+		{
+			name:       "anonymous",
+			path:       "/admin/reports",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   apierror.CodeUnauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(t, http.MethodGet, tt.path, tt.token, "")
+			if tt.wantCode == "" {
+				checkStatus(t, w, tt.wantStatus)
+				return
+			}
+			checkProblem(t, w, tt.wantStatus, tt.wantCode)
+		})
+	}
+}
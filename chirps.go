@@ -1,20 +1,25 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/chirptext"
+	"github.com/d-shames3/chirpy/internal/contentfilter"
 	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/events"
+	"github.com/d-shames3/chirpy/internal/spam"
+	"github.com/d-shames3/chirpy/internal/store"
 	"github.com/google/uuid"
 )
 
 type chirp struct {
-	Body string `json:"body"`
+	Body string `json:"body" validate:"required"`
 }
 
 type chirpResponse struct {
@@ -25,49 +30,55 @@ type chirpResponse struct {
 	UserID    uuid.UUID `json:"user_id"`
 }
 
-const (
-	bleep    = "****"
-	maxChars = 140
+type chirpDeletedEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+var (
+	errMalformedChirpID = apierror.BadRequest("chirp id is not in UUID format")
+	errChirpNotFound    = apierror.NotFound("chirp not found")
 )
 
 func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
 	chirpIdPath := r.PathValue("chirpId")
 	if chirpIdPath == "" {
-		respondWithError(w, http.StatusBadRequest, "no chirp id provided")
+		respondWithError(w, r, apierror.BadRequest("no chirp id provided"))
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
-		return
-	}
+	userId := principal(r).UserID
 
-	userId, err := auth.ValidateJWT(token, cfg.serverSecret)
+	chirpId, err := uuid.Parse(chirpIdPath)
 	if err != nil {
-		respondWithError(w, http.StatusForbidden, err.Error())
+		respondWithError(w, r, errMalformedChirpID)
 		return
 	}
 
-	chirpId, err := uuid.Parse(chirpIdPath)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "malformed chirp id")
+	chirp, err := cfg.store.Chirps().GetChirp(r.Context(), chirpId)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errChirpNotFound.Wrap(err))
 		return
 	}
-
-	chirp, err := cfg.db.GetChirp(r.Context(), chirpId)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
 	if userId != chirp.UserID {
-		respondWithError(w, http.StatusForbidden, "user is not author of chirp")
+		respondWithError(w, r, apierror.Forbidden("user is not author of chirp"))
 		return
 	}
 
-	if err = cfg.db.DeleteChirp(r.Context(), chirpId); err != nil {
-		respondWithError(w, http.StatusNotFound, err.Error())
+	err = cfg.store.WithTx(r.Context(), func(tx store.Store) error {
+		if err := tx.Chirps().DeleteChirp(r.Context(), chirpId); err != nil {
+			return err
+		}
+		payload := chirpDeletedEvent{ID: chirp.ID, UserID: chirp.UserID}
+		return events.Record(r.Context(), tx.Outbox(), events.AggregateChirp, chirp.ID, events.ChirpDeleted, payload)
+	})
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -78,19 +89,33 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 	chirpIdPath := r.PathValue("chirpId")
 	if chirpIdPath == "" {
-		respondWithError(w, http.StatusBadRequest, "no chirp id provided")
+		respondWithError(w, r, apierror.BadRequest("no chirp id provided"))
 		return
 	}
 
 	chirpId, err := uuid.Parse(chirpIdPath)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "chirp id is not in UUID format")
+		respondWithError(w, r, errMalformedChirpID)
 		return
 	}
 
-	chirp, err := cfg.db.GetChirp(r.Context(), chirpId)
+	chirp, err := cfg.store.Chirps().GetChirp(r.Context(), chirpId)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errChirpNotFound.Wrap(err))
+		return
+	}
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, err.Error())
+		respondWithError(w, r, err)
+		return
+	}
+
+	visible, err := cfg.chirpVisible(r, chirp)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	if !visible {
+		respondWithError(w, r, errChirpNotFound)
 		return
 	}
 
@@ -105,6 +130,22 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, chirpResponse)
 }
 
+// chirpVisible reports whether the requester may see chirp. Hidden chirps and
+// chirps by shadowbanned users are only shown to their author.
+func (cfg *apiConfig) chirpVisible(r *http.Request, chirp database.Chirp) (bool, error) {
+	if viewer := viewerID(r); viewer.Valid && viewer.UUID == chirp.UserID {
+		return true, nil
+	}
+	if chirp.HiddenAt.Valid {
+		return false, nil
+	}
+	author, err := cfg.store.Users().GetUserByID(r.Context(), chirp.UserID)
+	if err != nil {
+		return false, err
+	}
+	return !author.ShadowbannedAt.Valid, nil
+}
+
 func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	userIdString := r.URL.Query().Get("author_id")
 	sortKey := r.URL.Query().Get("sort")
@@ -114,14 +155,25 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	if userIdString != "" {
 		userId, err := uuid.Parse(userIdString)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithError(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "author_id",
+				Code:    "uuid",
+				Message: "author_id must be a UUID",
+			}))
+			return
+		}
+		chirps, err = cfg.store.Chirps().GetChirpsByAuthor(r.Context(), database.GetChirpsByAuthorParams{
+			UserID:   userId,
+			ViewerID: viewerID(r),
+		})
+		if err != nil {
+			respondWithError(w, r, err)
 			return
 		}
-		chirps, err = cfg.db.GetChirpsByAuthor(r.Context(), userId)
 	} else {
-		chirps, err = cfg.db.GetChirps(r.Context())
+		chirps, err = cfg.store.Chirps().GetChirps(r.Context(), viewerID(r))
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithError(w, r, err)
 			return
 		}
 	}
@@ -149,70 +201,161 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
 	var chirp chirp
-	defer r.Body.Close()
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&chirp); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "error reading chirp json")
+	if err := decodeJSON(w, r, &chirp); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
+	userId := principal(r).UserID
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+	body := chirptext.Normalize(chirp.Body)
+	if err := checkChirpBody(body); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
-
-	userId, err := auth.ValidateJWT(token, cfg.serverSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+	filtered := cfg.contentFilter.Check(body)
+	if rejection, rejected := filtered.Rejection(); rejected {
+		respondWithError(w, r, apierror.Validation(apierror.FieldError{
+			Field:   "body",
+			Code:    "content_filter",
+			Message: "chirp " + rejection.Reason,
+		}))
+		return
 	}
 
-	validChirp, err := validateChirp(chirp)
+	verdicts, err := cfg.checkSpam(r, userId, filtered.Body)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
+	strongest, _ := spam.Strongest(verdicts)
+	if strongest.Action == spam.Reject {
+		respondWithError(w, r, spamRejection(w, strongest))
+		return
+	}
+	reason := flagReason(filtered.Flags(), verdicts)
 
 	createChirpParams := database.CreateChirpParams{
 		UserID: userId,
-		Body:   validChirp.Body,
+		Body:   filtered.Body,
 	}
-	chirpData, err := cfg.db.CreateChirp(r.Context(), createChirpParams)
+	var chirpResponse chirpResponse
+	err = cfg.store.WithTx(r.Context(), func(tx store.Store) error {
+		chirpData, err := tx.Chirps().CreateChirp(r.Context(), createChirpParams)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			_, err := tx.Reports().CreateReportCase(r.Context(), database.CreateReportCaseParams{
+				ChirpID:    uuid.NullUUID{UUID: chirpData.ID, Valid: true},
+				AuthorID:   userId,
+				FlagReason: reason,
+				HidChirp:   strongest.Action == spam.Hide,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		// A hidden chirp is still returned to its author as if it were
+		// posted, so spammers don't learn to work around the checks.
+		if strongest.Action == spam.Hide {
+			if err := tx.Chirps().HideChirp(r.Context(), chirpData.ID); err != nil {
+				return err
+			}
+		}
+
+		chirpResponse.ID = chirpData.ID
+		chirpResponse.UserID = userId
+		chirpResponse.Body = chirpData.Body
+		chirpResponse.CreatedAt = chirpData.CreatedAt
+		chirpResponse.UpdatedAt = chirpData.UpdatedAt
+		return events.Record(r.Context(), tx.Outbox(), events.AggregateChirp, chirpData.ID, events.ChirpCreated, chirpResponse)
+	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
-	chirpResponse := chirpResponse{
-		ID:        chirpData.ID,
-		UserID:    userId,
-		Body:      chirp.Body,
-		CreatedAt: chirpData.CreatedAt,
-		UpdatedAt: chirpData.UpdatedAt,
-	}
+	cfg.metrics.ChirpCreated()
 	respondWithJSON(w, http.StatusCreated, chirpResponse)
 }
 
-func validateChirp(c chirp) (chirp, error) {
-	if len(c.Body) > maxChars {
-		return c, errors.New("Chirp is too long")
+// checkChirpBody reports what stops a normalized body from being posted.
+func checkChirpBody(body string) error {
+	var controlErr *chirptext.ControlCharError
+	switch err := chirptext.Validate(body); {
+	case errors.Is(err, chirptext.ErrBlank):
+		return apierror.Validation(apierror.FieldError{
+			Field:   "body",
+			Code:    "blank",
+			Message: "body must not be blank",
+		})
+	case errors.As(err, &controlErr):
+		return apierror.Validation(apierror.FieldError{
+			Field:   "body",
+			Code:    "control_character",
+			Message: fmt.Sprintf("body must not contain control characters, found %U at character %d", controlErr.Char, controlErr.Position),
+		})
+	case err != nil:
+		return err
+	}
+	if length := chirptext.Length(body); length > chirptext.MaxLength {
+		return apierror.Validation(apierror.FieldError{
+			Field:   "body",
+			Code:    "max_length",
+			Message: fmt.Sprintf("body must be at most %d characters long, is %d", chirptext.MaxLength, length),
+			Length:  length,
+			Limit:   chirptext.MaxLength,
+		})
 	}
-	return stripProfanity(c), nil
+	return nil
 }
 
-func stripProfanity(c chirp) chirp {
-	profanity := map[string]int{"kerfuffle": 0, "sharbert": 1, "fornax": 2}
-	words := strings.Split(c.Body, " ")
-
-	for i, word := range words {
-		_, ok := profanity[strings.ToLower(word)]
-		if ok {
-			words[i] = bleep
-		}
+// checkSpam compares body with the author's recent chirps.
+func (cfg *apiConfig) checkSpam(r *http.Request, userID uuid.UUID, body string) ([]spam.Verdict, error) {
+	author, err := cfg.store.Users().GetUserByID(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := cfg.store.Chirps().GetRecentChirpsByAuthor(r.Context(), database.GetRecentChirpsByAuthorParams{
+		UserID:        userID,
+		WindowSeconds: int32(cfg.spamRules.Window.Seconds()),
+		RowLimit:      int32(cfg.spamRules.RecentLimit()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	post := spam.Post{Body: body, AuthorCreatedAt: author.CreatedAt, Now: time.Now()}
+	for _, c := range recent {
+		post.Recent = append(post.Recent, spam.Prior{Body: c.Body, CreatedAt: c.CreatedAt})
 	}
-	cleanWords := strings.Join(words, " ")
+	return cfg.spamRules.Evaluate(post), nil
+}
 
-	cleanChirp := chirp{Body: cleanWords}
-	return cleanChirp
+// spamRejection is the error for a chirp refused by a spam check: 429 when
+// a new account is posting too fast, since waiting fixes it, and 422
+// otherwise.
+func spamRejection(w http.ResponseWriter, v spam.Verdict) error {
+	if v.Check == spam.Velocity {
+		w.Header().Set("Retry-After", ceilSeconds(v.RetryAfter))
+		return apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "chirp "+v.Reason+", retry later")
+	}
+	return apierror.Validation(apierror.FieldError{
+		Field:   "body",
+		Code:    string(v.Check),
+		Message: "chirp " + v.Reason,
+	})
+}
 
+// flagReason summarizes why the content filter or spam checks queued a
+// chirp for review, e.g.
+// `words: contains the word "grift"; spam: is mostly capital letters`.
+func flagReason(flags []contentfilter.Match, verdicts []spam.Verdict) string {
+	var reasons []string
+	for _, f := range flags {
+		reasons = append(reasons, f.Filter+": "+f.Reason)
+	}
+	for _, v := range verdicts {
+		reasons = append(reasons, string(v.Check)+": "+v.Reason)
+	}
+	return strings.Join(reasons, "; ")
 }
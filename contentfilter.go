@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/contentfilter"
+)
+
+type testContentFilterParams struct {
+	Body string `json:"body" validate:"required"`
+}
+
+type contentFilterTestResponse struct {
+	Body     string                `json:"body"`
+	Rejected bool                  `json:"rejected"`
+	Flagged  bool                  `json:"flagged"`
+	Matches  []contentfilter.Match `json:"matches"`
+}
+
+func (cfg *apiConfig) getContentFilterHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, cfg.contentFilter.Lists())
+}
+
+func (cfg *apiConfig) replaceContentFilterHandler(w http.ResponseWriter, r *http.Request) {
+	var lists contentfilter.Lists
+	if err := decodeJSON(w, r, &lists); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	if err := cfg.contentFilter.Replace(lists); err != nil {
+		respondWithError(w, r, contentFilterError(err))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, cfg.contentFilter.Lists())
+}
+
+func (cfg *apiConfig) reloadContentFilterHandler(w http.ResponseWriter, r *http.Request) {
+	lists, err := cfg.contentFilter.Reload()
+	if errors.Is(err, contentfilter.ErrNoFile) {
+		respondWithError(w, r, apierror.Conflict("content filter lists were not loaded from a file").Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, contentFilterError(err))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, lists)
+}
+
+// testContentFilterHandler shows what the active lists would do to a chirp
+// without posting it.
+func (cfg *apiConfig) testContentFilterHandler(w http.ResponseWriter, r *http.Request) {
+	params := testContentFilterParams{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	result := cfg.contentFilter.Check(params.Body)
+	_, rejected := result.Rejection()
+	resp := contentFilterTestResponse{
+		Body:     result.Body,
+		Rejected: rejected,
+		Flagged:  len(result.Flags()) > 0,
+		Matches:  result.Matches,
+	}
+	if resp.Matches == nil {
+		resp.Matches = []contentfilter.Match{}
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func contentFilterError(err error) error {
+	var invalidErr *contentfilter.InvalidError
+	if errors.As(err, &invalidErr) {
+		return apierror.Validation(apierror.FieldError{
+			Field:   invalidErr.Field,
+			Code:    "invalid",
+			Message: invalidErr.Message,
+		}).Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+// Package apierror defines the errors handlers return to API clients: a
+// stable machine-readable code, the HTTP status it maps to, a message that is
+// safe to show, and optionally the underlying cause for logs only.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Code string
+
+const (
+	CodeBadRequest         Code = "bad_request"
+	CodeValidation         Code = "validation_failed"
+	CodeUnauthenticated    Code = "unauthenticated"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeAccountSuspended   Code = "account_suspended"
+	CodeAccountBanned      Code = "account_banned"
+	CodeForbidden          Code = "forbidden"
+	CodeInsufficientScope  Code = "insufficient_scope"
+	CodeInvalidCSRFToken   Code = "invalid_csrf_token"
+	CodeNotFound           Code = "not_found"
+	CodeConflict           Code = "conflict"
+	CodePayloadTooLarge    Code = "payload_too_large"
+	CodeUnsupportedMedia   Code = "unsupported_media_type"
+	CodeRateLimited        Code = "rate_limited"
+	CodeNotImplemented     Code = "not_implemented"
+	CodeInternal           Code = "internal_error"
+)
+
+// FieldError describes one invalid field in a request body or query.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Length and Limit accompany length errors where the length isn't just
+	// a count of what was sent, so clients can show the same number.
+	Length int `json:"length,omitempty"`
+	Limit  int `json:"limit,omitempty"`
+}
+
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Fields  []FieldError
+	// Err is the underlying cause. It is logged but never sent to clients.
+	Err error
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e with err recorded as its cause.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func Unauthenticated(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+func NotImplemented(message string) *Error {
+	return New(http.StatusNotImplemented, CodeNotImplemented, message)
+}
+
+// Internal hides err behind a generic message.
+func Internal(err error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, "internal server error").Wrap(err)
+}
+
+// Validation reports one or more invalid fields.
+func Validation(fields ...FieldError) *Error {
+	e := New(http.StatusUnprocessableEntity, CodeValidation, "request failed validation")
+	e.Fields = fields
+	return e
+}
+
+// From converts any error into an *Error. Errors that already are one pass
+// through unchanged; anything else is a 500.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Internal(err)
+}
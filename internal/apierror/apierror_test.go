@@ -0,0 +1,62 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   Code
+	}{
+		{
+			name:       "apiErrorPassesThrough",
+			err:        Forbidden("user is not author of chirp"),
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "wrappedAPIError",
+			err:        fmt.Errorf("deleting chirp: %w", NotFound("chirp not found")),
+			wantStatus: http.StatusNotFound,
+			wantCode:   CodeNotFound,
+		},
+		{
+			name:       "otherError",
+			err:        errors.New("pq: connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			if got.Status != tt.wantStatus {
+				t.Errorf("From() status = %d, want %d", got.Status, tt.wantStatus)
+			}
+			if got.Code != tt.wantCode {
+				t.Errorf("From() code = %q, want %q", got.Code, tt.wantCode)
+			}
+			if !errors.Is(got, tt.err) && got.Err != nil {
+				t.Errorf("From() lost the cause %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestInternalHidesCause(t *testing.T) {
+	cause := errors.New(`pq: relation "users" does not exist`)
+	e := Internal(cause)
+	if e.Message != "internal server error" {
+		t.Errorf("Message = %q, want generic message", e.Message)
+	}
+	if !errors.Is(e, cause) {
+		t.Error("Internal() should keep the cause for logging")
+	}
+}
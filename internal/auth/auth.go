@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -9,8 +10,10 @@ import (
 	"time"
 
 	"github.com/alexedwards/argon2id"
+	"github.com/d-shames3/chirpy/internal/tracing"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/codes"
 )
 
 func GetAPIKey(headers http.Header) (string, error) {
@@ -28,6 +31,12 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(key), err
 }
 
+// SessionID names the session a refresh token belongs to without revealing
+// the token. Access tokens issued from the same refresh token share it.
+func SessionID(refreshToken string) string {
+	return HashToken(refreshToken)[:32]
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	rawToken := headers.Get("Authorization")
 	if rawToken == "" {
@@ -37,12 +46,43 @@ func GetBearerToken(headers http.Header) (string, error) {
 	return strings.Replace(rawToken, "Bearer ", "", 1), nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	claims := &jwt.RegisteredClaims{
-		Issuer:    "Chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
+// accessClaims are the claims in a Chirpy access token. Scope is empty for
+// tokens issued at login, which are unrestricted.
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Scope     string `json:"scope,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+// AccessToken is what a valid access token says about its bearer.
+type AccessToken struct {
+	UserID uuid.UUID
+	// Scopes is nil for unrestricted tokens.
+	Scopes []string
+	// SessionID is empty for tokens not issued from a refresh token.
+	SessionID string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// MakeJWT issues an access token for userID. Passing scopes limits the token
+// to them; with none it is unrestricted.
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration, scopes ...string) (string, error) {
+	return MakeSessionJWT(userID, "", tokenSecret, expiresIn, scopes...)
+}
+
+// MakeSessionJWT is MakeJWT for a token issued from a refresh token, whose
+// SessionID it carries.
+func MakeSessionJWT(userID uuid.UUID, sessionID, tokenSecret string, expiresIn time.Duration, scopes ...string) (string, error) {
+	claims := &accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "Chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Scope:     strings.Join(scopes, " "),
+		SessionID: sessionID,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -55,38 +95,58 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (any, error) {
+	token, err := ParseJWT(tokenString, tokenSecret)
+	return token.UserID, err
+}
+
+// ParseJWT validates an access token and returns its claims.
+func ParseJWT(tokenString, tokenSecret string) (AccessToken, error) {
+	claims := &accessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.Nil, err
+		return AccessToken{}, err
 	}
 
-	rawUserId, err := token.Claims.GetSubject()
+	userId, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, err
+		return AccessToken{}, err
 	}
 
-	userId, err := uuid.Parse(rawUserId)
-	if err != nil {
-		return uuid.Nil, err
+	token := AccessToken{UserID: userId, SessionID: claims.SessionID}
+	if claims.Scope != "" {
+		token.Scopes = strings.Fields(claims.Scope)
 	}
-
-	return userId, nil
+	if claims.IssuedAt != nil {
+		token.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		token.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return token, nil
 }
 
-func HashPassword(password string) (string, error) {
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Tracer().Start(ctx, "argon2id.CreateHash")
+	defer span.End()
+
 	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return "", err
 	}
 
 	return hash, nil
 }
 
-func CheckPasswordHash(password string, hash string) (bool, error) {
+func CheckPasswordHash(ctx context.Context, password string, hash string) (bool, error) {
+	_, span := tracing.Tracer().Start(ctx, "argon2id.ComparePasswordAndHash")
+	defer span.End()
+
 	match, err := argon2id.ComparePasswordAndHash(password, hash)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return false, err
 	}
 
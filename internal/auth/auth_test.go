@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -53,8 +54,8 @@ func TestGetBearerToken(t *testing.T) {
 func TestCheckPasswordHash(t *testing.T) {
 	password1 := "correctPassword123!"
 	password2 := "anotherPassword456!"
-	hash1, _ := HashPassword(password1)
-	hash2, _ := HashPassword(password2)
+	hash1, _ := HashPassword(context.Background(), password1)
+	hash2, _ := HashPassword(context.Background(), password2)
 
 	tests := []struct {
 		name          string
@@ -102,7 +103,7 @@ func TestCheckPasswordHash(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			match, err := CheckPasswordHash(tt.password, tt.hash)
+			match, err := CheckPasswordHash(context.Background(), tt.password, tt.hash)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CheckPasswordHash() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -160,3 +161,97 @@ func TestValidateJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestParseJWT(t *testing.T) {
+	userID := uuid.New()
+
+	unrestricted, _ := MakeJWT(userID, "secret", time.Hour)
+	got, err := ParseJWT(unrestricted, "secret")
+	if err != nil || got.UserID != userID || got.Scopes != nil {
+		t.Errorf("ParseJWT(unrestricted) = %+v, %v, want user %v and nil scopes", got, err, userID)
+	}
+	if d := got.ExpiresAt.Sub(got.IssuedAt); d != time.Hour {
+		t.Errorf("ParseJWT(unrestricted) lifetime = %v, want %v", d, time.Hour)
+	}
+
+	scoped, _ := MakeJWT(userID, "secret", time.Hour, ScopeChirpsRead, ScopeChirpsWrite)
+	got, err = ParseJWT(scoped, "secret")
+	if err != nil || len(got.Scopes) != 2 || got.Scopes[0] != ScopeChirpsRead || got.Scopes[1] != ScopeChirpsWrite {
+		t.Errorf("ParseJWT(scoped) = %+v, %v, want scopes %q", got, err, []string{ScopeChirpsRead, ScopeChirpsWrite})
+	}
+
+	if got.SessionID != "" {
+		t.Errorf("ParseJWT(scoped).SessionID = %q, want none", got.SessionID)
+	}
+
+	refresh, _ := MakeRefreshToken()
+	session, _ := MakeSessionJWT(userID, SessionID(refresh), "secret", time.Hour, ScopeChirpsRead)
+	got, err = ParseJWT(session, "secret")
+	if err != nil || got.SessionID != SessionID(refresh) || len(got.Scopes) != 1 {
+		t.Errorf("ParseJWT(session) = %+v, %v, want session %q and one scope", got, err, SessionID(refresh))
+	}
+	if id := SessionID(refresh); id == refresh || id == SessionID(refresh+"x") {
+		t.Errorf("SessionID(%q) = %q, want an id distinct from the token and from other sessions", refresh, id)
+	}
+
+	expired, _ := MakeJWT(userID, "secret", -time.Minute)
+	if _, err := ParseJWT(expired, "secret"); err == nil {
+		t.Error("ParseJWT accepted an expired token")
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	if _, ok := PrincipalFrom(context.Background()); ok {
+		t.Fatal("PrincipalFrom found a principal in an empty context")
+	}
+
+	want := Principal{UserID: uuid.New(), Tier: TierRed, Scopes: []string{"chirps:read"}}
+	got, ok := PrincipalFrom(WithPrincipal(context.Background(), want))
+	if !ok || got.UserID != want.UserID || got.Tier != want.Tier {
+		t.Fatalf("PrincipalFrom = %+v, %v, want %+v", got, ok, want)
+	}
+
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{name: "unrestricted", scopes: nil, scope: "chirps:write", want: true},
+		{name: "granted", scopes: []string{"chirps:read"}, scope: "chirps:read", want: true},
+		{name: "notGranted", scopes: []string{"chirps:read"}, scope: "chirps:write", want: false},
+		{name: "empty", scopes: []string{}, scope: "chirps:read", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Principal{Scopes: tt.scopes}).HasScope(tt.scope); got != tt.want {
+				t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatalf("MakePersonalAccessToken error = %v", err)
+	}
+	if !IsPersonalAccessToken(token) || len(token) != len(PersonalAccessTokenPrefix)+64 {
+		t.Errorf("MakePersonalAccessToken = %q, want prefixed 32-byte hex token", token)
+	}
+	if other, _ := MakePersonalAccessToken(); other == token {
+		t.Error("MakePersonalAccessToken returned the same token twice")
+	}
+
+	jwt, err := MakeJWT(uuid.New(), "secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsPersonalAccessToken(jwt) {
+		t.Error("IsPersonalAccessToken accepted a JWT")
+	}
+
+	if HashToken(token) != HashToken(token) || HashToken(token) == HashToken(token+"x") {
+		t.Error("HashToken is not a deterministic function of the token")
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Scopes a personal access token can be granted.
+const (
+	ScopeChirpsRead   = "chirps:read"
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeProfileWrite = "profile:write"
+)
+
+// Scopes that are never granted to personal access tokens, so only a login
+// session can manage tokens or use the admin routes.
+const (
+	ScopeTokens = "tokens"
+	ScopeAdmin  = "admin"
+)
+
+var GrantableScopes = []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeProfileWrite}
+
+// PersonalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from JWTs without a database lookup, and spotted by secret scanners.
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+func MakePersonalAccessToken() (string, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	return PersonalAccessTokenPrefix + hex.EncodeToString(key), err
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// HashToken returns the form a high-entropy token is stored in. Unlike
+// passwords these don't need a slow hash, since they can't be guessed.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
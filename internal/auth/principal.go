@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/google/uuid"
+)
+
+// Tiers a principal can belong to.
+const (
+	TierFree = "free"
+	TierRed  = "red"
+)
+
+// Principal is the authenticated caller a request is made on behalf of.
+type Principal struct {
+	UserID uuid.UUID
+	Tier   string
+	Role   rbac.Role
+	// Scopes limits what the credential may do. Nil means unrestricted, as
+	// for access tokens issued at login.
+	Scopes []string
+	// SessionID identifies the refresh token the access token was issued
+	// from: a login, a browser session or an OAuth grant. It is empty for
+	// personal access tokens.
+	SessionID string
+}
+
+// HasScope reports whether the principal's credential grants scope.
+func (p Principal) HasScope(scope string) bool {
+	return p.Scopes == nil || slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored in ctx by WithPrincipal.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
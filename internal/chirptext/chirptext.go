@@ -0,0 +1,110 @@
+// Package chirptext normalizes chirp bodies and measures them the way people
+// read them: one per user-perceived character (grapheme cluster), so "👍🏽"
+// and "é" count once however many code points or bytes they take, and every
+// link counts the same however long it is.
+package chirptext
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxLength is the longest chirp, in weighted characters.
+	MaxLength = 140
+	// LinkWeight is what every link counts as.
+	LinkWeight = 23
+)
+
+var ErrBlank = errors.New("chirp is empty or only whitespace")
+
+// ControlCharError reports a character that isn't allowed in a chirp.
+// Position counts grapheme clusters from 1, like Length.
+type ControlCharError struct {
+	Char     rune
+	Position int
+}
+
+func (e *ControlCharError) Error() string {
+	return fmt.Sprintf("chirp contains control character %U at position %d", e.Char, e.Position)
+}
+
+// linkPattern matches links with a scheme or starting www. Bare domains
+// aren't weighted, since "e.g." and "file.txt" look just like them.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s]+`)
+
+// trailingPunct is left out of a link when it ends a sentence or clause,
+// as in "see https://example.com.".
+const trailingPunct = `.,:;!?'")]}`
+
+// Normalize returns body in Unicode normalization form C, so a character
+// typed precomposed or as a base plus combining marks is stored and counted
+// the same way. Windows line endings become plain newlines.
+func Normalize(body string) string {
+	return norm.NFC.String(strings.ReplaceAll(body, "\r\n", "\n"))
+}
+
+// Validate rejects bodies that are blank or contain control characters.
+// Newlines and tabs are allowed, and so are zero-width joiners, which emoji
+// sequences need; bidirectional overrides are not, since they can make a
+// chirp display differently from what it says.
+func Validate(body string) error {
+	if strings.TrimFunc(body, unicode.IsSpace) == "" {
+		return ErrBlank
+	}
+	graphemes := uniseg.NewGraphemes(body)
+	for position := 1; graphemes.Next(); position++ {
+		for _, r := range graphemes.Runes() {
+			if isControl(r) {
+				return &ControlCharError{Char: r, Position: position}
+			}
+		}
+	}
+	return nil
+}
+
+func isControl(r rune) bool {
+	switch {
+	case r == '\n', r == '\t':
+		return false
+	case unicode.IsControl(r):
+		return true
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+// Length returns body's weighted length: LinkWeight for each link and one
+// for every other grapheme cluster.
+func Length(body string) int {
+	length, last := 0, 0
+	for _, span := range linkSpans(body) {
+		length += uniseg.GraphemeClusterCount(body[last:span[0]]) + LinkWeight
+		last = span[1]
+	}
+	return length + uniseg.GraphemeClusterCount(body[last:])
+}
+
+// Links returns the links in body, as Length counts them.
+func Links(body string) []string {
+	var links []string
+	for _, span := range linkSpans(body) {
+		links = append(links, body[span[0]:span[1]])
+	}
+	return links
+}
+
+func linkSpans(body string) [][]int {
+	spans := linkPattern.FindAllStringIndex(body, -1)
+	for _, span := range spans {
+		span[1] = span[0] + len(strings.TrimRight(body[span[0]:span[1]], trailingPunct))
+	}
+	return spans
+}
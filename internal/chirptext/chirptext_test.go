@@ -0,0 +1,111 @@
+package chirptext
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"cafe\u0301", "caf\u00e9"},
+		{"caf\u00e9", "caf\u00e9"},
+		{"line one\r\nline two", "line one\nline two"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantErr  error
+		wantChar rune
+		wantPos  int
+	}{
+		{name: "plain", body: "hello world"},
+		{name: "newlines and tabs", body: "one\n\ttwo"},
+		{name: "emoji zwj sequence", body: "\U0001F469\u200d\U0001F4BB at work"},
+		{name: "empty", body: "", wantErr: ErrBlank},
+		{name: "whitespace", body: " \n\t\u3000", wantErr: ErrBlank},
+		{name: "bell", body: "ding\a", wantChar: '\a', wantPos: 5},
+		{name: "carriage return", body: "a\rb", wantChar: '\r', wantPos: 2},
+		{name: "position counts characters", body: "e\u0301\U0001F44D\U0001F3FD\x00", wantChar: 0, wantPos: 3},
+		{name: "bidi override", body: "abc\u202edef", wantChar: '\u202e', wantPos: 4},
+		{name: "bidi isolate", body: "\u2067x", wantChar: '\u2067', wantPos: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.body)
+			if tt.wantPos > 0 {
+				var ctrl *ControlCharError
+				if !errors.As(err, &ctrl) || ctrl.Char != tt.wantChar || ctrl.Position != tt.wantPos {
+					t.Errorf("Validate(%q) = %v, want %U at %d", tt.body, err, tt.wantChar, tt.wantPos)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.body, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"ascii", "hello", 5},
+		{"empty", "", 0},
+		{"precomposed accent", "caf\u00e9", 4},
+		{"combining accent", "cafe\u0301", 4},
+		{"skin tone emoji", "👍🏽", 1},
+		{"zwj family", "\U0001F468\u200d\U0001F469\u200d\U0001F467", 1},
+		{"flags", "🇺🇸🇯🇵", 2},
+		{"fifty emoji", strings.Repeat("😀", 50), 50},
+		{"link", "https://example.com/a/very/long/path?with=query", LinkWeight},
+		{"short link", "see http://x.co", 4 + LinkWeight},
+		{"www link", "www.example.com rocks", LinkWeight + 6},
+		{"trailing punctuation", "go to https://example.com.", 6 + LinkWeight + 1},
+		{"link in parens", "(https://example.com)", 1 + LinkWeight + 1},
+		{"two links", "https://a.example https://b.example", 2*LinkWeight + 1},
+		{"bare domain counts as text", "example.com", 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.body); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"no links here", nil},
+		{"go to https://example.com.", []string{"https://example.com"}},
+		{"(www.a.example) and http://b.example/x?y=1", []string{"www.a.example", "http://b.example/x?y=1"}},
+		{"example.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			if got := Links(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Links(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,791 @@
+// Package config loads Chirpy's settings from defaults, an optional JSON
+// config file, the environment and command-line flags, in increasing order of
+// precedence.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/httpheaders"
+	"github.com/d-shames3/chirpy/internal/logging"
+	"github.com/d-shames3/chirpy/internal/ratelimit"
+	"github.com/d-shames3/chirpy/internal/spam"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/d-shames3/chirpy/internal/tracing"
+)
+
+const (
+	PlatformDev  = "dev"
+	PlatformProd = "prod"
+)
+
+const minSecretLength = 32
+
+// Mode selects which settings Validate checks.
+type Mode int
+
+const (
+	// ModeServe checks every setting the HTTP server uses.
+	ModeServe Mode = iota
+	// ModeDatabase checks only what the migrate and admin commands need:
+	// the database URL, logging and tracing.
+	ModeDatabase
+)
+
+// databaseCommands run against the database without serving, so they are
+// validated with ModeDatabase.
+var databaseCommands = []string{"migrate", "admin"}
+
+type Config struct {
+	Port           int
+	FilepathRoot   string
+	DBURL          string
+	Platform       string
+	ServerSecret   string
+	PolkaKey       string
+	AccessTokenTTL time.Duration
+	AutoMigrate    bool
+	LogLevel       string
+	LogFormat      string
+	TraceExporter  string
+	TraceFile      string
+
+	// MetricsAddr is a separate host:port to serve /metrics on without
+	// authentication, for scrapers. Keep it off the public network. Empty
+	// leaves /metrics on the main port only, behind the metrics:view
+	// permission.
+	MetricsAddr string
+
+	RateLimitBackend string
+	// TrustedProxies lists the proxies whose X-Forwarded-For is believed when
+	// working out the client address, as comma-separated CIDRs or addresses.
+	TrustedProxies string
+
+	// CORS lists are comma-separated. No allowed origins disables CORS.
+	CORSAllowedOrigins   string
+	CORSAllowedMethods   string
+	CORSAllowedHeaders   string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+	// AppContentSecurityPolicy is sent with the /app/ static files; API
+	// responses get a fixed policy that allows nothing.
+	AppContentSecurityPolicy string
+	HSTSMaxAge               time.Duration
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// DrainDelay is how long the server keeps serving with readiness failing
+	// before it stops accepting connections, giving load balancers time to
+	// notice.
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+
+	// ReadinessTimeout bounds each readiness check; ReadinessCacheTTL is how
+	// long a readiness report is reused before the checks run again.
+	ReadinessTimeout  time.Duration
+	ReadinessCacheTTL time.Duration
+
+	// ReportHideThreshold is how many reports hide a chirp until a moderator
+	// resolves them. Zero turns auto-hiding off.
+	ReportHideThreshold int
+	// ContentFilterFile holds the content filter lists as JSON. Edits made
+	// through the admin API are saved back to it. Empty uses built-in lists
+	// that only last until a restart.
+	ContentFilterFile string
+
+	// SpamWindow is how far back a new chirp is compared with its author's
+	// others. Each spam check has an action: off, flag, hide or reject.
+	SpamWindow              time.Duration
+	SpamDuplicateAction     string
+	SpamNearDuplicateAction string
+	// SpamNewAccountAge is how long accounts are limited to
+	// SpamNewAccountMaxChirps chirps per SpamWindow.
+	SpamNewAccountAge       time.Duration
+	SpamNewAccountMaxChirps int
+	SpamVelocityAction      string
+	SpamMaxLinks            int
+	SpamLinksAction         string
+}
+
+func defaults() Config {
+	return Config{
+		Port:             8080,
+		FilepathRoot:     ".",
+		Platform:         PlatformProd,
+		AccessTokenTTL:   time.Hour,
+		LogLevel:         "info",
+		LogFormat:        logging.FormatText,
+		TraceExporter:    tracing.ExporterNone,
+		RateLimitBackend: ratelimit.BackendMemory,
+
+		CORSAllowedMethods:       "GET,POST,PUT,DELETE",
+		CORSAllowedHeaders:       "Authorization,Content-Type,X-CSRF-Token,X-Request-ID",
+		CORSMaxAge:               10 * time.Minute,
+		AppContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'",
+		HSTSMaxAge:               365 * 24 * time.Hour,
+
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   30 * time.Second,
+		ReadinessTimeout:  2 * time.Second,
+		ReadinessCacheTTL: time.Second,
+
+		ReportHideThreshold: 5,
+
+		SpamWindow:              time.Hour,
+		SpamDuplicateAction:     string(spam.Reject),
+		SpamNearDuplicateAction: string(spam.Flag),
+		SpamNewAccountAge:       24 * time.Hour,
+		SpamNewAccountMaxChirps: 10,
+		SpamVelocityAction:      string(spam.Reject),
+		SpamMaxLinks:            3,
+		SpamLinksAction:         string(spam.Flag),
+	}
+}
+
+// setting describes one configuration value. Its JSON key is name, its flag
+// is name with underscores replaced by dashes, and its environment variable
+// is env.
+type setting struct {
+	name   string
+	env    string
+	usage  string
+	secret bool
+	isBool bool
+	set    func(c *Config, v string) error
+	get    func(c *Config) string
+}
+
+var settings = []setting{
+	{
+		name:  "port",
+		env:   "PORT",
+		usage: "port to listen on",
+		set:   intSetter(func(c *Config) *int { return &c.Port }),
+		get:   func(c *Config) string { return strconv.Itoa(c.Port) },
+	},
+	{
+		name:  "metrics_addr",
+		env:   "METRICS_ADDR",
+		usage: "internal host:port serving /metrics without authentication, e.g. 127.0.0.1:9090",
+		set:   stringSetter(func(c *Config) *string { return &c.MetricsAddr }),
+		get:   func(c *Config) string { return c.MetricsAddr },
+	},
+	{
+		name:  "filepath_root",
+		env:   "FILEPATH_ROOT",
+		usage: "directory served under /app/",
+		set:   stringSetter(func(c *Config) *string { return &c.FilepathRoot }),
+		get:   func(c *Config) string { return c.FilepathRoot },
+	},
+	{
+		name:  "db_url",
+		env:   "DB_URL",
+		usage: "database URL (postgres://... or sqlite:path)",
+		set:   stringSetter(func(c *Config) *string { return &c.DBURL }),
+		get:   func(c *Config) string { return redactURL(c.DBURL) },
+	},
+	{
+		name:  "platform",
+		env:   "PLATFORM",
+		usage: "deployment platform (dev or prod)",
+		set:   stringSetter(func(c *Config) *string { return &c.Platform }),
+		get:   func(c *Config) string { return c.Platform },
+	},
+	{
+		name:   "server_secret",
+		env:    "SERVER_SECRET",
+		usage:  "secret used to sign access tokens",
+		secret: true,
+		set:    stringSetter(func(c *Config) *string { return &c.ServerSecret }),
+		get:    func(c *Config) string { return c.ServerSecret },
+	},
+	{
+		name:   "polka_key",
+		env:    "POLKA_KEY",
+		usage:  "API key Polka uses to call the webhook",
+		secret: true,
+		set:    stringSetter(func(c *Config) *string { return &c.PolkaKey }),
+		get:    func(c *Config) string { return c.PolkaKey },
+	},
+	{
+		name:  "access_token_ttl",
+		env:   "ACCESS_TOKEN_TTL",
+		usage: "lifetime of access tokens, e.g. 1h",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.AccessTokenTTL }),
+		get:   func(c *Config) string { return c.AccessTokenTTL.String() },
+	},
+	{
+		name:  "log_level",
+		env:   "LOG_LEVEL",
+		usage: "minimum log level (debug, info, warn or error)",
+		set:   stringSetter(func(c *Config) *string { return &c.LogLevel }),
+		get:   func(c *Config) string { return c.LogLevel },
+	},
+	{
+		name:  "log_format",
+		env:   "LOG_FORMAT",
+		usage: "log output format (text or json)",
+		set:   stringSetter(func(c *Config) *string { return &c.LogFormat }),
+		get:   func(c *Config) string { return c.LogFormat },
+	},
+	{
+		name:  "trace_exporter",
+		env:   "TRACE_EXPORTER",
+		usage: "where to send traces (none, stdout, file or otlp)",
+		set:   stringSetter(func(c *Config) *string { return &c.TraceExporter }),
+		get:   func(c *Config) string { return c.TraceExporter },
+	},
+	{
+		name:  "trace_file",
+		env:   "TRACE_FILE",
+		usage: "file spans are appended to when trace_exporter is file",
+		set:   stringSetter(func(c *Config) *string { return &c.TraceFile }),
+		get:   func(c *Config) string { return c.TraceFile },
+	},
+	{
+		name:  "rate_limit_backend",
+		env:   "RATE_LIMIT_BACKEND",
+		usage: "where rate limit counters are kept (none, memory or postgres)",
+		set:   stringSetter(func(c *Config) *string { return &c.RateLimitBackend }),
+		get:   func(c *Config) string { return c.RateLimitBackend },
+	},
+	{
+		name:  "trusted_proxies",
+		env:   "TRUSTED_PROXIES",
+		usage: "comma-separated CIDRs of proxies allowed to set X-Forwarded-For",
+		set:   stringSetter(func(c *Config) *string { return &c.TrustedProxies }),
+		get:   func(c *Config) string { return c.TrustedProxies },
+	},
+	{
+		name:  "cors_allowed_origins",
+		env:   "CORS_ALLOWED_ORIGINS",
+		usage: "comma-separated origins allowed to call the API from a browser, or *",
+		set:   stringSetter(func(c *Config) *string { return &c.CORSAllowedOrigins }),
+		get:   func(c *Config) string { return c.CORSAllowedOrigins },
+	},
+	{
+		name:  "cors_allowed_methods",
+		env:   "CORS_ALLOWED_METHODS",
+		usage: "comma-separated methods allowed in cross-origin requests",
+		set:   stringSetter(func(c *Config) *string { return &c.CORSAllowedMethods }),
+		get:   func(c *Config) string { return c.CORSAllowedMethods },
+	},
+	{
+		name:  "cors_allowed_headers",
+		env:   "CORS_ALLOWED_HEADERS",
+		usage: "comma-separated request headers allowed in cross-origin requests",
+		set:   stringSetter(func(c *Config) *string { return &c.CORSAllowedHeaders }),
+		get:   func(c *Config) string { return c.CORSAllowedHeaders },
+	},
+	{
+		name:   "cors_allow_credentials",
+		env:    "CORS_ALLOW_CREDENTIALS",
+		usage:  "let cross-origin requests send cookies",
+		isBool: true,
+		set:    boolSetter(func(c *Config) *bool { return &c.CORSAllowCredentials }),
+		get:    func(c *Config) string { return strconv.FormatBool(c.CORSAllowCredentials) },
+	},
+	{
+		name:  "cors_max_age",
+		env:   "CORS_MAX_AGE",
+		usage: "how long browsers may cache preflight responses, e.g. 10m",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.CORSMaxAge }),
+		get:   func(c *Config) string { return c.CORSMaxAge.String() },
+	},
+	{
+		name:  "app_content_security_policy",
+		env:   "APP_CONTENT_SECURITY_POLICY",
+		usage: "Content-Security-Policy sent with /app/ files",
+		set:   stringSetter(func(c *Config) *string { return &c.AppContentSecurityPolicy }),
+		get:   func(c *Config) string { return c.AppContentSecurityPolicy },
+	},
+	{
+		name:  "hsts_max_age",
+		env:   "HSTS_MAX_AGE",
+		usage: "Strict-Transport-Security max-age for HTTPS requests, 0 to disable",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.HSTSMaxAge }),
+		get:   func(c *Config) string { return c.HSTSMaxAge.String() },
+	},
+	{
+		name:  "read_timeout",
+		env:   "READ_TIMEOUT",
+		usage: "maximum time to read a whole request, including the body",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.ReadTimeout }),
+		get:   func(c *Config) string { return c.ReadTimeout.String() },
+	},
+	{
+		name:  "read_header_timeout",
+		env:   "READ_HEADER_TIMEOUT",
+		usage: "maximum time to read request headers",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.ReadHeaderTimeout }),
+		get:   func(c *Config) string { return c.ReadHeaderTimeout.String() },
+	},
+	{
+		name:  "write_timeout",
+		env:   "WRITE_TIMEOUT",
+		usage: "maximum time to write a response",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.WriteTimeout }),
+		get:   func(c *Config) string { return c.WriteTimeout.String() },
+	},
+	{
+		name:  "idle_timeout",
+		env:   "IDLE_TIMEOUT",
+		usage: "how long to keep idle keep-alive connections open",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.IdleTimeout }),
+		get:   func(c *Config) string { return c.IdleTimeout.String() },
+	},
+	{
+		name:  "drain_delay",
+		env:   "DRAIN_DELAY",
+		usage: "how long to report not ready before refusing new connections on shutdown",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.DrainDelay }),
+		get:   func(c *Config) string { return c.DrainDelay.String() },
+	},
+	{
+		name:  "shutdown_timeout",
+		env:   "SHUTDOWN_TIMEOUT",
+		usage: "how long to wait for in-flight work on shutdown, e.g. 30s",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.ShutdownTimeout }),
+		get:   func(c *Config) string { return c.ShutdownTimeout.String() },
+	},
+	{
+		name:  "readiness_timeout",
+		env:   "READINESS_TIMEOUT",
+		usage: "how long each readiness check may take, e.g. 2s",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.ReadinessTimeout }),
+		get:   func(c *Config) string { return c.ReadinessTimeout.String() },
+	},
+	{
+		name:  "readiness_cache_ttl",
+		env:   "READINESS_CACHE_TTL",
+		usage: "how long to reuse a readiness result, e.g. 1s",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.ReadinessCacheTTL }),
+		get:   func(c *Config) string { return c.ReadinessCacheTTL.String() },
+	},
+	{
+		name:   "auto_migrate",
+		env:    "AUTO_MIGRATE",
+		usage:  "apply pending migrations before serving",
+		isBool: true,
+		set:    boolSetter(func(c *Config) *bool { return &c.AutoMigrate }),
+		get:    func(c *Config) string { return strconv.FormatBool(c.AutoMigrate) },
+	},
+	{
+		name:  "report_hide_threshold",
+		env:   "REPORT_HIDE_THRESHOLD",
+		usage: "number of reports that hide a chirp until it is reviewed (0 disables)",
+		set:   intSetter(func(c *Config) *int { return &c.ReportHideThreshold }),
+		get:   func(c *Config) string { return strconv.Itoa(c.ReportHideThreshold) },
+	},
+	{
+		name:  "content_filter_file",
+		env:   "CONTENT_FILTER_FILE",
+		usage: "JSON file with the content filter lists, saved to on admin edits (built-in lists when empty)",
+		set:   stringSetter(func(c *Config) *string { return &c.ContentFilterFile }),
+		get:   func(c *Config) string { return c.ContentFilterFile },
+	},
+	{
+		name:  "spam_window",
+		env:   "SPAM_WINDOW",
+		usage: "how far back to look for duplicate chirps and count a new account's chirps, e.g. 1h",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.SpamWindow }),
+		get:   func(c *Config) string { return c.SpamWindow.String() },
+	},
+	{
+		name:  "spam_duplicate_action",
+		env:   "SPAM_DUPLICATE_ACTION",
+		usage: "what to do with a chirp repeating one from the spam window: off, flag, hide or reject",
+		set:   stringSetter(func(c *Config) *string { return &c.SpamDuplicateAction }),
+		get:   func(c *Config) string { return c.SpamDuplicateAction },
+	},
+	{
+		name:  "spam_near_duplicate_action",
+		env:   "SPAM_NEAR_DUPLICATE_ACTION",
+		usage: "what to do with a chirp nearly the same as one from the spam window: off, flag, hide or reject",
+		set:   stringSetter(func(c *Config) *string { return &c.SpamNearDuplicateAction }),
+		get:   func(c *Config) string { return c.SpamNearDuplicateAction },
+	},
+	{
+		name:  "spam_new_account_age",
+		env:   "SPAM_NEW_ACCOUNT_AGE",
+		usage: "how long an account is limited to spam_new_account_max_chirps per spam window, e.g. 24h",
+		set:   durationSetter(func(c *Config) *time.Duration { return &c.SpamNewAccountAge }),
+		get:   func(c *Config) string { return c.SpamNewAccountAge.String() },
+	},
+	{
+		name:  "spam_new_account_max_chirps",
+		env:   "SPAM_NEW_ACCOUNT_MAX_CHIRPS",
+		usage: "chirps a new account may post per spam window (0 disables)",
+		set:   intSetter(func(c *Config) *int { return &c.SpamNewAccountMaxChirps }),
+		get:   func(c *Config) string { return strconv.Itoa(c.SpamNewAccountMaxChirps) },
+	},
+	{
+		name:  "spam_velocity_action",
+		env:   "SPAM_VELOCITY_ACTION",
+		usage: "what to do with a chirp over a new account's limit: off, flag, hide or reject",
+		set:   stringSetter(func(c *Config) *string { return &c.SpamVelocityAction }),
+		get:   func(c *Config) string { return c.SpamVelocityAction },
+	},
+	{
+		name:  "spam_max_links",
+		env:   "SPAM_MAX_LINKS",
+		usage: "most links a chirp may carry (0 only checks for chirps that are mostly links)",
+		set:   intSetter(func(c *Config) *int { return &c.SpamMaxLinks }),
+		get:   func(c *Config) string { return strconv.Itoa(c.SpamMaxLinks) },
+	},
+	{
+		name:  "spam_links_action",
+		env:   "SPAM_LINKS_ACTION",
+		usage: "what to do with a link-heavy chirp: off, flag, hide or reject",
+		set:   stringSetter(func(c *Config) *string { return &c.SpamLinksAction }),
+		get:   func(c *Config) string { return c.SpamLinksAction },
+	},
+}
+
+// Load builds a Config from args (typically os.Args[1:]) and getenv. The
+// config file is named by -config or CHIRPY_CONFIG. Arguments left after the
+// flags, such as a subcommand, are returned alongside the config. The config
+// is validated for the subcommand, so `migrate` and `admin` don't need the
+// server's secrets.
+func Load(args []string, getenv func(string) string) (Config, []string, error) {
+	fs := flag.NewFlagSet("chirpy", flag.ContinueOnError)
+	configPath := fs.String("config", getenv("CHIRPY_CONFIG"), "path to a JSON config file")
+	flagValues := map[string]string{}
+	for _, s := range settings {
+		fs.Var(&flagValue{name: s.name, isBool: s.isBool, values: flagValues}, flagName(s.name), s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	cfg := defaults()
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return Config{}, nil, fmt.Errorf("config file %s: %w", *configPath, err)
+		}
+	}
+
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := s.set(&cfg, v); err != nil {
+				return Config{}, nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := flagValues[s.name]; ok {
+			if err := s.set(&cfg, v); err != nil {
+				return Config{}, nil, fmt.Errorf("-%s: %w", flagName(s.name), err)
+			}
+		}
+	}
+
+	mode := ModeServe
+	if fs.NArg() > 0 && slices.Contains(databaseCommands, fs.Arg(0)) {
+		mode = ModeDatabase
+	}
+	if err := cfg.Validate(mode); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	raw := map[string]json.RawMessage{}
+	decoder := json.NewDecoder(f)
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON object")
+	}
+
+	known := map[string]setting{}
+	for _, s := range settings {
+		known[s.name] = s
+	}
+
+	for key, value := range raw {
+		s, ok := known[key]
+		if !ok {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+
+		var v string
+		if err := json.Unmarshal(value, &v); err != nil {
+			// Numbers and booleans are accepted unquoted.
+			v = string(value)
+		}
+		if err := s.set(c, v); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting mode needs at once.
+func (c Config) Validate(mode Mode) error {
+	var errs []error
+
+	if c.DBURL == "" {
+		errs = append(errs, errors.New("db_url is required"))
+	} else if _, _, err := store.ParseURL(c.DBURL); err != nil {
+		errs = append(errs, fmt.Errorf("db_url: %w", err))
+	}
+	if _, err := logging.New(io.Discard, c.LogLevel, c.LogFormat); err != nil {
+		errs = append(errs, err)
+	}
+	if err := tracing.CheckExporter(c.TraceExporter, c.TraceFile); err != nil {
+		errs = append(errs, err)
+	}
+	if mode == ModeDatabase {
+		return errors.Join(errs...)
+	}
+
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", c.Port))
+	}
+	if c.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			errs = append(errs, fmt.Errorf("metrics_addr: %w", err))
+		}
+	}
+	if c.FilepathRoot == "" {
+		errs = append(errs, errors.New("filepath_root is required"))
+	}
+	if c.Platform != PlatformDev && c.Platform != PlatformProd {
+		errs = append(errs, fmt.Errorf("platform must be %q or %q, got %q", PlatformDev, PlatformProd, c.Platform))
+	}
+	if len(c.ServerSecret) < minSecretLength {
+		errs = append(errs, fmt.Errorf("server_secret must be at least %d bytes", minSecretLength))
+	}
+	if c.PolkaKey == "" {
+		errs = append(errs, errors.New("polka_key is required"))
+	}
+	if err := ratelimit.CheckBackend(c.RateLimitBackend); err != nil {
+		errs = append(errs, err)
+	} else if c.RateLimitBackend == ratelimit.BackendPostgres {
+		if driver, _, err := store.ParseURL(c.DBURL); err == nil && driver != store.DriverPostgres {
+			errs = append(errs, errors.New("rate_limit_backend postgres needs a postgres db_url"))
+		}
+	}
+	if _, err := ratelimit.ParseTrustedProxies(c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := httpheaders.NewCORS(c.CORS()); err != nil {
+		errs = append(errs, err)
+	}
+	if c.CORSMaxAge < 0 {
+		errs = append(errs, errors.New("cors_max_age must not be negative"))
+	}
+	if c.HSTSMaxAge < 0 {
+		errs = append(errs, errors.New("hsts_max_age must not be negative"))
+	}
+	if c.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("access_token_ttl must be positive"))
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read_timeout", c.ReadTimeout},
+		{"read_header_timeout", c.ReadHeaderTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+		{"readiness_timeout", c.ReadinessTimeout},
+	} {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", d.name))
+		}
+	}
+	if c.DrainDelay < 0 {
+		errs = append(errs, errors.New("drain_delay must not be negative"))
+	}
+	if c.ReadinessCacheTTL < 0 {
+		errs = append(errs, errors.New("readiness_cache_ttl must not be negative"))
+	}
+	if c.ReportHideThreshold < 0 {
+		errs = append(errs, errors.New("report_hide_threshold must not be negative"))
+	}
+	if c.SpamWindow <= 0 {
+		errs = append(errs, errors.New("spam_window must be positive"))
+	}
+	if c.SpamNewAccountAge < 0 {
+		errs = append(errs, errors.New("spam_new_account_age must not be negative"))
+	}
+	if c.SpamNewAccountMaxChirps < 0 {
+		errs = append(errs, errors.New("spam_new_account_max_chirps must not be negative"))
+	}
+	if c.SpamMaxLinks < 0 {
+		errs = append(errs, errors.New("spam_max_links must not be negative"))
+	}
+	for _, a := range []struct {
+		name  string
+		value string
+	}{
+		{"spam_duplicate_action", c.SpamDuplicateAction},
+		{"spam_near_duplicate_action", c.SpamNearDuplicateAction},
+		{"spam_velocity_action", c.SpamVelocityAction},
+		{"spam_links_action", c.SpamLinksAction},
+	} {
+		if _, err := spam.ParseAction(a.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SpamRules returns the spam settings as rules for spam.Rules.Evaluate.
+// Validate must have passed.
+func (c Config) SpamRules() spam.Rules {
+	return spam.Rules{
+		Window:              c.SpamWindow,
+		DuplicateAction:     spam.Action(c.SpamDuplicateAction),
+		NearDuplicateAction: spam.Action(c.SpamNearDuplicateAction),
+		NewAccountAge:       c.SpamNewAccountAge,
+		NewAccountMaxChirps: c.SpamNewAccountMaxChirps,
+		VelocityAction:      spam.Action(c.SpamVelocityAction),
+		MaxLinks:            c.SpamMaxLinks,
+		LinksAction:         spam.Action(c.SpamLinksAction),
+	}
+}
+
+// CORS returns the CORS settings as options for httpheaders.NewCORS.
+func (c Config) CORS() httpheaders.CORSOptions {
+	return httpheaders.CORSOptions{
+		AllowedOrigins:   splitList(c.CORSAllowedOrigins),
+		AllowedMethods:   splitList(c.CORSAllowedMethods),
+		AllowedHeaders:   splitList(c.CORSAllowedHeaders),
+		AllowCredentials: c.CORSAllowCredentials,
+		MaxAge:           c.CORSMaxAge,
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// LogValue logs the config as a group with one attribute per setting. Secrets
+// are masked and database passwords removed.
+func (c Config) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(settings))
+	for _, s := range settings {
+		attrs = append(attrs, slog.String(s.name, s.redacted(&c)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func (s setting) redacted(c *Config) string {
+	v := s.get(c)
+	if s.secret && v != "" {
+		v = "[redacted]"
+	}
+	return v
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+func flagName(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// flagValue records flags that were set explicitly so they can be applied
+// after the config file and environment.
+type flagValue struct {
+	name   string
+	isBool bool
+	values map[string]string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.values == nil {
+		return ""
+	}
+	return f.values[f.name]
+}
+
+func (f *flagValue) Set(v string) error {
+	f.values[f.name] = v
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+func stringSetter(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func intSetter(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func boolSetter(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func durationSetter(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*field(c) = d
+		return nil
+	}
+}
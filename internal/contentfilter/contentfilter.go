@@ -0,0 +1,80 @@
+// Package contentfilter screens chirp bodies with an ordered pipeline of
+// filters. Each filter can reject a chirp outright, mask the offending text or
+// flag the chirp for a moderator to review.
+package contentfilter
+
+type Action string
+
+const (
+	Mask   Action = "mask"
+	Flag   Action = "flag"
+	Reject Action = "reject"
+)
+
+// Bleep replaces masked text.
+const Bleep = "****"
+
+// Match records one filter objecting to a body. Reason reads as the end of a
+// sentence starting "chirp", e.g. "contains the word \"fornax\"".
+type Match struct {
+	Filter string `json:"filter"`
+	Action Action `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// ContentFilter is one stage of a Pipeline. Check returns body with anything
+// it masks replaced, and a Match for each thing it objected to; Filter is
+// filled in from Name.
+type ContentFilter interface {
+	Name() string
+	Check(body string) (string, []Match)
+}
+
+// Pipeline runs filters in order, each seeing the body as masked by the ones
+// before it.
+type Pipeline []ContentFilter
+
+type Result struct {
+	// Body is the input with every mask applied.
+	Body    string
+	Matches []Match
+}
+
+// Run passes body through the pipeline, stopping at the first filter that
+// rejects it.
+func (p Pipeline) Run(body string) Result {
+	res := Result{Body: body}
+	for _, f := range p {
+		masked, matches := f.Check(res.Body)
+		res.Body = masked
+		for _, m := range matches {
+			m.Filter = f.Name()
+			res.Matches = append(res.Matches, m)
+		}
+		if _, rejected := res.Rejection(); rejected {
+			break
+		}
+	}
+	return res
+}
+
+// Rejection returns the match that rejected the body, if any.
+func (r Result) Rejection() (Match, bool) {
+	for _, m := range r.Matches {
+		if m.Action == Reject {
+			return m, true
+		}
+	}
+	return Match{}, false
+}
+
+// Flags returns the matches that want the body reviewed.
+func (r Result) Flags() []Match {
+	var flags []Match
+	for _, m := range r.Matches {
+		if m.Action == Flag {
+			flags = append(flags, m)
+		}
+	}
+	return flags
+}
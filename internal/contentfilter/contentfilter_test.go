@@ -0,0 +1,279 @@
+package contentfilter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func mustPipeline(t *testing.T, l Lists) Pipeline {
+	t.Helper()
+	p, err := l.Pipeline()
+	if err != nil {
+		t.Fatalf("Pipeline() error = %v", err)
+	}
+	return p
+}
+
+func TestDefaultWords(t *testing.T) {
+	p := mustPipeline(t, DefaultLists())
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"I had a kerfuffle today", "I had a **** today"},
+		{"what a kerfuffle!", "what a ****!"},
+		{"KERFUFFLE", "****"},
+		{"(Sharbert)", "(****)"},
+		{"f0rn@x and k3rfuffl3", "**** and ****"},
+		{"ker-fuffle", "****"},
+		{"$harbert", "****"},
+		{"fornaxes are fine", "fornaxes are fine"},
+		{"spacing  kept\tkerfuffle\n", "spacing  kept\t****\n"},
+		{"nothing to see", "nothing to see"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			res := p.Run(tt.body)
+			if res.Body != tt.want {
+				t.Errorf("Run(%q).Body = %q, want %q", tt.body, res.Body, tt.want)
+			}
+			if _, rejected := res.Rejection(); rejected {
+				t.Errorf("Run(%q) rejected", tt.body)
+			}
+		})
+	}
+}
+
+func TestActions(t *testing.T) {
+	p := mustPipeline(t, Lists{
+		Words: []WordRule{
+			{Term: "kerfuffle", Action: Mask},
+			{Term: "grift", Action: Flag},
+			{Term: "slur", Action: Reject},
+		},
+		Patterns: []PatternRule{
+			{Pattern: `\b\d{3}-\d{3}-\d{4}\b`, Action: Mask},
+			{Pattern: `(?i)buy now`, Action: Flag, Reason: "looks like an advert"},
+		},
+		Links: []LinkRule{
+			{Domain: "spam.example", Action: Reject},
+			{Domain: "tracker.example", Action: Mask},
+		},
+		Spam: SpamRules{Action: Flag, MaxRepeatedChars: 5, MaxUppercaseRatio: 0.8},
+	})
+	tests := []struct {
+		name      string
+		body      string
+		wantBody  string
+		wantFlags []string
+		wantRej   string
+	}{
+		{
+			name:     "masked word and number",
+			body:     "kerfuffle, call 555-123-4567",
+			wantBody: "****, call ****",
+		},
+		{
+			name:      "flagged word kept",
+			body:      "total grift",
+			wantBody:  "total grift",
+			wantFlags: []string{`contains the word "grift"`},
+		},
+		{
+			name:    "rejected word",
+			body:    "some slur here",
+			wantRej: `contains the word "slur"`,
+		},
+		{
+			name:      "pattern reason",
+			body:      "BUY NOW while stocks last",
+			wantBody:  "BUY NOW while stocks last",
+			wantFlags: []string{"looks like an advert"},
+		},
+		{
+			name:    "blocked subdomain",
+			body:    "see https://www.spam.example/deal?x=1",
+			wantRej: "links to the blocked domain spam.example",
+		},
+		{
+			name:     "masked link without scheme",
+			body:     "via tracker.example/abc.",
+			wantBody: "via ****",
+		},
+		{
+			name:     "lookalike domain allowed",
+			body:     "notspam.example is fine",
+			wantBody: "notspam.example is fine",
+		},
+		{
+			name:      "repeated characters",
+			body:      "wow!!!!!!",
+			wantBody:  "wow!!!!!!",
+			wantFlags: []string{"repeats a character 6 times"},
+		},
+		{
+			name:      "shouting",
+			body:      "THIS IS ALL CAPS FOR SURE",
+			wantBody:  "THIS IS ALL CAPS FOR SURE",
+			wantFlags: []string{"is mostly capital letters"},
+		},
+		{
+			name:     "short caps allowed",
+			body:     "OK LOL",
+			wantBody: "OK LOL",
+		},
+		{
+			name:    "reject stops the pipeline",
+			body:    "slur!!!!!!!!",
+			wantRej: `contains the word "slur"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := p.Run(tt.body)
+			rej, rejected := res.Rejection()
+			if tt.wantRej != "" {
+				if !rejected || rej.Reason != tt.wantRej {
+					t.Fatalf("Run(%q) rejection = %+v, %v, want %q", tt.body, rej, rejected, tt.wantRej)
+				}
+				if len(res.Flags()) > 0 {
+					t.Errorf("Run(%q) kept going after rejecting: %+v", tt.body, res.Matches)
+				}
+				return
+			}
+			if rejected {
+				t.Fatalf("Run(%q) rejected: %+v", tt.body, rej)
+			}
+			if res.Body != tt.wantBody {
+				t.Errorf("Run(%q).Body = %q, want %q", tt.body, res.Body, tt.wantBody)
+			}
+			var flags []string
+			for _, m := range res.Flags() {
+				flags = append(flags, m.Reason)
+			}
+			if !reflect.DeepEqual(flags, tt.wantFlags) {
+				t.Errorf("Run(%q) flags = %q, want %q", tt.body, flags, tt.wantFlags)
+			}
+		})
+	}
+}
+
+func TestMatchFilterNames(t *testing.T) {
+	p := mustPipeline(t, Lists{
+		Words: []WordRule{{Term: "grift", Action: Flag}},
+		Links: []LinkRule{{Domain: "spam.example", Action: Flag}},
+		Spam:  SpamRules{Action: Flag},
+	})
+	res := p.Run("grift at spam.example")
+	want := []Match{
+		{Filter: "words", Action: Flag, Reason: `contains the word "grift"`},
+		{Filter: "links", Action: Flag, Reason: "links to the blocked domain spam.example"},
+	}
+	if !reflect.DeepEqual(res.Matches, want) {
+		t.Errorf("Matches = %+v, want %+v", res.Matches, want)
+	}
+}
+
+func TestInvalidLists(t *testing.T) {
+	spam := SpamRules{Action: Flag}
+	tests := []struct {
+		name  string
+		lists Lists
+		field string
+	}{
+		{"empty term", Lists{Words: []WordRule{{Term: "?-", Action: Mask}}, Spam: spam}, "words[0].term"},
+		{"duplicate term", Lists{Words: []WordRule{{Term: "fornax", Action: Mask}, {Term: "F0RNAX", Action: Reject}}, Spam: spam}, "words[1].term"},
+		{"unknown action", Lists{Words: []WordRule{{Term: "fornax", Action: "delete"}}, Spam: spam}, "words[0].action"},
+		{"bad regexp", Lists{Patterns: []PatternRule{{Pattern: "(", Action: Flag}}, Spam: spam}, "patterns[0].pattern"},
+		{"pattern matches everything", Lists{Patterns: []PatternRule{{Pattern: ".*", Action: Flag}}, Spam: spam}, "patterns[0].pattern"},
+		{"url instead of domain", Lists{Links: []LinkRule{{Domain: "https://spam.example/", Action: Reject}}, Spam: spam}, "links[0].domain"},
+		{"duplicate domain", Lists{Links: []LinkRule{{Domain: "spam.example", Action: Reject}, {Domain: "*.SPAM.example", Action: Flag}}, Spam: spam}, "links[1].domain"},
+		{"masking spam", Lists{Spam: SpamRules{Action: Mask}}, "spam.action"},
+		{"negative repeats", Lists{Spam: SpamRules{Action: Flag, MaxRepeatedChars: -1}}, "spam.max_repeated_chars"},
+		{"ratio above one", Lists{Spam: SpamRules{Action: Flag, MaxUppercaseRatio: 1.5}}, "spam.max_uppercase_ratio"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.lists.Pipeline()
+			var invalidErr *InvalidError
+			if !errors.As(err, &invalidErr) || invalidErr.Field != tt.field {
+				t.Errorf("Pipeline() error = %v, want an InvalidError for %s", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestManagerFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters.json")
+	if err := os.WriteFile(path, []byte(`{"words": [{"term": "grift", "action": "reject"}], "spam": {"action": "flag"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, rejected := m.Check("grift").Rejection(); !rejected {
+		t.Errorf("grift allowed by the lists from the file")
+	}
+	if got := m.Lists(); got.Links == nil || got.Patterns == nil {
+		t.Errorf("Lists() = %+v, want empty lists rather than nil", got)
+	}
+
+	replaced := DefaultLists()
+	if err := m.Replace(replaced); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	if got := m.Check("grift and fornax").Body; got != "grift and ****" {
+		t.Errorf("Check after Replace = %q", got)
+	}
+	reopened, err := Open(path)
+	if err != nil || !reflect.DeepEqual(reopened.Lists(), replaced) {
+		t.Errorf("reopened lists = %+v, %v, want the replacement saved", reopened.Lists(), err)
+	}
+
+	if err := m.Replace(Lists{Spam: SpamRules{Action: Mask}}); err == nil {
+		t.Errorf("Replace with invalid lists succeeded")
+	}
+	if got := m.Check("fornax").Body; got != "****" {
+		t.Errorf("invalid Replace changed the active lists: %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"words": [{"term": "fornax", "action": "flag"}], "spam": {"action": "flag"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if res := m.Check("fornax"); res.Body != "fornax" || len(res.Flags()) != 1 {
+		t.Errorf("Check after Reload = %+v, want fornax flagged", res)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"wrds": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Reload(); err == nil {
+		t.Errorf("Reload of a bad file succeeded")
+	}
+	if len(m.Check("fornax").Flags()) != 1 {
+		t.Errorf("failed Reload changed the active lists")
+	}
+}
+
+func TestManagerWithoutFile(t *testing.T) {
+	m, err := Open("")
+	if err != nil {
+		t.Fatalf("Open(\"\") error = %v", err)
+	}
+	if !reflect.DeepEqual(m.Lists(), DefaultLists()) {
+		t.Errorf("Lists() = %+v, want the defaults", m.Lists())
+	}
+	if _, err := m.Reload(); !errors.Is(err, ErrNoFile) {
+		t.Errorf("Reload() error = %v, want %v", err, ErrNoFile)
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Open of a missing file succeeded")
+	}
+}
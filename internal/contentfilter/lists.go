@@ -0,0 +1,273 @@
+package contentfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Lists configures every filter. It is what the filter file holds and what
+// the admin endpoints read and replace.
+type Lists struct {
+	Words    []WordRule    `json:"words"`
+	Patterns []PatternRule `json:"patterns"`
+	Links    []LinkRule    `json:"links"`
+	Spam     SpamRules     `json:"spam"`
+}
+
+// DefaultLists are used when no filter file is configured.
+func DefaultLists() Lists {
+	return Lists{
+		Words: []WordRule{
+			{Term: "kerfuffle", Action: Mask},
+			{Term: "sharbert", Action: Mask},
+			{Term: "fornax", Action: Mask},
+		},
+		Patterns: []PatternRule{},
+		Links:    []LinkRule{},
+		Spam:     SpamRules{Action: Flag, MaxRepeatedChars: 10, MaxUppercaseRatio: 0.8},
+	}
+}
+
+// InvalidError reports a rule that can't be used. Field is its JSON path,
+// e.g. words[2].action.
+type InvalidError struct {
+	Field   string
+	Message string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func invalid(field, format string, args ...any) error {
+	return &InvalidError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func checkAction(field string, got Action, allowed ...Action) error {
+	for _, a := range allowed {
+		if got == a {
+			return nil
+		}
+	}
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		names[i] = string(a)
+	}
+	return invalid(field, "must be one of %s", strings.Join(names, ", "))
+}
+
+// Pipeline validates l and builds its filters, run in the order words,
+// patterns, links, spam.
+func (l Lists) Pipeline() (Pipeline, error) {
+	words := WordList{terms: map[string]Action{}}
+	firstSeen := map[string]int{}
+	for i, rule := range l.Words {
+		field := fmt.Sprintf("words[%d]", i)
+		term := normalizeWord(rule.Term)
+		if term == "" {
+			return nil, invalid(field+".term", "must contain a letter or digit")
+		}
+		if j, dup := firstSeen[term]; dup {
+			return nil, invalid(field+".term", "matches the same words as words[%d]", j)
+		}
+		if err := checkAction(field+".action", rule.Action, Mask, Flag, Reject); err != nil {
+			return nil, err
+		}
+		firstSeen[term] = i
+		words.terms[term] = rule.Action
+	}
+
+	var patterns Patterns
+	for i, rule := range l.Patterns {
+		field := fmt.Sprintf("patterns[%d]", i)
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, invalid(field+".pattern", "%v", err)
+		}
+		if re.MatchString("") {
+			return nil, invalid(field+".pattern", "must not match an empty chirp")
+		}
+		if err := checkAction(field+".action", rule.Action, Mask, Flag, Reject); err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern{re: re, rule: rule})
+	}
+
+	links := LinkBlocklist{domains: map[string]Action{}}
+	for i, rule := range l.Links {
+		field := fmt.Sprintf("links[%d]", i)
+		domain := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(rule.Domain, "*."), "."))
+		if m := linkPattern.FindStringSubmatch(domain); m == nil || m[1] != domain {
+			return nil, invalid(field+".domain", "must be a domain name such as example.com")
+		}
+		if _, dup := links.domains[domain]; dup {
+			return nil, invalid(field+".domain", "is listed twice")
+		}
+		if err := checkAction(field+".action", rule.Action, Mask, Flag, Reject); err != nil {
+			return nil, err
+		}
+		links.domains[domain] = rule.Action
+	}
+
+	if err := checkAction("spam.action", l.Spam.Action, Flag, Reject); err != nil {
+		return nil, err
+	}
+	if l.Spam.MaxRepeatedChars < 0 {
+		return nil, invalid("spam.max_repeated_chars", "must not be negative")
+	}
+	if l.Spam.MaxUppercaseRatio < 0 || l.Spam.MaxUppercaseRatio > 1 {
+		return nil, invalid("spam.max_uppercase_ratio", "must be between 0 and 1")
+	}
+
+	return Pipeline{words, patterns, links, Spam{rules: l.Spam}}, nil
+}
+
+// ErrNoFile is returned by Reload when the lists didn't come from a file.
+var ErrNoFile = errors.New("content filter lists are not loaded from a file")
+
+// Manager holds the active lists. Replacing or reloading them takes effect
+// for the next Check without a restart.
+type Manager struct {
+	path string
+	// mu serializes Replace and Reload so the file and the active lists
+	// agree.
+	mu     sync.Mutex
+	active atomic.Pointer[activeLists]
+}
+
+type activeLists struct {
+	lists    Lists
+	pipeline Pipeline
+}
+
+// Open loads the lists from the JSON file at path, or uses DefaultLists when
+// path is empty.
+func Open(path string) (*Manager, error) {
+	m := &Manager{path: path}
+	lists := DefaultLists()
+	if path != "" {
+		var err error
+		if lists, err = readLists(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.activate(lists); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Check runs body through the active pipeline.
+func (m *Manager) Check(body string) Result {
+	return m.active.Load().pipeline.Run(body)
+}
+
+func (m *Manager) Lists() Lists {
+	return m.active.Load().lists
+}
+
+// Replace validates and activates lists, first saving them to the file the
+// Manager was opened from, if any, so they survive a restart.
+func (m *Manager) Replace(lists Lists) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := lists.Pipeline(); err != nil {
+		return err
+	}
+	if m.path != "" {
+		if err := writeLists(m.path, withoutNils(lists)); err != nil {
+			return err
+		}
+	}
+	return m.activate(lists)
+}
+
+// Reload rereads the file, picking up edits made to it directly. The active
+// lists are kept if it can't be read or is invalid.
+func (m *Manager) Reload() (Lists, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.path == "" {
+		return Lists{}, ErrNoFile
+	}
+	lists, err := readLists(m.path)
+	if err != nil {
+		return Lists{}, err
+	}
+	if err := m.activate(lists); err != nil {
+		return Lists{}, fmt.Errorf("%s: %w", m.path, err)
+	}
+	return m.Lists(), nil
+}
+
+func (m *Manager) activate(lists Lists) error {
+	pipeline, err := lists.Pipeline()
+	if err != nil {
+		return err
+	}
+	m.active.Store(&activeLists{lists: withoutNils(lists), pipeline: pipeline})
+	return nil
+}
+
+// withoutNils swaps nil lists for empty ones, so readers of the file and the
+// API always get arrays.
+func withoutNils(lists Lists) Lists {
+	if lists.Words == nil {
+		lists.Words = []WordRule{}
+	}
+	if lists.Patterns == nil {
+		lists.Patterns = []PatternRule{}
+	}
+	if lists.Links == nil {
+		lists.Links = []LinkRule{}
+	}
+	return lists
+}
+
+func readLists(path string) (Lists, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Lists{}, err
+	}
+	var lists Lists
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&lists); err != nil {
+		return Lists{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return Lists{}, fmt.Errorf("%s: unexpected data after JSON object", path)
+	}
+	return lists, nil
+}
+
+// writeLists replaces the file atomically, so a crash mid-write can't leave
+// a truncated list behind.
+func writeLists(path string, lists Lists) error {
+	data, err := json.MarshalIndent(lists, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
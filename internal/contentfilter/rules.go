@@ -0,0 +1,160 @@
+package contentfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type PatternRule struct {
+	// Pattern uses Go's regexp syntax; prefix it with (?i) to ignore case.
+	Pattern string `json:"pattern"`
+	Action  Action `json:"action"`
+	// Reason is shown to the author on rejection and to moderators on a
+	// flag. It defaults to naming the pattern.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Patterns matches regular expressions against the whole body. A masking
+// rule replaces each match.
+type Patterns []pattern
+
+type pattern struct {
+	re   *regexp.Regexp
+	rule PatternRule
+}
+
+func (p Patterns) Name() string { return "patterns" }
+
+func (p Patterns) Check(body string) (string, []Match) {
+	var matches []Match
+	for _, pat := range p {
+		if !pat.re.MatchString(body) {
+			continue
+		}
+		if pat.rule.Action == Mask {
+			body = pat.re.ReplaceAllLiteralString(body, Bleep)
+		}
+		reason := pat.rule.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("matches the pattern %q", pat.rule.Pattern)
+		}
+		matches = append(matches, Match{Action: pat.rule.Action, Reason: reason})
+	}
+	return body, matches
+}
+
+type LinkRule struct {
+	// Domain blocks itself and every subdomain.
+	Domain string `json:"domain"`
+	Action Action `json:"action"`
+}
+
+// LinkBlocklist matches links to listed domains, with or without a scheme.
+// A masking rule replaces the whole link.
+type LinkBlocklist struct {
+	domains map[string]Action
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})\b(?::\d+)?(?:[/?#][^\s]*)?`)
+
+func (l LinkBlocklist) Name() string { return "links" }
+
+func (l LinkBlocklist) Check(body string) (string, []Match) {
+	var (
+		matches []Match
+		seen    = map[string]bool{}
+	)
+	masked := linkPattern.ReplaceAllStringFunc(body, func(link string) string {
+		host := strings.ToLower(linkPattern.FindStringSubmatch(link)[1])
+		domain, action, ok := l.lookup(host)
+		if !ok {
+			return link
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			matches = append(matches, Match{Action: action, Reason: "links to the blocked domain " + domain})
+		}
+		if action == Mask {
+			return Bleep
+		}
+		return link
+	})
+	return masked, matches
+}
+
+// lookup finds host or the closest parent domain on the list.
+func (l LinkBlocklist) lookup(host string) (string, Action, bool) {
+	for {
+		if action, ok := l.domains[host]; ok {
+			return host, action, true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return "", "", false
+		}
+		host = parent
+	}
+}
+
+// minShoutingLetters keeps short chirps like "OK" or "LOL" from counting as
+// shouting.
+const minShoutingLetters = 12
+
+type SpamRules struct {
+	// Action is flag or reject; there's nothing sensible to mask.
+	Action Action `json:"action"`
+	// MaxRepeatedChars is the longest run of one character allowed, as in
+	// "!!!!!!!!". Zero turns the check off.
+	MaxRepeatedChars int `json:"max_repeated_chars"`
+	// MaxUppercaseRatio is the largest share of a chirp's letters that may be
+	// capitals. Zero turns the check off.
+	MaxUppercaseRatio float64 `json:"max_uppercase_ratio"`
+}
+
+// Spam applies SpamRules' heuristics.
+type Spam struct {
+	rules SpamRules
+}
+
+func (s Spam) Name() string { return "spam" }
+
+func (s Spam) Check(body string) (string, []Match) {
+	var matches []Match
+	if limit := s.rules.MaxRepeatedChars; limit > 0 {
+		if run := longestRun(body); run > limit {
+			matches = append(matches, Match{Action: s.rules.Action, Reason: fmt.Sprintf("repeats a character %d times", run)})
+		}
+	}
+	if limit := s.rules.MaxUppercaseRatio; limit > 0 {
+		var letters, upper int
+		for _, r := range body {
+			if unicode.IsLetter(r) {
+				letters++
+				if unicode.IsUpper(r) {
+					upper++
+				}
+			}
+		}
+		if letters >= minShoutingLetters && float64(upper)/float64(letters) > limit {
+			matches = append(matches, Match{Action: s.rules.Action, Reason: "is mostly capital letters"})
+		}
+	}
+	return body, matches
+}
+
+func longestRun(s string) int {
+	longest, run := 0, 0
+	var prev rune
+	for i, r := range s {
+		if i > 0 && r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		longest = max(longest, run)
+	}
+	return longest
+}
@@ -0,0 +1,128 @@
+package contentfilter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type WordRule struct {
+	Term   string `json:"term"`
+	Action Action `json:"action"`
+}
+
+// WordList matches listed words regardless of case, surrounding punctuation,
+// punctuation inside the word and common leet-speak substitutions, so
+// "Kerfuffle!", "k3rfuffl3" and "ker-fuffle" all match "kerfuffle". Words are
+// compared whole: "fornaxes" does not match "fornax".
+type WordList struct {
+	terms map[string]Action
+}
+
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+// normalizeWord folds s to the form terms are compared in: lower case, leet
+// substitutions undone and anything that isn't a letter or digit dropped.
+func normalizeWord(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		r = unicode.ToLower(r)
+		if plain, ok := leet[r]; ok {
+			r = plain
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (w WordList) Name() string { return "words" }
+
+func (w WordList) Check(body string) (string, []Match) {
+	var (
+		out     strings.Builder
+		matches []Match
+		seen    = map[string]bool{}
+	)
+	for _, token := range splitKeepingSpace(body) {
+		term, lead, trail, ok := w.match(token)
+		if !ok {
+			out.WriteString(token)
+			continue
+		}
+		action := w.terms[term]
+		if action == Mask {
+			out.WriteString(lead + Bleep + trail)
+		} else {
+			out.WriteString(token)
+		}
+		if !seen[term] {
+			seen[term] = true
+			matches = append(matches, Match{Action: action, Reason: fmt.Sprintf("contains the word %q", term)})
+		}
+	}
+	return out.String(), matches
+}
+
+// match looks token up with its surrounding punctuation trimmed, then whole
+// in case the punctuation was leet ("$harbert"). lead and trail are what a
+// mask should keep.
+func (w WordList) match(token string) (term, lead, trail string, ok bool) {
+	if strings.TrimFunc(token, unicode.IsSpace) == "" {
+		return "", "", "", false
+	}
+	start := strings.IndexFunc(token, isWordRune)
+	if start >= 0 {
+		end := strings.LastIndexFunc(token, isWordRune)
+		_, size := utf8.DecodeRuneInString(token[end:])
+		end += size
+		term = normalizeWord(token[start:end])
+		if _, ok := w.terms[term]; ok {
+			return term, token[:start], token[end:], true
+		}
+	}
+	term = normalizeWord(token)
+	if _, ok := w.terms[term]; ok {
+		return term, "", "", true
+	}
+	return "", "", "", false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// splitKeepingSpace cuts s into alternating runs of space and non-space, so
+// joining the pieces gives back s.
+func splitKeepingSpace(s string) []string {
+	var pieces []string
+	start, inSpace := 0, false
+	for i, r := range s {
+		space := unicode.IsSpace(r)
+		if i > start && space != inSpace {
+			pieces = append(pieces, s[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	if start < len(s) {
+		pieces = append(pieces, s[start:])
+	}
+	return pieces
+}
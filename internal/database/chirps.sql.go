@@ -20,7 +20,7 @@ VALUES (
     now(),
     now()
 )
-RETURNING id, user_id, body, created_at, updated_at
+RETURNING id, user_id, body, created_at, updated_at, hidden_at
 `
 
 type CreateChirpParams struct {
@@ -37,6 +37,7 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.Body,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
@@ -58,7 +59,8 @@ SELECT
     user_id,
     body,
     created_at,
-    updated_at
+    updated_at,
+    hidden_at
 FROM chirps
 WHERE id = $1
 `
@@ -72,23 +74,28 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.Body,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
 SELECT
-    id,
-    user_id,
-    body,
-    created_at,
-    updated_at
+    chirps.id,
+    chirps.user_id,
+    chirps.body,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.hidden_at
 FROM chirps
-ORDER BY created_at
+JOIN users ON users.id = chirps.user_id
+WHERE (users.shadowbanned_at IS NULL AND chirps.hidden_at IS NULL)
+    OR chirps.user_id = $1
+ORDER BY chirps.created_at
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+func (q *Queries) GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +109,7 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.Body,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -117,19 +125,79 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
+SELECT
+    chirps.id,
+    chirps.user_id,
+    chirps.body,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.hidden_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = $1
+    AND ((users.shadowbanned_at IS NULL AND chirps.hidden_at IS NULL)
+        OR chirps.user_id = $2)
+ORDER BY chirps.created_at
+`
+
+type GetChirpsByAuthorParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsByAuthor(ctx context.Context, arg GetChirpsByAuthorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, arg.UserID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Body,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentChirpsByAuthor = `-- name: GetRecentChirpsByAuthor :many
 SELECT
     id,
     user_id,
     body,
     created_at,
-    updated_at
+    updated_at,
+    hidden_at
 FROM chirps
 WHERE user_id = $1
-ORDER BY created_at
+    AND created_at > now() - make_interval(secs => $2::int)
+ORDER BY created_at DESC
+LIMIT $3
 `
 
-func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, userID)
+type GetRecentChirpsByAuthorParams struct {
+	UserID        uuid.UUID
+	WindowSeconds int32
+	RowLimit      int32
+}
+
+func (q *Queries) GetRecentChirpsByAuthor(ctx context.Context, arg GetRecentChirpsByAuthorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentChirpsByAuthor, arg.UserID, arg.WindowSeconds, arg.RowLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -143,6 +211,7 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.Body,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -156,3 +225,25 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const hideChirp = `-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, now())
+WHERE id = $1
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, hideChirp, id)
+	return err
+}
+
+const unhideChirp = `-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) UnhideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unhideChirp, id)
+	return err
+}
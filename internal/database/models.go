@@ -6,6 +6,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,95 @@ type Chirp struct {
 	Body      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	HiddenAt  sql.NullTime
+}
+
+type Job struct {
+	ID          uuid.UUID
+	Kind        string
+	Payload     json.RawMessage
+	Status      string
+	Attempts    int32
+	MaxAttempts int32
+	RunAt       time.Time
+	UniqueKey   sql.NullString
+	LockedAt    sql.NullTime
+	LastError   sql.NullString
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	ModeratorID uuid.NullUUID
+	Action      string
+	Reason      string
+	ExpiresAt   sql.NullTime
+	CreatedAt   time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Kind      string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+}
+
+type OAuthAuthorizationCode struct {
+	CodeHash      string
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        string
+	CodeChallenge string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type OAuthClient struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris string
+	CreatedAt    time.Time
+}
+
+type OutboxEvent struct {
+	ID            int64
+	AggregateType string
+	AggregateID   uuid.UUID
+	EventType     string
+	Payload       json.RawMessage
+	CreatedAt     time.Time
+	DispatchedAt  sql.NullTime
+	Attempts      int32
+	LastError     sql.NullString
+	MaxAttempts   int32
+	AvailableAt   sql.NullTime
+	DeadAt        sql.NullTime
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt time.Time
+	FullAt    time.Time
 }
 
 type RefreshToken struct {
@@ -26,6 +116,37 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	ClientID  uuid.NullUUID
+	Scopes    sql.NullString
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CaseID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
+	Resolution sql.NullString
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+}
+
+type ReportCase struct {
+	ID             uuid.UUID
+	ChirpID        uuid.NullUUID
+	AuthorID       uuid.UUID
+	ReportCount    int32
+	ClaimedBy      uuid.NullUUID
+	ClaimedAt      sql.NullTime
+	Resolution     sql.NullString
+	ResolutionNote string
+	ResolvedBy     uuid.NullUUID
+	ResolvedAt     sql.NullTime
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FlagReason     string
+	HidChirp       bool
 }
 
 type User struct {
@@ -35,4 +156,8 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Role           string
+	SuspendedUntil sql.NullTime
+	BannedAt       sql.NullTime
+	ShadowbannedAt sql.NullTime
 }
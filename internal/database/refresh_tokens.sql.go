@@ -7,30 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
 const createToken = `-- name: CreateToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes)
 VALUES (
     $1,
     now(),
     now(),
     $2,
     now() + interval '60 days',
-    NULL
+    NULL,
+    $3,
+    $4
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes
 `
 
 type CreateTokenParams struct {
-	Token  string
-	UserID uuid.UUID
+	Token    string
+	UserID   uuid.UUID
+	ClientID uuid.NullUUID
+	Scopes   sql.NullString
 }
 
 func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createToken, arg.Token, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createToken,
+		arg.Token,
+		arg.UserID,
+		arg.ClientID,
+		arg.Scopes,
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -39,10 +49,26 @@ func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) (Refre
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ClientID,
+		&i.Scopes,
 	)
 	return i, err
 }
 
+const deleteStaleTokens = `-- name: DeleteStaleTokens :execrows
+DELETE
+FROM refresh_tokens
+WHERE expires_at < now() OR revoked_at IS NOT NULL
+`
+
+func (q *Queries) DeleteStaleTokens(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStaleTokens)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getToken = `-- name: GetToken :one
 SELECT
     token,
@@ -50,7 +76,9 @@ SELECT
     updated_at,
     user_id,
     expires_at,
-    revoked_at
+    revoked_at,
+    client_id,
+    scopes
 FROM refresh_tokens
 WHERE token = $1
 `
@@ -65,6 +93,8 @@ func (q *Queries) GetToken(ctx context.Context, token string) (RefreshToken, err
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ClientID,
+		&i.Scopes,
 	)
 	return i, err
 }
@@ -73,7 +103,7 @@ const revokeToken = `-- name: RevokeToken :one
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
 WHERE token = $1
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes
 `
 
 func (q *Queries) RevokeToken(ctx context.Context, token string) (RefreshToken, error) {
@@ -86,6 +116,19 @@ func (q *Queries) RevokeToken(ctx context.Context, token string) (RefreshToken,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ClientID,
+		&i.Scopes,
 	)
 	return i, err
 }
+
+const revokeUserTokens = `-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserTokens, userID)
+	return err
+}
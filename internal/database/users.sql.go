@@ -20,7 +20,7 @@ VALUES (
     $1,
     $2
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, banned_at, shadowbanned_at
 `
 
 type CreateUserParams struct {
@@ -38,6 +38,10 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.BannedAt,
+		&i.ShadowbannedAt,
 	)
 	return i, err
 }
@@ -58,7 +62,11 @@ SELECT
     updated_at,
     email,
     hashed_password,
-    is_chirpy_red
+    is_chirpy_red,
+    role,
+    suspended_until,
+    banned_at,
+    shadowbanned_at
 FROM users
 WHERE email = $1
 `
@@ -73,6 +81,44 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.BannedAt,
+		&i.ShadowbannedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT
+    id,
+    created_at,
+    updated_at,
+    email,
+    hashed_password,
+    is_chirpy_red,
+    role,
+    suspended_until,
+    banned_at,
+    shadowbanned_at
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.BannedAt,
+		&i.ShadowbannedAt,
 	)
 	return i, err
 }
@@ -95,7 +141,7 @@ SET
     hashed_password = $2, 
     updated_at = NOW()
 WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, banned_at, shadowbanned_at
 `
 
 type UpdateUserCredsParams struct {
@@ -114,6 +160,42 @@ func (q *Queries) UpdateUserCreds(ctx context.Context, arg UpdateUserCredsParams
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.BannedAt,
+		&i.ShadowbannedAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET
+    role = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, banned_at, shadowbanned_at
+`
+
+type UpdateUserRoleParams struct {
+	Role string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Role, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.BannedAt,
+		&i.ShadowbannedAt,
 	)
 	return i, err
 }
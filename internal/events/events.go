@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AggregateChirp  = "chirp"
+	AggregateReport = "report"
+	AggregateUser   = "user"
+)
+
+const (
+	ChirpCreated   = "chirp.created"
+	ChirpDeleted   = "chirp.deleted"
+	ReportResolved = "report.resolved"
+	UserCreated    = "user.created"
+	UserUpgraded   = "user.upgraded"
+)
+
+// AllEvents can be passed to Subscribe to receive every event type.
+const AllEvents = "*"
+
+type Event struct {
+	ID            int64
+	AggregateType string
+	AggregateID   uuid.UUID
+	Type          string
+	Payload       json.RawMessage
+	CreatedAt     time.Time
+}
+
+// Handler reacts to a single event. Delivery is at-least-once, so handlers
+// must tolerate seeing the same event more than once.
+type Handler func(ctx context.Context, e Event) error
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]subscriber
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: map[string][]subscriber{}}
+}
+
+func (b *Bus) Subscribe(name, eventType string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], subscriber{name: name, handler: h})
+}
+
+// Publish delivers e to every matching subscriber in registration order and
+// returns the joined errors of the subscribers that failed.
+func (b *Bus) Publish(ctx context.Context, e Event) error {
+	b.mu.RLock()
+	subs := append([]subscriber{}, b.subscribers[e.Type]...)
+	subs = append(subs, b.subscribers[AllEvents]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subs {
+		if err := s.handler(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
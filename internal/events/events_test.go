@@ -0,0 +1,85 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBusPublish(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		wantCalls []string
+		wantErr   bool
+	}{
+		{
+			name:      "matchingAndWildcard",
+			eventType: ChirpCreated,
+			wantCalls: []string{"chirps", "all"},
+			wantErr:   false,
+		},
+		{
+			name:      "wildcardOnly",
+			eventType: UserUpgraded,
+			wantCalls: []string{"all"},
+			wantErr:   false,
+		},
+		{
+			name:      "failingSubscriber",
+			eventType: ChirpDeleted,
+			wantCalls: []string{"failing", "all"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			record := func(name string, err error) Handler {
+				return func(ctx context.Context, e Event) error {
+					calls = append(calls, name)
+					return err
+				}
+			}
+
+			bus := NewBus()
+			bus.Subscribe("chirps", ChirpCreated, record("chirps", nil))
+			bus.Subscribe("failing", ChirpDeleted, record("failing", errors.New("boom")))
+			bus.Subscribe("all", AllEvents, record("all", nil))
+
+			err := bus.Publish(context.Background(), Event{Type: tt.eventType, AggregateID: uuid.New()})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Publish error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("Publish called %v, want %v", calls, tt.wantCalls)
+			}
+			for i := range calls {
+				if calls[i] != tt.wantCalls[i] {
+					t.Errorf("Publish called %v, want %v", calls, tt.wantCalls)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{30, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
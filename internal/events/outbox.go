@@ -0,0 +1,261 @@
+package events
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/tracing"
+	"github.com/google/uuid"
+)
+
+// dispatchLockKey identifies the advisory lock that keeps a single dispatcher
+// draining the outbox at a time, which is what preserves per-aggregate order
+// when several instances are running.
+const dispatchLockKey int64 = 0x63686972707901
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	staleHeartbeatPolls = 5
+	// claimTimeout is how long a claimed batch is reserved for the
+	// dispatcher that claimed it. If it crashes, the events become
+	// available again afterwards.
+	claimTimeout = 5 * time.Minute
+	// deliveryTimeout bounds delivering a batch, leaving time to record the
+	// outcome before the claim runs out.
+	deliveryTimeout = 4 * time.Minute
+	maxRetryDelay   = 5 * time.Minute
+)
+
+// Recorder is satisfied by database.Queries and store.OutboxRepository.
+type Recorder interface {
+	CreateOutboxEvent(ctx context.Context, arg database.CreateOutboxEventParams) (database.OutboxEvent, error)
+}
+
+// Record writes an event to the outbox. Pass a Recorder bound to the same
+// transaction as the write it describes so both commit or roll back together.
+func Record(ctx context.Context, q Recorder, aggregateType string, aggregateID uuid.UUID, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       data,
+	})
+	return err
+}
+
+type Dispatcher struct {
+	db           *sql.DB
+	bus          *Bus
+	pollInterval time.Duration
+	batchSize    int32
+
+	stop context.CancelFunc
+	done chan struct{}
+	// lastPoll is the UnixNano time the run loop last finished a pass.
+	lastPoll atomic.Int64
+}
+
+func NewDispatcher(db *sql.DB, bus *Bus, pollInterval time.Duration) *Dispatcher {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	return &Dispatcher{
+		db:           db,
+		bus:          bus,
+		pollInterval: pollInterval,
+		batchSize:    defaultBatchSize,
+	}
+}
+
+// Start runs the dispatcher in the background until Shutdown is called.
+func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.stop = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+	go func() {
+		defer close(d.done)
+		d.Run(ctx)
+	}()
+}
+
+// Shutdown stops polling and waits for the batch in progress to finish or for
+// ctx to expire. Undelivered events stay in the outbox for the next start.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	if d == nil || d.stop == nil {
+		return nil
+	}
+	d.stop()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Healthy reports an error if the dispatcher isn't running or its poll loop
+// has not completed a pass recently.
+func (d *Dispatcher) Healthy() error {
+	if d.stop == nil {
+		return errors.New("dispatcher not started")
+	}
+	return checkHeartbeat(d.lastPoll.Load(), d.pollInterval)
+}
+
+// checkHeartbeat fails if the unix-nano timestamp last is more than a few poll
+// intervals old. A loop that hasn't finished its first pass yet counts as
+// stale too.
+func checkHeartbeat(last int64, pollInterval time.Duration) error {
+	if last == 0 {
+		return errors.New("no poll completed yet")
+	}
+	if since := time.Since(time.Unix(0, last)); since > staleHeartbeatPolls*pollInterval {
+		return fmt.Errorf("last poll completed %s ago", since.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DispatchBatch(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "dispatching outbox events", "error", err)
+		}
+		d.lastPoll.Store(time.Now().UnixNano())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchBatch delivers one batch of pending events. Events are delivered in
+// insertion order; once an event for an aggregate fails, later events for the
+// same aggregate are held back until it succeeds or is given up on as dead.
+//
+// The batch is claimed under the dispatch lock in a short transaction and
+// delivered after it commits, so slow subscribers hold neither a connection
+// nor the lock.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) error {
+	claimed, err := d.claim(ctx)
+	if err != nil || len(claimed) == 0 {
+		return err
+	}
+	slices.SortFunc(claimed, func(a, b database.OutboxEvent) int { return cmp.Compare(a.ID, b.ID) })
+
+	// Delivery has to stop before the claim runs out, or another dispatcher
+	// could pick the same events up while they're still being delivered.
+	deliverCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	// Recording the outcome outlives a shutdown, so claims are released
+	// rather than left to expire.
+	recordCtx := context.WithoutCancel(ctx)
+	q := database.New(tracing.WrapDB(d.db, "postgresql"))
+
+	var errs []error
+	failed := map[uuid.UUID]bool{}
+	for _, row := range claimed {
+		if failed[row.AggregateID] || deliverCtx.Err() != nil {
+			errs = append(errs, q.ReleaseOutboxEvent(recordCtx, row.ID))
+			continue
+		}
+
+		e := Event{
+			ID:            row.ID,
+			AggregateType: row.AggregateType,
+			AggregateID:   row.AggregateID,
+			Type:          row.EventType,
+			Payload:       row.Payload,
+			CreatedAt:     row.CreatedAt,
+		}
+		if err := d.bus.Publish(deliverCtx, e); err != nil {
+			failed[row.AggregateID] = true
+			errs = append(errs, d.markFailed(recordCtx, q, row, err))
+			continue
+		}
+		slog.DebugContext(ctx, "outbox event delivered",
+			"event_id", e.ID,
+			"event_type", e.Type,
+			"aggregate_type", e.AggregateType,
+			"aggregate_id", e.AggregateID,
+		)
+		errs = append(errs, q.MarkOutboxEventDispatched(recordCtx, row.ID))
+	}
+	return errors.Join(errs...)
+}
+
+// claim takes the dispatch lock and claims the next batch. It returns nothing
+// if another dispatcher holds the lock.
+func (d *Dispatcher) claim(ctx context.Context) ([]database.OutboxEvent, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := database.New(tracing.WrapDB(tx, "postgresql"))
+	locked, err := q.TryOutboxDispatchLock(ctx, dispatchLockKey)
+	if err != nil || !locked {
+		return nil, err
+	}
+	claimed, err := q.ClaimOutboxEvents(ctx, database.ClaimOutboxEventsParams{
+		ClaimSeconds: int32(claimTimeout.Seconds()),
+		BatchSize:    d.batchSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, tx.Commit()
+}
+
+func (d *Dispatcher) markFailed(ctx context.Context, q *database.Queries, row database.OutboxEvent, deliveryErr error) error {
+	failed, err := q.MarkOutboxEventFailed(ctx, database.MarkOutboxEventFailedParams{
+		LastError:    sql.NullString{String: deliveryErr.Error(), Valid: true},
+		RetrySeconds: int32(retryDelay(row.Attempts + 1).Seconds()),
+		ID:           row.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if failed.DeadAt.Valid {
+		slog.ErrorContext(ctx, "giving up on outbox event",
+			"event_id", row.ID,
+			"event_type", row.EventType,
+			"aggregate_type", row.AggregateType,
+			"aggregate_id", row.AggregateID,
+			"attempts", failed.Attempts,
+			"error", deliveryErr,
+		)
+	}
+	return nil
+}
+
+// retryDelay is how long to wait before retrying an event that has failed
+// attempts times: doubling from a second, capped at maxRetryDelay.
+func retryDelay(attempts int32) time.Duration {
+	d := time.Second
+	for i := int32(1); i < attempts && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryDelay)
+}
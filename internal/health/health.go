@@ -0,0 +1,122 @@
+// Package health runs readiness checks against Chirpy's dependencies.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc returns nil when the dependency it checks is usable.
+type CheckFunc func(ctx context.Context) error
+
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Duration is how long the check took, in milliseconds.
+	Duration int64 `json:"duration_ms"`
+}
+
+type Report struct {
+	Status    string            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// OK reports whether every check passed.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs its checks concurrently, each bounded by timeout, and reuses
+// the last report for ttl so frequent probes from several load balancers
+// don't each cost a round of database queries.
+type Checker struct {
+	timeout time.Duration
+	ttl     time.Duration
+	now     func() time.Time
+
+	checks []check
+
+	mu   sync.Mutex
+	last Report
+}
+
+func NewChecker(timeout, ttl time.Duration) *Checker {
+	return &Checker{timeout: timeout, ttl: ttl, now: time.Now}
+}
+
+// Add registers a check. It must be called before the first Run.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Run returns a fresh report, or the cached one if it is younger than the
+// ttl. Concurrent callers wait for a single run rather than starting their
+// own. The returned report must not be modified.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.last.CheckedAt.IsZero() && c.now().Sub(c.last.CheckedAt) < c.ttl {
+		return c.last
+	}
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.runOne(ctx, chk.fn)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusOK,
+		Checks:    make(map[string]Result, len(c.checks)),
+		CheckedAt: c.now().UTC(),
+	}
+	for i, chk := range c.checks {
+		report.Checks[chk.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	c.last = report
+	return report
+}
+
+func (c *Checker) runOne(ctx context.Context, fn CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := c.now()
+	errc := make(chan error, 1)
+	go func() { errc <- fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Status: StatusOK, Duration: c.now().Sub(start).Milliseconds()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
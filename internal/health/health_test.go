@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckerRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]CheckFunc
+		wantStatus string
+		wantErrs   map[string]string
+	}{
+		{
+			name: "allPass",
+			checks: map[string]CheckFunc{
+				"database":   func(context.Context) error { return nil },
+				"migrations": func(context.Context) error { return nil },
+			},
+			wantStatus: StatusOK,
+			wantErrs:   map[string]string{},
+		},
+		{
+			name: "oneFails",
+			checks: map[string]CheckFunc{
+				"database":   func(context.Context) error { return errors.New("connection refused") },
+				"migrations": func(context.Context) error { return nil },
+			},
+			wantStatus: StatusFail,
+			wantErrs:   map[string]string{"database": "connection refused"},
+		},
+		{
+			name: "timeout",
+			checks: map[string]CheckFunc{
+				"database": func(ctx context.Context) error {
+					time.Sleep(time.Second)
+					return nil
+				},
+			},
+			wantStatus: StatusFail,
+			wantErrs:   map[string]string{"database": context.DeadlineExceeded.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(50*time.Millisecond, time.Second)
+			for name, fn := range tt.checks {
+				c.Add(name, fn)
+			}
+
+			report := c.Run(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("got %d check results, want %d", len(report.Checks), len(tt.checks))
+			}
+			for name, res := range report.Checks {
+				if res.Error != tt.wantErrs[name] {
+					t.Errorf("%s error = %q, want %q", name, res.Error, tt.wantErrs[name])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckerCache(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewChecker(time.Second, 2*time.Second)
+	c.now = func() time.Time { return now }
+
+	var calls atomic.Int32
+	c.Add("database", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	c.Run(context.Background())
+	now = now.Add(time.Second)
+	c.Run(context.Background())
+	if got := calls.Load(); got != 1 {
+		t.Errorf("check ran %d times within ttl, want 1", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	c.Run(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Errorf("check ran %d times after ttl expired, want 2", got)
+	}
+}
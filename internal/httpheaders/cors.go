@@ -0,0 +1,180 @@
+package httpheaders
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CORSOptions struct {
+	// AllowedOrigins lists origins such as https://chirpy.example.com. "*"
+	// allows any origin and https://*.example.com any subdomain.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// CORS answers preflight requests and adds Access-Control-* headers to
+// responses for allowed origins. Requests from other origins are served
+// without them, so browsers refuse to expose the response.
+type CORS struct {
+	opts      CORSOptions
+	anyOrigin bool
+	origins   map[string]bool
+	// suffixes holds wildcard origins as scheme plus "." plus domain, e.g.
+	// "https://.example.com".
+	suffixes []string
+	methods  string
+	headers  map[string]bool
+}
+
+// NewCORS validates opts. It returns nil, which disables CORS, when no
+// origins are allowed.
+func NewCORS(opts CORSOptions) (*CORS, error) {
+	if len(opts.AllowedOrigins) == 0 {
+		return nil, nil
+	}
+
+	c := &CORS{
+		opts:    opts,
+		origins: map[string]bool{},
+		methods: strings.Join(opts.AllowedMethods, ", "),
+		headers: map[string]bool{},
+	}
+	var errs []error
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			c.anyOrigin = true
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("cors origin %q must be scheme://host[:port]", origin))
+			continue
+		}
+		origin = strings.ToLower(u.Scheme + "://" + u.Host)
+		if rest, ok := strings.CutPrefix(u.Host, "*."); ok {
+			c.suffixes = append(c.suffixes, strings.ToLower(u.Scheme+"://."+rest))
+			continue
+		}
+		c.origins[origin] = true
+	}
+	if c.anyOrigin && opts.AllowCredentials {
+		errs = append(errs, errors.New(`cors origin "*" can't be combined with credentials`))
+	}
+	for _, h := range opts.AllowedHeaders {
+		c.headers[http.CanonicalHeaderKey(strings.TrimSpace(h))] = true
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// IsPreflight reports whether r is a CORS preflight request.
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func (c *CORS) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if c.origins[origin] {
+		return true
+	}
+	for _, suffix := range c.suffixes {
+		scheme, domain, _ := strings.Cut(suffix, "://")
+		host, ok := strings.CutPrefix(origin, scheme+"://")
+		if ok && strings.HasSuffix(host, domain) && len(host) > len(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) allowMethod(method string) bool {
+	for _, m := range c.opts.AllowedMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// setOrigin writes the headers shared by preflight and actual responses.
+func (c *CORS) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin && !c.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// preflight answers a preflight request. Disallowed origins, methods and
+// headers get a bare 204, which browsers treat as a refusal.
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	if !c.allowOrigin(origin) || !c.allowMethod(r.Header.Get("Access-Control-Request-Method")) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	var requested []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if !c.headers[name] {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			requested = append(requested, name)
+		}
+	}
+
+	c.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", c.methods)
+	if len(requested) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if c.opts.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.opts.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// annotate adds CORS headers to the response for an actual request.
+func (c *CORS) annotate(h http.Header, r *http.Request) {
+	if !c.anyOrigin || c.opts.AllowCredentials {
+		h.Add("Vary", "Origin")
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.allowOrigin(origin) {
+		return
+	}
+	c.setOrigin(h, origin)
+	if len(c.opts.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(c.opts.ExposedHeaders, ", "))
+	}
+}
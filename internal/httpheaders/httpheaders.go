@@ -0,0 +1,68 @@
+// Package httpheaders applies CORS and security headers, with the policy
+// chosen per route.
+package httpheaders
+
+import (
+	"net/http"
+)
+
+// Policy is the set of headers for a route. A nil CORS disables
+// cross-origin access to it.
+type Policy struct {
+	CORS     *CORS
+	Security Security
+}
+
+// Headers looks up the route a request will be served by and applies that
+// route's policy, or the default one, before passing it to the mux. Preflight
+// requests are answered here because the mux only knows the routes by their
+// real methods.
+type Headers struct {
+	mux       *http.ServeMux
+	def       Policy
+	overrides map[string]Policy
+	isHTTPS   func(r *http.Request) bool
+}
+
+// New wraps mux. isHTTPS reports whether a request reached the service over
+// TLS, which decides whether HSTS is sent.
+func New(mux *http.ServeMux, def Policy, isHTTPS func(r *http.Request) bool) *Headers {
+	return &Headers{mux: mux, def: def, overrides: map[string]Policy{}, isHTTPS: isHTTPS}
+}
+
+// Override replaces the default policy for the route registered with
+// pattern, which must be written exactly as it was passed to the mux.
+func (h *Headers) Override(pattern string, p Policy) {
+	h.overrides[pattern] = p
+}
+
+func (h *Headers) policy(pattern string) Policy {
+	if p, ok := h.overrides[pattern]; ok {
+		return p
+	}
+	return h.def
+}
+
+func (h *Headers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	preflight := IsPreflight(r)
+	probe := r
+	if preflight {
+		// Find the route the real request would hit.
+		probe = r.WithContext(r.Context())
+		probe.Method = r.Header.Get("Access-Control-Request-Method")
+	}
+	_, pattern := h.mux.Handler(probe)
+	p := h.policy(pattern)
+	p.Security.set(w.Header(), h.isHTTPS(r))
+
+	if preflight && pattern != "" && p.CORS != nil {
+		// Label the preflight with the route it was for in logs and metrics.
+		r.Pattern = pattern
+		p.CORS.preflight(w, r)
+		return
+	}
+	if p.CORS != nil {
+		p.CORS.annotate(w.Header(), r)
+	}
+	h.mux.ServeHTTP(w, r)
+}
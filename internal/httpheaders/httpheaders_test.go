@@ -0,0 +1,202 @@
+package httpheaders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHeaders(t *testing.T, opts CORSOptions) *Headers {
+	t.Helper()
+	cors, err := NewCORS(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	mux.HandleFunc("POST /api/chirps", ok)
+	mux.HandleFunc("POST /api/webhooks", ok)
+	mux.HandleFunc("/app/", ok)
+
+	h := New(mux, Policy{
+		CORS:     cors,
+		Security: Security{ContentSecurityPolicy: "default-src 'none'", FrameOptions: "DENY", HSTSMaxAge: time.Hour},
+	}, func(r *http.Request) bool { return r.Header.Get("X-Forwarded-Proto") == "https" })
+	h.Override("POST /api/webhooks", Policy{})
+	h.Override("/app/", Policy{Security: Security{ContentSecurityPolicy: "default-src 'self'"}})
+	return h
+}
+
+func TestHeaders(t *testing.T) {
+	opts := CORSOptions{
+		AllowedOrigins: []string{"https://web.example", "https://*.partner.example"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		wantStatus int
+		want       map[string]string
+	}{
+		{
+			name:       "preflightAllowed",
+			method:     http.MethodOptions,
+			path:       "/api/chirps",
+			header:     map[string]string{"Origin": "https://web.example", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "content-type, authorization"},
+			wantStatus: http.StatusNoContent,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "https://web.example",
+				"Access-Control-Allow-Methods": "GET, POST",
+				"Access-Control-Allow-Headers": "Content-Type, Authorization",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name:       "preflightWildcardSubdomain",
+			method:     http.MethodOptions,
+			path:       "/api/chirps",
+			header:     map[string]string{"Origin": "https://app.partner.example", "Access-Control-Request-Method": "POST"},
+			wantStatus: http.StatusNoContent,
+			want:       map[string]string{"Access-Control-Allow-Origin": "https://app.partner.example"},
+		},
+		{
+			name:       "preflightUnknownOrigin",
+			method:     http.MethodOptions,
+			path:       "/api/chirps",
+			header:     map[string]string{"Origin": "https://evil.example", "Access-Control-Request-Method": "POST"},
+			wantStatus: http.StatusNoContent,
+			want:       map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:       "preflightDisallowedHeader",
+			method:     http.MethodOptions,
+			path:       "/api/chirps",
+			header:     map[string]string{"Origin": "https://web.example", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "X-Debug"},
+			wantStatus: http.StatusNoContent,
+			want:       map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:       "preflightRouteWithoutCORS",
+			method:     http.MethodOptions,
+			path:       "/api/webhooks",
+			header:     map[string]string{"Origin": "https://web.example", "Access-Control-Request-Method": "POST"},
+			wantStatus: http.StatusMethodNotAllowed,
+			want:       map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:       "actualRequest",
+			method:     http.MethodPost,
+			path:       "/api/chirps",
+			header:     map[string]string{"Origin": "https://web.example"},
+			wantStatus: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":   "https://web.example",
+				"Access-Control-Expose-Headers": "X-Request-ID",
+				"Vary":                          "Origin",
+				"Content-Security-Policy":       "default-src 'none'",
+				"X-Frame-Options":               "DENY",
+				"X-Content-Type-Options":        "nosniff",
+				"Strict-Transport-Security":     "",
+			},
+		},
+		{
+			name:       "hstsOverHTTPS",
+			method:     http.MethodPost,
+			path:       "/api/chirps",
+			header:     map[string]string{"X-Forwarded-Proto": "https"},
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"Strict-Transport-Security": "max-age=3600; includeSubDomains"},
+		},
+		{
+			name:       "appOverride",
+			method:     http.MethodGet,
+			path:       "/app/index.html",
+			header:     map[string]string{"Origin": "https://web.example"},
+			wantStatus: http.StatusOK,
+			want: map[string]string{
+				"Content-Security-Policy":     "default-src 'self'",
+				"X-Frame-Options":             "",
+				"X-Content-Type-Options":      "nosniff",
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+	}
+
+	h := newTestHeaders(t, opts)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for k, v := range tt.want {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestNewCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CORSOptions
+		wantNil bool
+		wantErr string
+	}{
+		{name: "disabled", opts: CORSOptions{}, wantNil: true},
+		{name: "anyOrigin", opts: CORSOptions{AllowedOrigins: []string{"*"}}},
+		{name: "anyOriginWithCredentials", opts: CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}, wantErr: "credentials"},
+		{name: "path", opts: CORSOptions{AllowedOrigins: []string{"https://web.example/app"}}, wantErr: "scheme://host"},
+		{name: "bareHost", opts: CORSOptions{AllowedOrigins: []string{"web.example"}}, wantErr: "scheme://host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCORS(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (c == nil) != tt.wantNil {
+				t.Errorf("NewCORS = %v, wantNil %v", c, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestAnyOriginWithoutCredentials(t *testing.T) {
+	h := newTestHeaders(t, CORSOptions{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"POST"}})
+	r := httptest.NewRequest(http.MethodPost, "/api/chirps", nil)
+	r.Header.Set("Origin", "https://anywhere.example")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "" {
+		t.Errorf("Vary = %q, want none for a wildcard origin", got)
+	}
+}
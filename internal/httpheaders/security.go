@@ -0,0 +1,34 @@
+package httpheaders
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Security lists the security headers sent with every response. Empty fields
+// are left out; X-Content-Type-Options: nosniff is always sent.
+type Security struct {
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+	// HSTSMaxAge is sent as Strict-Transport-Security, but only on responses
+	// to requests that arrived over HTTPS.
+	HSTSMaxAge time.Duration
+}
+
+func (s Security) set(h http.Header, https bool) {
+	h.Set("X-Content-Type-Options", "nosniff")
+	if s.ContentSecurityPolicy != "" {
+		h.Set("Content-Security-Policy", s.ContentSecurityPolicy)
+	}
+	if s.FrameOptions != "" {
+		h.Set("X-Frame-Options", s.FrameOptions)
+	}
+	if s.ReferrerPolicy != "" {
+		h.Set("Referrer-Policy", s.ReferrerPolicy)
+	}
+	if https && s.HSTSMaxAge > 0 {
+		h.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(s.HSTSMaxAge.Seconds()))+"; includeSubDomains")
+	}
+}
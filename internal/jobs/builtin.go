@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/store"
+)
+
+const (
+	PruneRefreshTokens      = "refresh_tokens.prune"
+	PruneAuthorizationCodes = "oauth_codes.prune"
+	PruneRateLimits         = "rate_limits.prune"
+	PruneOutboxEvents       = "outbox_events.prune"
+	PruneJobs               = "jobs.prune"
+)
+
+const (
+	pruneRefreshTokensEvery      = time.Hour
+	pruneAuthorizationCodesEvery = time.Hour
+	pruneRateLimitsEvery         = time.Hour
+	pruneOutboxEventsEvery       = time.Hour
+	pruneJobsEvery               = time.Hour
+	// outboxRetention is how long dispatched and dead events are kept
+	// around for debugging before they are pruned.
+	outboxRetention = 7 * 24 * time.Hour
+	// succeededJobRetention is how long succeeded jobs stay listed in the
+	// admin API. Dead jobs are kept until an admin retries them.
+	succeededJobRetention = 7 * 24 * time.Hour
+)
+
+type pruneRefreshTokensArgs struct{}
+
+type pruneAuthorizationCodesArgs struct{}
+
+type pruneRateLimitsArgs struct{}
+
+type pruneOutboxEventsArgs struct{}
+
+type pruneJobsArgs struct{}
+
+// RateLimitBuckets is satisfied by database.Queries.
+type RateLimitBuckets interface {
+	DeleteFullRateLimitBuckets(ctx context.Context) (int64, error)
+}
+
+// OutboxEvents is satisfied by database.Queries.
+type OutboxEvents interface {
+	DeleteSettledOutboxEvents(ctx context.Context, retentionSeconds int32) (int64, error)
+}
+
+// RegisterBuiltins installs the handlers and schedules Chirpy always runs.
+func RegisterBuiltins(q *Queue, tokens store.RefreshTokenRepository, oauth store.OAuthRepository, outbox OutboxEvents) {
+	Register(q, PruneRefreshTokens, func(ctx context.Context, _ pruneRefreshTokensArgs) error {
+		deleted, err := tokens.DeleteStaleTokens(ctx)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "pruned expired or revoked refresh tokens", "job_kind", PruneRefreshTokens, "count", deleted)
+		return nil
+	})
+	q.Schedule(PruneRefreshTokens, pruneRefreshTokensEvery, pruneRefreshTokensArgs{})
+
+	Register(q, PruneAuthorizationCodes, func(ctx context.Context, _ pruneAuthorizationCodesArgs) error {
+		deleted, err := oauth.DeleteExpiredOAuthAuthorizationCodes(ctx)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "pruned expired OAuth authorization codes", "job_kind", PruneAuthorizationCodes, "count", deleted)
+		return nil
+	})
+	q.Schedule(PruneAuthorizationCodes, pruneAuthorizationCodesEvery, pruneAuthorizationCodesArgs{})
+
+	Register(q, PruneOutboxEvents, func(ctx context.Context, _ pruneOutboxEventsArgs) error {
+		deleted, err := outbox.DeleteSettledOutboxEvents(ctx, int32(outboxRetention.Seconds()))
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "pruned dispatched or dead outbox events", "job_kind", PruneOutboxEvents, "count", deleted)
+		return nil
+	})
+	q.Schedule(PruneOutboxEvents, pruneOutboxEventsEvery, pruneOutboxEventsArgs{})
+
+	Register(q, PruneJobs, func(ctx context.Context, _ pruneJobsArgs) error {
+		deleted, err := q.db.DeleteSucceededJobs(ctx, int32(succeededJobRetention.Seconds()))
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "pruned succeeded jobs", "job_kind", PruneJobs, "count", deleted)
+		return nil
+	})
+	q.Schedule(PruneJobs, pruneJobsEvery, pruneJobsArgs{})
+}
+
+// RegisterRateLimitPrune deletes shared rate limit buckets that have refilled.
+// It is only needed with the postgres rate limit backend.
+func RegisterRateLimitPrune(q *Queue, buckets RateLimitBuckets) {
+	Register(q, PruneRateLimits, func(ctx context.Context, _ pruneRateLimitsArgs) error {
+		deleted, err := buckets.DeleteFullRateLimitBuckets(ctx)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "pruned idle rate limit buckets", "job_kind", PruneRateLimits, "count", deleted)
+		return nil
+	})
+	q.Schedule(PruneRateLimits, pruneRateLimitsEvery, pruneRateLimitsArgs{})
+}
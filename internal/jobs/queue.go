@@ -0,0 +1,364 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+const (
+	defaultMaxAttempts  = 5
+	defaultPollInterval = time.Second
+	baseBackoff         = 5 * time.Second
+	maxBackoff          = time.Hour
+	staleLockTimeout    = 10 * time.Minute
+	staleHeartbeatPolls = 5
+	// jobTimeout bounds each handler run. It is shorter than staleLockTimeout
+	// so a job is never released as stale while its handler is still running,
+	// leaving time to record the outcome.
+	jobTimeout = staleLockTimeout - time.Minute
+)
+
+var ErrUnknownKind = errors.New("no handler registered for job kind")
+
+type HandlerFunc func(ctx context.Context, payload json.RawMessage) error
+
+type EnqueueOptions struct {
+	RunAt       time.Time
+	MaxAttempts int32
+	// UniqueKey deduplicates jobs: enqueueing a second job with the same key is
+	// a no-op that reports ok=false.
+	UniqueKey string
+}
+
+// Enqueue inserts a job. Pass a Queries bound to a transaction to enqueue
+// atomically with other writes.
+func Enqueue(ctx context.Context, q *database.Queries, kind string, args any, opts EnqueueOptions) (job database.Job, ok bool, err error) {
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return database.Job{}, false, err
+	}
+
+	if opts.RunAt.IsZero() {
+		opts.RunAt = time.Now().UTC()
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+
+	job, err = q.CreateJob(ctx, database.CreateJobParams{
+		Kind:        kind,
+		Payload:     payload,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt,
+		UniqueKey:   sql.NullString{String: opts.UniqueKey, Valid: opts.UniqueKey != ""},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Job{}, false, nil
+	}
+	if err != nil {
+		return database.Job{}, false, err
+	}
+	return job, true, nil
+}
+
+type schedule struct {
+	kind  string
+	every time.Duration
+	args  any
+}
+
+type Queue struct {
+	db           *database.Queries
+	workers      int
+	pollInterval time.Duration
+
+	mu        sync.RWMutex
+	handlers  map[string]HandlerFunc
+	schedules []schedule
+
+	stop  context.CancelFunc
+	loops sync.WaitGroup
+	// lastSchedule is the UnixNano time the scheduler loop last finished.
+	lastSchedule atomic.Int64
+}
+
+func NewQueue(db *database.Queries, workers int, pollInterval time.Duration) *Queue {
+	if workers <= 0 {
+		workers = 1
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	return &Queue{
+		db:           db,
+		workers:      workers,
+		pollInterval: pollInterval,
+		handlers:     map[string]HandlerFunc{},
+	}
+}
+
+// Register installs a typed handler for kind. The job payload is decoded into
+// T before fn is called.
+func Register[T any](q *Queue, kind string, fn func(ctx context.Context, args T) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = func(ctx context.Context, payload json.RawMessage) error {
+		var args T
+		if err := json.Unmarshal(payload, &args); err != nil {
+			return fmt.Errorf("decoding %s payload: %w", kind, err)
+		}
+		return fn(ctx, args)
+	}
+}
+
+// Schedule enqueues a job of kind once per period. Each period is keyed by its
+// start time so that several instances scheduling the same job only create
+// one run.
+func (q *Queue) Schedule(kind string, every time.Duration, args any) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.schedules = append(q.schedules, schedule{kind: kind, every: every, args: args})
+}
+
+// Start launches the workers and the scheduler. Call Shutdown to stop them.
+func (q *Queue) Start(ctx context.Context) {
+	ctx, q.stop = context.WithCancel(ctx)
+
+	for range q.workers {
+		q.loops.Add(1)
+		go func() {
+			defer q.loops.Done()
+			q.work(ctx)
+		}()
+	}
+
+	q.loops.Add(1)
+	go func() {
+		defer q.loops.Done()
+		q.schedule(ctx)
+	}()
+}
+
+// Shutdown stops claiming new jobs, cancels the context of running jobs and
+// waits for their handlers to return or for ctx to expire, whichever comes
+// first. A cancelled job goes back to pending to run again straight away. Jobs
+// whose handlers ignore cancellation and are still running when ctx expires
+// are left locked and get released by the stale lock sweep on another worker.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	if q == nil || q.stop == nil {
+		return nil
+	}
+	q.stop()
+
+	done := make(chan struct{})
+	go func() {
+		q.loops.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Healthy reports an error if the queue isn't running or its scheduler loop,
+// which also sweeps stale locks, has stalled.
+func (q *Queue) Healthy() error {
+	if q.stop == nil {
+		return errors.New("queue not started")
+	}
+	last := q.lastSchedule.Load()
+	if last == 0 {
+		return errors.New("scheduler has not run yet")
+	}
+	if since := time.Since(time.Unix(0, last)); since > staleHeartbeatPolls*q.pollInterval {
+		return fmt.Errorf("scheduler last ran %s ago", since.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// List returns the most recently created jobs, optionally filtered by status.
+func (q *Queue) List(ctx context.Context, status string, limit int32) ([]database.Job, error) {
+	return q.db.ListJobs(ctx, database.ListJobsParams{
+		Status:   sql.NullString{String: status, Valid: status != ""},
+		RowLimit: limit,
+	})
+}
+
+// Retry moves a job that isn't currently running back to pending with a fresh
+// attempt budget. It returns sql.ErrNoRows if there is no such job.
+func (q *Queue) Retry(ctx context.Context, id uuid.UUID) (database.Job, error) {
+	return q.db.RequeueJob(ctx, id)
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			ran, err := q.runNext(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "claiming job", "error", err)
+			}
+			if !ran {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims and runs a single job. The handler gets jobTimeout and is
+// cancelled when the queue stops; the outcome is recorded either way, even for
+// a job claimed just as the queue was stopping.
+func (q *Queue) runNext(ctx context.Context) (bool, error) {
+	job, err := q.db.ClaimJob(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, nil
+		}
+		return false, err
+	}
+
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Kind]
+	q.mu.RUnlock()
+
+	if !ok {
+		err = ErrUnknownKind
+	} else {
+		jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+		err = handler(jobCtx, job.Payload)
+		cancel()
+	}
+
+	stopping := ctx.Err() != nil
+	ctx = context.WithoutCancel(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "job failed",
+			"job_kind", job.Kind,
+			"job_id", job.ID,
+			"attempts", job.Attempts,
+			"error", err,
+		)
+	}
+	if err := q.record(ctx, job, ok, stopping, err); err != nil {
+		slog.ErrorContext(ctx, "recording job outcome", "job_kind", job.Kind, "job_id", job.ID, "error", err)
+	}
+	return true, nil
+}
+
+// record stores the outcome of running job. runErr is the handler's error,
+// known is false if no handler was registered for the job's kind, and
+// stopping is true if the run was cut short by the queue stopping.
+func (q *Queue) record(ctx context.Context, job database.Job, known, stopping bool, runErr error) error {
+	if runErr == nil {
+		return q.db.CompleteJob(ctx, job.ID)
+	}
+
+	lastError := sql.NullString{String: runErr.Error(), Valid: true}
+	if stopping {
+		return q.db.RetryJobLater(ctx, database.RetryJobLaterParams{
+			ID:        job.ID,
+			RunAt:     time.Now().UTC(),
+			LastError: lastError,
+		})
+	}
+	if !known || job.Attempts >= job.MaxAttempts {
+		return q.db.KillJob(ctx, database.KillJobParams{ID: job.ID, LastError: lastError})
+	}
+
+	return q.db.RetryJobLater(ctx, database.RetryJobLaterParams{
+		ID:        job.ID,
+		RunAt:     time.Now().UTC().Add(Backoff(job.Attempts)),
+		LastError: lastError,
+	})
+}
+
+func (q *Queue) schedule(ctx context.Context) {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	lastSlot := map[string]time.Time{}
+	for {
+		now := time.Now().UTC()
+
+		q.mu.RLock()
+		schedules := append([]schedule{}, q.schedules...)
+		q.mu.RUnlock()
+
+		for _, s := range schedules {
+			slot := now.Truncate(s.every)
+			if lastSlot[s.kind].Equal(slot) {
+				continue
+			}
+			opts := EnqueueOptions{
+				RunAt:     slot,
+				UniqueKey: fmt.Sprintf("%s@%s", s.kind, slot.Format(time.RFC3339)),
+			}
+			if _, _, err := Enqueue(ctx, q.db, s.kind, s.args, opts); err != nil {
+				if ctx.Err() == nil {
+					slog.ErrorContext(ctx, "scheduling job", "job_kind", s.kind, "error", err)
+				}
+				continue
+			}
+			lastSlot[s.kind] = slot
+		}
+
+		staleBefore := sql.NullTime{Time: now.Add(-staleLockTimeout), Valid: true}
+		if released, err := q.db.ReleaseStaleJobs(ctx, staleBefore); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "releasing stale jobs", "error", err)
+		} else if released > 0 {
+			slog.WarnContext(ctx, "released stale jobs", "count", released)
+		}
+		q.lastSchedule.Store(time.Now().UnixNano())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Backoff returns how long to wait before retrying a job that has failed
+// attempts times: exponential from baseBackoff, capped at maxBackoff, with up
+// to 20% jitter so failing jobs don't retry in lockstep.
+func Backoff(attempts int32) time.Duration {
+	d := baseBackoff
+	for i := int32(1); i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxBackoff)
+	return d + time.Duration(rand.Int64N(int64(d/5)+1))
+}
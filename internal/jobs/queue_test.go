@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int32
+		wantMin  time.Duration
+		wantMax  time.Duration
+	}{
+		{
+			name:     "firstRetry",
+			attempts: 1,
+			wantMin:  baseBackoff,
+			wantMax:  baseBackoff + baseBackoff/5,
+		},
+		{
+			name:     "doubles",
+			attempts: 3,
+			wantMin:  4 * baseBackoff,
+			wantMax:  4*baseBackoff + 4*baseBackoff/5,
+		},
+		{
+			name:     "capped",
+			attempts: 30,
+			wantMin:  maxBackoff,
+			wantMax:  maxBackoff + maxBackoff/5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Backoff(tt.attempts)
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("Backoff(%d) = %v, want between %v and %v", tt.attempts, got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrument(t *testing.T) {
+	m := New(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	handler := m.Instrument(mux)
+
+	tests := []struct {
+		name   string
+		path   string
+		route  string
+		code   string
+		wantN  float64
+		repeat int
+	}{
+		{
+			name:   "patternLabel",
+			path:   "/api/chirps/a",
+			route:  "GET /api/chirps/{chirpId}",
+			code:   "404",
+			wantN:  2,
+			repeat: 2,
+		},
+		{
+			name:   "implicitOK",
+			path:   "/api/healthz",
+			route:  "GET /api/healthz",
+			code:   "200",
+			wantN:  1,
+			repeat: 1,
+		},
+		{
+			name:   "unmatched",
+			path:   "/nope",
+			route:  unmatchedRoute,
+			code:   "404",
+			wantN:  1,
+			repeat: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tt.repeat; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			}
+			got := testutil.ToFloat64(m.requests.WithLabelValues(tt.route, http.MethodGet, tt.code))
+			if got != tt.wantN {
+				t.Errorf("requests{route=%q,code=%s} = %v, want %v", tt.route, tt.code, got, tt.wantN)
+			}
+		})
+	}
+
+	if got := testutil.ToFloat64(m.inFlight); got != 0 {
+		t.Errorf("in-flight = %v after all requests finished, want 0", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New(nil)
+	m.Login(LoginSuccess)
+	m.Login(LoginFailure)
+	m.Login(LoginFailure)
+	m.ChirpCreated()
+	m.Webhook("polka", WebhookProcessed)
+	m.RateLimited("login")
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		`chirpy_logins_total{result="failure"} 2`,
+		`chirpy_logins_total{result="success"} 1`,
+		`chirpy_chirps_created_total 1`,
+		`chirpy_webhooks_total{outcome="processed",provider="polka"} 1`,
+		`chirpy_rate_limited_total{policy="login"} 1`,
+		`chirpy_http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("exposition missing %q", want)
+		}
+	}
+}
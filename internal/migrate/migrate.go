@@ -0,0 +1,99 @@
+// Package migrate applies the goose migrations embedded in the binary.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/d-shames3/chirpy/internal/store"
+	pgschema "github.com/d-shames3/chirpy/sql/schema"
+	sqliteschema "github.com/d-shames3/chirpy/sql/sqlite/schema"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// lockID is the Postgres advisory lock held while migrating so that several
+// instances starting at once don't apply the same migration twice.
+const lockID int64 = 0x63686972707902
+
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+type Migrator struct {
+	provider *goose.Provider
+}
+
+func New(driver string, db *sql.DB) (*Migrator, error) {
+	var (
+		dialect goose.Dialect
+		fsys    fs.FS
+		opts    []goose.ProviderOption
+	)
+
+	switch driver {
+	case store.DriverPostgres:
+		dialect = goose.DialectPostgres
+		fsys = pgschema.FS
+		locker, err := lock.NewPostgresSessionLocker(lock.WithLockID(lockID))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, goose.WithSessionLocker(locker))
+	case store.DriverSQLite:
+		dialect = goose.DialectSQLite3
+		fsys = sqliteschema.FS
+	default:
+		return nil, fmt.Errorf("unsupported driver %q", driver)
+	}
+
+	provider, err := goose.NewProvider(dialect, db, fsys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return m.provider.Up(ctx)
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	return m.provider.Down(ctx)
+}
+
+// Redo rolls back the most recently applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.provider.UpByOne(ctx)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+// Check returns ErrSchemaBehind if any embedded migration has not been
+// applied.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, target, err := m.provider.GetVersions(ctx)
+	if err != nil {
+		return err
+	}
+	pending, err := m.provider.HasPending(ctx)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return fmt.Errorf("%w: at version %d, binary expects %d", ErrSchemaBehind, current, target)
+	}
+	return nil
+}
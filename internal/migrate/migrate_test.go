@@ -0,0 +1,72 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/d-shames3/chirpy/internal/store"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pressly/goose/v3"
+)
+
+func TestMigratorSQLite(t *testing.T) {
+	ctx := context.Background()
+	driver, dsn, err := store.ParseURL("sqlite:" + filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := New(driver, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Check on empty database error = %v, want %v", err, ErrSchemaBehind)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up error = %v", err)
+	}
+	if len(applied) == 0 {
+		t.Fatal("Up applied no migrations")
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Errorf("Check after Up error = %v", err)
+	}
+
+	redone, err := m.Redo(ctx)
+	if err != nil || len(redone) != 2 {
+		t.Errorf("Redo = %v, %v, want down and up results", redone, err)
+	}
+
+	if _, err := m.Down(ctx); err != nil {
+		t.Fatalf("Down error = %v", err)
+	}
+	if err := m.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Check after Down error = %v, want %v", err, ErrSchemaBehind)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status error = %v", err)
+	}
+	last := statuses[len(statuses)-1]
+	if last.State != goose.StatePending {
+		t.Errorf("last migration state = %v, want %v", last.State, goose.StatePending)
+	}
+	for _, s := range statuses[:len(statuses)-1] {
+		if s.State != goose.StateApplied {
+			t.Errorf("migration %d state = %v, want %v", s.Source.Version, s.State, goose.StateApplied)
+		}
+	}
+}
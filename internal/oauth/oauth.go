@@ -0,0 +1,159 @@
+// Package oauth holds the protocol rules of Chirpy's OAuth 2.0 authorization
+// server (RFC 6749 with PKCE, RFC 7636): validating redirect URIs, scopes
+// and code verifiers, and the error codes clients branch on. The handlers
+// and storage live with the rest of the API.
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Error codes from RFC 6749 sections 4.1.2.1 and 5.2 and RFC 7009.
+const (
+	ErrInvalidRequest          = "invalid_request"
+	ErrInvalidClient           = "invalid_client"
+	ErrInvalidGrant            = "invalid_grant"
+	ErrUnauthorizedClient      = "unauthorized_client"
+	ErrUnsupportedGrantType    = "unsupported_grant_type"
+	ErrUnsupportedResponseType = "unsupported_response_type"
+	ErrInvalidScope            = "invalid_scope"
+	ErrAccessDenied            = "access_denied"
+	ErrServerError             = "server_error"
+	ErrUnsupportedTokenType    = "unsupported_token_type"
+)
+
+// Error is an OAuth error response. It is sent as JSON from the token
+// endpoints and as query parameters on the redirect from the authorization
+// endpoint.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func NewError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
+
+// MethodS256 is the only PKCE challenge method accepted; plain offers no
+// protection once the authorization request leaks.
+const MethodS256 = "S256"
+
+// verifierPattern is the code_verifier syntax from RFC 7636 section 4.1.
+var verifierPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+
+// ValidateChallenge checks the PKCE parameters of an authorization request.
+func ValidateChallenge(challenge, method string) error {
+	if challenge == "" {
+		return NewError(ErrInvalidRequest, "code_challenge is required")
+	}
+	if method != MethodS256 {
+		return NewError(ErrInvalidRequest, "code_challenge_method must be S256")
+	}
+	// A base64url SHA-256 digest without padding is always 43 characters.
+	if b, err := base64.RawURLEncoding.DecodeString(challenge); err != nil || len(b) != sha256.Size {
+		return NewError(ErrInvalidRequest, "code_challenge is not a S256 challenge")
+	}
+	return nil
+}
+
+// Challenge derives the S256 code_challenge for verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyChallenge reports whether verifier is the one challenge was derived
+// from.
+func VerifyChallenge(verifier, challenge string) bool {
+	if !verifierPattern.MatchString(verifier) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(Challenge(verifier)), []byte(challenge)) == 1
+}
+
+// ValidateRedirectURI checks a redirect URI a client registers. It must be
+// absolute, without whitespace and without a fragment. Plain http is only allowed to loopback
+// addresses, for native apps listening locally; other non-https schemes are
+// taken to be private-use schemes of native apps.
+func ValidateRedirectURI(raw string) error {
+	if strings.ContainsFunc(raw, unicode.IsSpace) {
+		return fmt.Errorf("redirect URI %q must not contain whitespace", raw)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() {
+		return fmt.Errorf("redirect URI %q is not an absolute URI", raw)
+	}
+	if u.Fragment != "" || strings.Contains(raw, "#") {
+		return fmt.Errorf("redirect URI %q must not have a fragment", raw)
+	}
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return fmt.Errorf("redirect URI %q has no host", raw)
+		}
+	case "http":
+		if !isLoopback(u.Hostname()) {
+			return fmt.Errorf("redirect URI %q must use https unless it is a loopback address", raw)
+		}
+	case "javascript", "data", "file":
+		return fmt.Errorf("redirect URI %q uses a forbidden scheme", raw)
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ParseScope splits a space-separated scope parameter, rejecting anything
+// not in grantable. The result is sorted and free of duplicates.
+func ParseScope(scope string, grantable []string) ([]string, error) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return nil, NewError(ErrInvalidScope, "scope is required")
+	}
+	for _, s := range scopes {
+		if !slices.Contains(grantable, s) {
+			return nil, NewError(ErrInvalidScope, fmt.Sprintf("unknown scope %q", s))
+		}
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes), nil
+}
+
+// RedirectURL adds params to the query of redirectURI, keeping any query it
+// was registered with.
+func RedirectURL(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	for k, vs := range params {
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
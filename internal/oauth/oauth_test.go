@@ -0,0 +1,133 @@
+package oauth
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// The example from RFC 7636 appendix B.
+const (
+	rfcVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestChallenge(t *testing.T) {
+	if got := Challenge(rfcVerifier); got != rfcChallenge {
+		t.Fatalf("Challenge(%q) = %q, want %q", rfcVerifier, got, rfcChallenge)
+	}
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"match", rfcVerifier, rfcChallenge, true},
+		{"wrongVerifier", strings.Replace(rfcVerifier, "d", "e", 1), rfcChallenge, false},
+		{"tooShort", "abc", Challenge("abc"), false},
+		{"badCharacters", strings.Repeat("a", 42) + "!", Challenge(strings.Repeat("a", 42) + "!"), false},
+		{"empty", "", rfcChallenge, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyChallenge(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("VerifyChallenge(%q, %q) = %v, want %v", tt.verifier, tt.challenge, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		method    string
+		wantErr   bool
+	}{
+		{"valid", rfcChallenge, MethodS256, false},
+		{"missing", "", MethodS256, true},
+		{"plain", rfcChallenge, "plain", true},
+		{"noMethod", rfcChallenge, "", true},
+		{"notADigest", "short", MethodS256, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateChallenge(tt.challenge, tt.method)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateChallenge error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var oerr *Error
+			if err != nil && (!errors.As(err, &oerr) || oerr.Code != ErrInvalidRequest) {
+				t.Errorf("ValidateChallenge error = %v, want %s", err, ErrInvalidRequest)
+			}
+		})
+	}
+}
+
+func TestValidateRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		wantErr bool
+	}{
+		{"https://partner.example/callback", false},
+		{"https://partner.example/callback?app=1", false},
+		{"http://localhost:8000/cb", false},
+		{"http://127.0.0.1:5000/cb", false},
+		{"http://[::1]/cb", false},
+		{"com.partner.app:/oauth", false},
+		{"http://partner.example/callback", true},
+		{"https://partner.example/callback#frag", true},
+		{"/callback", true},
+		{"https:///callback", true},
+		{"javascript:alert(1)", true},
+		{"https://partner.example/call back", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			if err := ValidateRedirectURI(tt.uri); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRedirectURI(%q) error = %v, wantErr %v", tt.uri, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	grantable := []string{"chirps:read", "chirps:write", "profile:write"}
+	tests := []struct {
+		name    string
+		scope   string
+		want    string
+		wantErr bool
+	}{
+		{name: "single", scope: "chirps:read", want: "chirps:read"},
+		{name: "sortedAndDeduplicated", scope: " chirps:write  chirps:read chirps:write", want: "chirps:read chirps:write"},
+		{name: "empty", scope: "  ", wantErr: true},
+		{name: "unknown", scope: "chirps:read admin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScope(tt.scope, grantable)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseScope(%q) error = %v, wantErr %v", tt.scope, err, tt.wantErr)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("ParseScope(%q) = %q, want %q", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirectURL(t *testing.T) {
+	got := RedirectURL("https://partner.example/cb?app=1", url.Values{"code": {"abc"}, "state": {"x y"}})
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Host != "partner.example" || q.Get("app") != "1" || q.Get("code") != "abc" || q.Get("state") != "x y" {
+		t.Errorf("RedirectURL = %q, want registered query kept and code and state added", got)
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/d-shames3/chirpy/internal/database"
+)
+
+// BucketStore is satisfied by database.Queries.
+type BucketStore interface {
+	CreateRateLimitBucket(ctx context.Context, arg database.CreateRateLimitBucketParams) error
+	TakeRateLimitToken(ctx context.Context, arg database.TakeRateLimitTokenParams) (database.TakeRateLimitTokenRow, error)
+}
+
+// Postgres keeps buckets in the rate_limit_buckets table so every instance
+// shares the same counts. Buckets that have refilled are deleted by the
+// rate_limits.prune job.
+type Postgres struct {
+	q BucketStore
+}
+
+func NewPostgres(q BucketStore) *Postgres {
+	return &Postgres{q: q}
+}
+
+// Allow makes sure key has a bucket before taking a token from it, so the
+// take always locks an existing row. A bucket pruned in between is created
+// once more.
+func (p *Postgres) Allow(ctx context.Context, key string, l Limit) (Result, error) {
+	var err error
+	for range 2 {
+		err = p.q.CreateRateLimitBucket(ctx, database.CreateRateLimitBucketParams{
+			Key:   key,
+			Burst: l.burst(),
+		})
+		if err != nil {
+			return Result{}, err
+		}
+		var row database.TakeRateLimitTokenRow
+		row, err = p.q.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+			Burst:         l.burst(),
+			RatePerSecond: l.rate(),
+			Key:           key,
+		})
+		if err == nil {
+			return newResult(l, row.Tokens, row.Allowed), nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return Result{}, err
+		}
+	}
+	return Result{}, err
+}
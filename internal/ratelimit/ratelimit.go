@@ -0,0 +1,81 @@
+// Package ratelimit implements token-bucket rate limiting with an in-process
+// backend for single instances and a Postgres one shared across instances.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Backends selectable with RATE_LIMIT_BACKEND.
+const (
+	BackendNone     = "none"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// CheckBackend reports whether backend names a supported backend.
+func CheckBackend(backend string) error {
+	switch backend {
+	case BackendNone, BackendMemory, BackendPostgres:
+		return nil
+	}
+	return fmt.Errorf("rate_limit_backend must be %q, %q or %q, got %q", BackendNone, BackendMemory, BackendPostgres, backend)
+}
+
+// Limit allows Requests per Window. A client may spend the whole allowance at
+// once; after that it refills evenly over the window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l Limit) burst() float64 {
+	return float64(l.Requests)
+}
+
+// rate is the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// Result describes a bucket right after a request was counted against it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed. It is
+	// zero when Allowed is true.
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	// Allow takes a token from the bucket for key, refilling it according to
+	// l first.
+	Allow(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+// newResult describes a bucket holding tokens after a request was counted.
+func newResult(l Limit, tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     l.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((l.burst() - tokens) / l.rate()),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / l.rate())
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
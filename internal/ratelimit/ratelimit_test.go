@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/database"
+)
+
+func TestMemoryAllow(t *testing.T) {
+	limit := Limit{Requests: 3, Window: 3 * time.Second}
+	start := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name          string
+		at            time.Duration
+		key           string
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}{
+		{name: "first", at: 0, key: "a", wantAllowed: true, wantRemaining: 2},
+		{name: "second", at: 0, key: "a", wantAllowed: true, wantRemaining: 1},
+		{name: "third", at: 0, key: "a", wantAllowed: true, wantRemaining: 0},
+		{name: "exhausted", at: 0, key: "a", wantAllowed: false, wantRemaining: 0, wantRetry: time.Second},
+		{name: "otherKey", at: 0, key: "b", wantAllowed: true, wantRemaining: 2},
+		{name: "partialRefill", at: 500 * time.Millisecond, key: "a", wantAllowed: false, wantRemaining: 0, wantRetry: 500 * time.Millisecond},
+		{name: "refilledOne", at: time.Second, key: "a", wantAllowed: true, wantRemaining: 0},
+		{name: "refilledFully", at: time.Hour, key: "a", wantAllowed: true, wantRemaining: 2},
+	}
+
+	m := NewMemory()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.now = func() time.Time { return start.Add(tt.at) }
+
+			res, err := m.Allow(context.Background(), tt.key, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", res.Allowed, tt.wantAllowed)
+			}
+			if res.Remaining != tt.wantRemaining {
+				t.Errorf("Remaining = %d, want %d", res.Remaining, tt.wantRemaining)
+			}
+			if res.RetryAfter != tt.wantRetry {
+				t.Errorf("RetryAfter = %s, want %s", res.RetryAfter, tt.wantRetry)
+			}
+			if res.Limit != limit.Requests {
+				t.Errorf("Limit = %d, want %d", res.Limit, limit.Requests)
+			}
+		})
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	m := NewMemory()
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return now }
+	limit := Limit{Requests: 1, Window: time.Second}
+
+	m.Allow(context.Background(), "a", limit)
+	now = now.Add(2 * sweepEvery)
+	m.Allow(context.Background(), "b", limit)
+
+	if _, ok := m.buckets["a"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+	if _, ok := m.buckets["b"]; !ok {
+		t.Error("bucket in use was swept")
+	}
+}
+
+type fakeBucketStore struct {
+	created []database.CreateRateLimitBucketParams
+	got     database.TakeRateLimitTokenParams
+	row     database.TakeRateLimitTokenRow
+	// missing is how many takes find no bucket, as if it had been pruned.
+	missing int
+}
+
+func (f *fakeBucketStore) CreateRateLimitBucket(_ context.Context, arg database.CreateRateLimitBucketParams) error {
+	f.created = append(f.created, arg)
+	return nil
+}
+
+func (f *fakeBucketStore) TakeRateLimitToken(_ context.Context, arg database.TakeRateLimitTokenParams) (database.TakeRateLimitTokenRow, error) {
+	f.got = arg
+	if f.missing > 0 {
+		f.missing--
+		return database.TakeRateLimitTokenRow{}, sql.ErrNoRows
+	}
+	return f.row, nil
+}
+
+func TestPostgresAllow(t *testing.T) {
+	f := &fakeBucketStore{row: database.TakeRateLimitTokenRow{Tokens: 0.25, Allowed: false}, missing: 1}
+	res, err := NewPostgres(f).Allow(context.Background(), "login:ip:192.0.2.1", Limit{Requests: 10, Window: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := database.TakeRateLimitTokenParams{Burst: 10, RatePerSecond: 10.0 / 60, Key: "login:ip:192.0.2.1"}
+	if f.got != want {
+		t.Errorf("params = %+v, want %+v", f.got, want)
+	}
+	wantCreate := database.CreateRateLimitBucketParams{Key: "login:ip:192.0.2.1", Burst: 10}
+	if len(f.created) != 2 || f.created[0] != wantCreate || f.created[1] != wantCreate {
+		t.Errorf("created = %+v, want %+v twice after the bucket went missing", f.created, wantCreate)
+	}
+	if res.Allowed || res.Remaining != 0 || res.RetryAfter != 4500*time.Millisecond {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "untrustedPeerIgnoresHeader", remoteAddr: "203.0.113.7:5000", forwarded: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "trustedProxy", remoteAddr: "10.1.2.3:5000", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofedLeftmost", remoteAddr: "10.1.2.3:5000", forwarded: []string{"6.6.6.6, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "proxyChain", remoteAddr: "10.1.2.3:5000", forwarded: []string{"198.51.100.1, 192.0.2.1", "10.9.9.9"}, want: "198.51.100.1"},
+		{name: "invalidHop", remoteAddr: "10.1.2.3:5000", forwarded: []string{"198.51.100.1, garbage"}, want: "10.1.2.3"},
+		{name: "trustedNoHeader", remoteAddr: "10.1.2.3:5000", want: "10.1.2.3"},
+		{name: "mappedIPv4", remoteAddr: "[::ffff:203.0.113.7]:5000", want: "203.0.113.7"},
+		{name: "ipv6", remoteAddr: "[2001:db8::1]:5000", want: "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := ClientIP(r, trusted); got != netip.MustParseAddr(tt.want) {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "", want: 0},
+		{in: "10.0.0.0/8", want: 1},
+		{in: "10.0.0.0/8,::1, 127.0.0.1", want: 3},
+		{in: "10.0.0.0/33", wantErr: true},
+		{in: "proxy.internal", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTrustedProxies(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("len = %d, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
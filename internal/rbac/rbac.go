@@ -0,0 +1,52 @@
+// Package rbac defines user roles and the permissions each one grants.
+package rbac
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+type Permission string
+
+const (
+	// ViewMetrics covers the admin hit counter and the Prometheus metrics.
+	ViewMetrics Permission = "metrics:view"
+	// ManageJobs covers listing and retrying background jobs.
+	ManageJobs Permission = "jobs:manage"
+	// ResetData covers wiping all users on the dev platform.
+	ResetData Permission = "data:reset"
+	// ModerateContent covers acting on other users' content.
+	ModerateContent Permission = "content:moderate"
+	// ModerateUsers covers suspending, banning and shadowbanning users.
+	ModerateUsers Permission = "users:moderate"
+	// ManageFilters covers editing the content filter lists.
+	ManageFilters Permission = "filters:manage"
+)
+
+var grants = map[Role][]Permission{
+	RoleUser:      nil,
+	RoleModerator: {ViewMetrics, ModerateContent, ModerateUsers, ManageFilters},
+	RoleAdmin:     {ViewMetrics, ManageJobs, ResetData, ModerateContent, ModerateUsers, ManageFilters},
+}
+
+// ParseRole validates s as a role name.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := grants[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (want %s, %s or %s)", s, RoleUser, RoleModerator, RoleAdmin)
+	}
+	return role, nil
+}
+
+// Can reports whether role grants p. Unknown roles grant nothing.
+func (r Role) Can(p Permission) bool {
+	return slices.Contains(grants[r], p)
+}
@@ -0,0 +1,49 @@
+package rbac
+
+import "testing"
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		role Role
+		perm Permission
+		want bool
+	}{
+		{RoleUser, ViewMetrics, false},
+		{RoleUser, ModerateContent, false},
+		{RoleUser, ModerateUsers, false},
+		{RoleUser, ManageFilters, false},
+		{RoleModerator, ModerateContent, true},
+		{RoleModerator, ModerateUsers, true},
+		{RoleModerator, ViewMetrics, true},
+		{RoleModerator, ManageFilters, true},
+		{RoleModerator, ManageJobs, false},
+		{RoleModerator, ResetData, false},
+		{RoleAdmin, ManageJobs, true},
+		{RoleAdmin, ResetData, true},
+		{RoleAdmin, ModerateUsers, true},
+		{RoleAdmin, ManageFilters, true},
+		{Role("root"), ResetData, false},
+		{Role(""), ViewMetrics, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.perm), func(t *testing.T) {
+			if got := tt.role.Can(tt.perm); got != tt.want {
+				t.Errorf("%q.Can(%q) = %v, want %v", tt.role, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	for _, s := range []string{"user", "moderator", "admin"} {
+		if role, err := ParseRole(s); err != nil || string(role) != s {
+			t.Errorf("ParseRole(%q) = %q, %v", s, role, err)
+		}
+	}
+	for _, s := range []string{"", "Admin", "root"} {
+		if _, err := ParseRole(s); err == nil {
+			t.Errorf("ParseRole(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package spam spots chirps that repeat what their author just posted, new
+// accounts posting faster than a person would, and chirps that are little
+// more than links.
+package spam
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/d-shames3/chirpy/internal/chirptext"
+)
+
+// Action is what happens to a chirp that trips a check.
+type Action string
+
+const (
+	// Off disables the check.
+	Off Action = "off"
+	// Flag posts the chirp and opens a report case for moderators.
+	Flag Action = "flag"
+	// Hide posts the chirp hidden from everyone but its author and opens a
+	// report case. The author isn't told.
+	Hide Action = "hide"
+	// Reject refuses the chirp.
+	Reject Action = "reject"
+)
+
+// ParseAction checks s names an Action.
+func ParseAction(s string) (Action, error) {
+	switch a := Action(s); a {
+	case Off, Flag, Hide, Reject:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown spam action %q, want off, flag, hide or reject", s)
+}
+
+// Check names what a Verdict is about; it doubles as the error code when the
+// chirp is rejected.
+type Check string
+
+const (
+	Duplicate     Check = "duplicate"
+	NearDuplicate Check = "near_duplicate"
+	Velocity      Check = "velocity"
+	LinkHeavy     Check = "link_heavy"
+)
+
+const (
+	// NearDuplicateSimilarity is how alike two chirps' shingles must be, as
+	// a Jaccard index, to count as near-duplicates.
+	NearDuplicateSimilarity = 0.8
+	// shingleSize is the length, in characters, of each shingle.
+	shingleSize = 5
+	// minLinkShare is the share of a chirp's weighted length that two or more
+	// links must make up for it to be link-heavy, whatever MaxLinks is.
+	minLinkShare = 0.8
+	// minRecent is how many recent chirps are always compared against.
+	minRecent = 50
+)
+
+// Rules configures the checks. Each has its own Action.
+type Rules struct {
+	// Window is how far back duplicates and velocity are looked for.
+	Window time.Duration
+
+	DuplicateAction     Action
+	NearDuplicateAction Action
+
+	// NewAccountAge is how long an account counts as new, and
+	// NewAccountMaxChirps how many chirps it may post per Window until then.
+	NewAccountAge       time.Duration
+	NewAccountMaxChirps int
+	VelocityAction      Action
+
+	// MaxLinks is the most links a chirp may carry.
+	MaxLinks    int
+	LinksAction Action
+}
+
+// RecentLimit is how many of the author's most recent chirps Evaluate needs.
+func (r Rules) RecentLimit() int {
+	return max(minRecent, r.NewAccountMaxChirps)
+}
+
+// Prior is one of the author's chirps from the last Window.
+type Prior struct {
+	Body      string
+	CreatedAt time.Time
+}
+
+// Post is a chirp about to be created.
+type Post struct {
+	Body            string
+	AuthorCreatedAt time.Time
+	Now             time.Time
+	// Recent holds the author's chirps from the last Window, newest first,
+	// up to RecentLimit of them.
+	Recent []Prior
+}
+
+// Verdict is a check a Post tripped.
+type Verdict struct {
+	Check  Check
+	Action Action
+	Reason string
+	// RetryAfter is set for Velocity: how long until the author could post.
+	RetryAfter time.Duration
+}
+
+// Evaluate runs every enabled check against p.
+func (r Rules) Evaluate(p Post) []Verdict {
+	var verdicts []Verdict
+	add := func(check Check, action Action, reason string) *Verdict {
+		if action == Off || action == "" {
+			return nil
+		}
+		verdicts = append(verdicts, Verdict{Check: check, Action: action, Reason: reason})
+		return &verdicts[len(verdicts)-1]
+	}
+
+	fingerprint := Fingerprint(p.Body)
+	shingles := Shingles(p.Body)
+	nearest := 0.0
+	exact := false
+	for _, prior := range p.Recent {
+		if Fingerprint(prior.Body) == fingerprint {
+			exact = true
+			break
+		}
+		nearest = max(nearest, Similarity(shingles, Shingles(prior.Body)))
+	}
+	switch {
+	case exact:
+		add(Duplicate, r.DuplicateAction, "repeats a chirp posted in the last "+formatWindow(r.Window))
+	case nearest >= NearDuplicateSimilarity:
+		add(NearDuplicate, r.NearDuplicateAction, fmt.Sprintf("is %.0f%% the same as a chirp posted in the last %s", nearest*100, formatWindow(r.Window)))
+	}
+
+	if r.NewAccountMaxChirps > 0 && p.Now.Sub(p.AuthorCreatedAt) < r.NewAccountAge && len(p.Recent) >= r.NewAccountMaxChirps {
+		v := add(Velocity, r.VelocityAction, fmt.Sprintf("is more than %d chirps in %s from a new account", r.NewAccountMaxChirps, formatWindow(r.Window)))
+		if v != nil {
+			// The author can post again once the oldest chirp counting
+			// against the limit leaves the window.
+			oldest := p.Recent[r.NewAccountMaxChirps-1].CreatedAt
+			v.RetryAfter = max(oldest.Add(r.Window).Sub(p.Now), time.Second)
+		}
+	}
+
+	links := chirptext.Links(p.Body)
+	switch share := float64(len(links)*chirptext.LinkWeight) / float64(max(chirptext.Length(p.Body), 1)); {
+	case r.MaxLinks > 0 && len(links) > r.MaxLinks:
+		add(LinkHeavy, r.LinksAction, fmt.Sprintf("has %d links, more than %d", len(links), r.MaxLinks))
+	case len(links) >= 2 && share >= minLinkShare:
+		add(LinkHeavy, r.LinksAction, "is mostly links")
+	}
+	return verdicts
+}
+
+// Strongest returns the verdict with the most severe action: Reject over
+// Hide over Flag.
+func Strongest(verdicts []Verdict) (Verdict, bool) {
+	rank := map[Action]int{Flag: 1, Hide: 2, Reject: 3}
+	var strongest Verdict
+	for _, v := range verdicts {
+		if rank[v.Action] > rank[strongest.Action] {
+			strongest = v
+		}
+	}
+	return strongest, strongest.Action != ""
+}
+
+// formatWindow writes d without zero units, "1h" rather than "1h0m0s".
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// normalize lowercases body and reduces it to its words, so changes of case,
+// punctuation or spacing don't make a repeat look new. A body with no words,
+// such as "🎉🎉🎉" or "!!!", only has its spacing collapsed; otherwise every
+// one of them would look the same.
+func normalize(body string) string {
+	words := strings.FieldsFunc(strings.ToLower(body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		words = strings.Fields(body)
+	}
+	return strings.Join(words, " ")
+}
+
+// Fingerprint hashes body's normalized form. Equal fingerprints mean exact
+// duplicates.
+func Fingerprint(body string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(normalize(body)))
+	return h.Sum64()
+}
+
+// Shingles returns the set of overlapping character sequences in body's
+// normalized form, hashed. A body shorter than a shingle is one shingle.
+func Shingles(body string) map[uint64]struct{} {
+	runes := []rune(normalize(body))
+	set := map[uint64]struct{}{}
+	for i := 0; i == 0 || i+shingleSize <= len(runes); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(string(runes[i:min(i+shingleSize, len(runes))])))
+		set[h.Sum64()] = struct{}{}
+	}
+	return set
+}
+
+// Similarity is the Jaccard index of two shingle sets: 1 when they are the
+// same, 0 when they share nothing.
+func Similarity(a, b map[uint64]struct{}) float64 {
+	shared := 0
+	for s := range a {
+		if _, ok := b[s]; ok {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 1
+	}
+	return float64(shared) / float64(union)
+}
@@ -0,0 +1,201 @@
+package spam
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testRules() Rules {
+	return Rules{
+		Window:              time.Hour,
+		DuplicateAction:     Reject,
+		NearDuplicateAction: Flag,
+		NewAccountAge:       24 * time.Hour,
+		NewAccountMaxChirps: 3,
+		VelocityAction:      Reject,
+		MaxLinks:            2,
+		LinksAction:         Hide,
+	}
+}
+
+func priors(bodies ...string) []Prior {
+	recent := make([]Prior, len(bodies))
+	for i, body := range bodies {
+		recent[i] = Prior{Body: body, CreatedAt: now.Add(-time.Duration(i+1) * time.Minute)}
+	}
+	return recent
+}
+
+func TestEvaluate(t *testing.T) {
+	oldAccount := now.Add(-30 * 24 * time.Hour)
+	newAccount := now.Add(-time.Hour)
+	tests := []struct {
+		name   string
+		rules  func(r *Rules)
+		post   Post
+		checks []Check
+		action Action
+	}{
+		{
+			name: "nothing recent",
+			post: Post{Body: "first chirp of the day", AuthorCreatedAt: oldAccount},
+		},
+		{
+			name:   "exact duplicate",
+			post:   Post{Body: "Buy my book!", AuthorCreatedAt: oldAccount, Recent: priors("hello", "buy   my BOOK")},
+			checks: []Check{Duplicate},
+			action: Reject,
+		},
+		{
+			name:   "near duplicate",
+			post:   Post{Body: "Check out my new mixtape, it is fire 1", AuthorCreatedAt: oldAccount, Recent: priors("Check out my new mixtape, it is fire 2")},
+			checks: []Check{NearDuplicate},
+			action: Flag,
+		},
+		{
+			name: "different chirps",
+			post: Post{Body: "the weather is lovely today", AuthorCreatedAt: oldAccount, Recent: priors("my cat knocked over a plant")},
+		},
+		{
+			name: "different emoji",
+			post: Post{Body: "🎉🎉🎉", AuthorCreatedAt: oldAccount, Recent: priors("👍")},
+		},
+		{
+			name: "punctuation after emoji",
+			post: Post{Body: "!!!", AuthorCreatedAt: oldAccount, Recent: priors("❤️")},
+		},
+		{
+			name:   "repeated emoji",
+			post:   Post{Body: "🎉 🎉", AuthorCreatedAt: oldAccount, Recent: priors("🎉  🎉")},
+			checks: []Check{Duplicate},
+			action: Reject,
+		},
+		{
+			name:  "duplicates off",
+			rules: func(r *Rules) { r.DuplicateAction = Off },
+			post:  Post{Body: "again", AuthorCreatedAt: oldAccount, Recent: priors("again")},
+		},
+		{
+			name:   "new account too fast",
+			post:   Post{Body: "four", AuthorCreatedAt: newAccount, Recent: priors("one", "two", "three")},
+			checks: []Check{Velocity},
+			action: Reject,
+		},
+		{
+			name: "new account under the limit",
+			post: Post{Body: "three", AuthorCreatedAt: newAccount, Recent: priors("one", "two")},
+		},
+		{
+			name: "old account posting fast",
+			post: Post{Body: "four", AuthorCreatedAt: oldAccount, Recent: priors("one", "two", "three")},
+		},
+		{
+			name:   "too many links",
+			post:   Post{Body: "https://a.example https://b.example https://c.example are my favourite sites of all time", AuthorCreatedAt: oldAccount},
+			checks: []Check{LinkHeavy},
+			action: Hide,
+		},
+		{
+			name:   "mostly links",
+			post:   Post{Body: "see https://a.example and www.b.example", AuthorCreatedAt: oldAccount},
+			checks: []Check{LinkHeavy},
+			action: Hide,
+		},
+		{
+			name: "one link with text",
+			post: Post{Body: "I wrote about it at https://blog.example/post", AuthorCreatedAt: oldAccount},
+		},
+		{
+			name:   "strongest wins",
+			post:   Post{Body: "https://a.example https://b.example", AuthorCreatedAt: newAccount, Recent: priors("https://a.example https://b.example", "x", "y")},
+			checks: []Check{Duplicate, Velocity, LinkHeavy},
+			action: Reject,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			if tt.rules != nil {
+				tt.rules(&rules)
+			}
+			tt.post.Now = now
+			verdicts := rules.Evaluate(tt.post)
+			var checks []Check
+			for _, v := range verdicts {
+				checks = append(checks, v.Check)
+			}
+			if !reflect.DeepEqual(checks, tt.checks) {
+				t.Fatalf("Evaluate checks = %v, want %v (%+v)", checks, tt.checks, verdicts)
+			}
+			strongest, ok := Strongest(verdicts)
+			if ok != (tt.action != "") || strongest.Action != tt.action {
+				t.Errorf("Strongest = %+v, %v, want action %q", strongest, ok, tt.action)
+			}
+		})
+	}
+}
+
+func TestVelocityRetryAfter(t *testing.T) {
+	rules := testRules()
+	verdicts := rules.Evaluate(Post{
+		Body:            "four",
+		AuthorCreatedAt: now.Add(-time.Hour),
+		Now:             now,
+		Recent:          priors("one", "two", "three"),
+	})
+	if len(verdicts) != 1 || verdicts[0].RetryAfter != 57*time.Minute {
+		t.Errorf("Evaluate = %+v, want a velocity verdict retrying after 57m", verdicts)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"same text", "Same   text!", 1, 1},
+		{"hi", "hi", 1, 1},
+		{"hi", "yo", 0, 0},
+		{"🎉🎉🎉", "👍", 0, 0},
+		{"!!!", "???", 0, 0},
+		{"Follow me for daily crypto tips and giveaways #1", "follow me for daily crypto tips and giveaways!! #2", NearDuplicateSimilarity, 1},
+		{"the quick brown fox", "a lazy dog sleeps", 0, 0.1},
+	}
+	for _, tt := range tests {
+		got := Similarity(Shingles(tt.a), Shingles(tt.b))
+		if got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q) = %.2f, want between %.2f and %.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestFormatWindow(t *testing.T) {
+	tests := map[time.Duration]string{
+		time.Hour:                     "1h",
+		90 * time.Minute:              "1h30m",
+		10 * time.Minute:              "10m",
+		45 * time.Second:              "45s",
+		time.Hour + 5*time.Second:     "1h0m5s",
+		24*time.Hour + 30*time.Minute: "24h30m",
+	}
+	for d, want := range tests {
+		if got := formatWindow(d); got != want {
+			t.Errorf("formatWindow(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	for _, s := range []string{"off", "flag", "hide", "reject"} {
+		if a, err := ParseAction(s); err != nil || string(a) != s {
+			t.Errorf("ParseAction(%q) = %q, %v", s, a, err)
+		}
+	}
+	if _, err := ParseAction("delete"); err == nil {
+		t.Errorf("ParseAction(\"delete\") succeeded")
+	}
+}
@@ -0,0 +1,1126 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	refreshTokenLifetime      = 60 * 24 * time.Hour
+	authorizationCodeLifetime = 10 * time.Minute
+	// defaultOutboxMaxAttempts mirrors the column default on
+	// outbox_events.max_attempts.
+	defaultOutboxMaxAttempts = 10
+)
+
+// userRoles mirrors the check constraint on users.role; the first is the
+// column default.
+var userRoles = []string{"user", "moderator", "admin"}
+
+// moderationActionKinds mirrors the check constraint on
+// moderation_actions.action.
+var moderationActionKinds = []string{"suspend", "unsuspend", "ban", "unban", "shadowban", "unshadowban", "warn"}
+
+// reportReasons and reportResolutions mirror the check constraints on
+// reports.reason and report_cases.resolution.
+var (
+	reportReasons     = []string{"spam", "harassment", "hate", "violence", "sexual", "misinformation", "other"}
+	reportResolutions = []string{"dismiss", "hide_chirp", "remove_chirp", "warn_author"}
+)
+
+type memData struct {
+	users    map[uuid.UUID]database.User
+	chirps   []database.Chirp
+	tokens   map[string]database.RefreshToken
+	pats     map[uuid.UUID]database.PersonalAccessToken
+	clients  map[uuid.UUID]database.OAuthClient
+	codes    map[string]database.OAuthAuthorizationCode
+	actions  []database.ModerationAction
+	cases    map[uuid.UUID]database.ReportCase
+	reports  []database.Report
+	inbox    []database.Notification
+	outbox   []database.OutboxEvent
+	outboxID int64
+}
+
+func (d *memData) clone() *memData {
+	return &memData{
+		users:    maps.Clone(d.users),
+		chirps:   slices.Clone(d.chirps),
+		tokens:   maps.Clone(d.tokens),
+		pats:     maps.Clone(d.pats),
+		clients:  maps.Clone(d.clients),
+		codes:    maps.Clone(d.codes),
+		actions:  slices.Clone(d.actions),
+		cases:    maps.Clone(d.cases),
+		reports:  slices.Clone(d.reports),
+		inbox:    slices.Clone(d.inbox),
+		outbox:   slices.Clone(d.outbox),
+		outboxID: d.outboxID,
+	}
+}
+
+type memState struct {
+	mu   sync.Mutex
+	data *memData
+}
+
+// Memory is a Store that keeps everything in process memory. It mirrors the
+// Postgres schema's constraints: unique emails and tokens, foreign keys from
+// chirps, tokens and OAuth clients to users and from OAuth codes and tokens
+// to clients with cascading deletes, and chirps listed in creation order.
+// Transactions are serialized.
+type Memory struct {
+	state *memState
+	// tx is the working copy of a transaction in progress. The state lock is
+	// already held while it is set.
+	tx *memData
+}
+
+func NewMemory() *Memory {
+	return &Memory{state: &memState{data: &memData{
+		users:   map[uuid.UUID]database.User{},
+		tokens:  map[string]database.RefreshToken{},
+		pats:    map[uuid.UUID]database.PersonalAccessToken{},
+		clients: map[uuid.UUID]database.OAuthClient{},
+		codes:   map[string]database.OAuthAuthorizationCode{},
+		cases:   map[uuid.UUID]database.ReportCase{},
+	}}}
+}
+
+func (m *Memory) Users() UserRepository                 { return memUsers{m} }
+func (m *Memory) Chirps() ChirpRepository               { return memChirps{m} }
+func (m *Memory) RefreshTokens() RefreshTokenRepository { return memRefreshTokens{m} }
+func (m *Memory) Outbox() OutboxRepository              { return memOutbox{m} }
+
+func (m *Memory) OAuth() OAuthRepository { return memOAuth{m} }
+
+func (m *Memory) Moderation() ModerationRepository { return memModeration{m} }
+
+func (m *Memory) Reports() ReportRepository { return memReports{m} }
+
+func (m *Memory) Notifications() NotificationRepository { return memNotifications{m} }
+
+func (m *Memory) PersonalAccessTokens() PersonalAccessTokenRepository {
+	return memPersonalAccessTokens{m}
+}
+
+func (m *Memory) WithTx(ctx context.Context, fn func(s Store) error) error {
+	if m.tx != nil {
+		return fn(m)
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	working := m.state.data.clone()
+	if err := fn(&Memory{state: m.state, tx: working}); err != nil {
+		return err
+	}
+	m.state.data = working
+	return nil
+}
+
+// OutboxEvents returns a copy of every event recorded so far.
+func (m *Memory) OutboxEvents() []database.OutboxEvent {
+	var events []database.OutboxEvent
+	m.do(func(d *memData) error {
+		events = slices.Clone(d.outbox)
+		return nil
+	})
+	return events
+}
+
+func (m *Memory) do(fn func(d *memData) error) error {
+	if m.tx != nil {
+		return fn(m.tx)
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	return fn(m.state.data)
+}
+
+// now matches the microsecond precision of Postgres timestamps.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+type memUsers struct{ m *Memory }
+
+func (r memUsers) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	var user database.User
+	err := r.m.do(func(d *memData) error {
+		if emailTaken(d, arg.Email, uuid.Nil) {
+			return fmt.Errorf("%w: email %q", ErrDuplicate, arg.Email)
+		}
+		ts := now()
+		user = database.User{
+			ID:             uuid.New(),
+			CreatedAt:      ts,
+			UpdatedAt:      ts,
+			Email:          arg.Email,
+			HashedPassword: arg.HashedPassword,
+			Role:           userRoles[0],
+		}
+		d.users[user.ID] = user
+		return nil
+	})
+	return user, err
+}
+
+func (r memUsers) GetUser(ctx context.Context, email string) (database.User, error) {
+	var user database.User
+	err := r.m.do(func(d *memData) error {
+		for _, u := range d.users {
+			if u.Email == email {
+				user = u
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return user, err
+}
+
+func (r memUsers) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	var user database.User
+	err := r.m.do(func(d *memData) error {
+		u, ok := d.users[id]
+		if !ok {
+			return ErrNotFound
+		}
+		user = u
+		return nil
+	})
+	return user, err
+}
+
+func (r memUsers) UpdateUserCreds(ctx context.Context, arg database.UpdateUserCredsParams) (database.User, error) {
+	var user database.User
+	err := r.m.do(func(d *memData) error {
+		u, ok := d.users[arg.ID]
+		if !ok {
+			return ErrNotFound
+		}
+		if emailTaken(d, arg.Email, arg.ID) {
+			return fmt.Errorf("%w: email %q", ErrDuplicate, arg.Email)
+		}
+		u.Email = arg.Email
+		u.HashedPassword = arg.HashedPassword
+		u.UpdatedAt = now()
+		d.users[u.ID] = u
+		user = u
+		return nil
+	})
+	return user, err
+}
+
+func (r memUsers) UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error) {
+	var user database.User
+	err := r.m.do(func(d *memData) error {
+		if !slices.Contains(userRoles, arg.Role) {
+			return fmt.Errorf("invalid role %q", arg.Role)
+		}
+		u, ok := d.users[arg.ID]
+		if !ok {
+			return ErrNotFound
+		}
+		u.Role = arg.Role
+		u.UpdatedAt = now()
+		d.users[arg.ID] = u
+		user = u
+		return nil
+	})
+	return user, err
+}
+
+func (r memUsers) UpdateUserChirpyRedStatus(ctx context.Context, id uuid.UUID) error {
+	return r.m.do(func(d *memData) error {
+		if u, ok := d.users[id]; ok {
+			u.IsChirpyRed = true
+			d.users[id] = u
+		}
+		return nil
+	})
+}
+
+func (r memUsers) DeleteUsers(ctx context.Context) error {
+	return r.m.do(func(d *memData) error {
+		clear(d.users)
+		d.chirps = nil
+		clear(d.tokens)
+		clear(d.pats)
+		clear(d.clients)
+		clear(d.codes)
+		d.actions = nil
+		clear(d.cases)
+		d.reports = nil
+		d.inbox = nil
+		return nil
+	})
+}
+
+func emailTaken(d *memData, email string, except uuid.UUID) bool {
+	for id, u := range d.users {
+		if u.Email == email && id != except {
+			return true
+		}
+	}
+	return false
+}
+
+type memChirps struct{ m *Memory }
+
+func (r memChirps) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	var chirp database.Chirp
+	err := r.m.do(func(d *memData) error {
+		if _, ok := d.users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.UserID)
+		}
+		ts := now()
+		chirp = database.Chirp{
+			ID:        uuid.New(),
+			UserID:    arg.UserID,
+			Body:      arg.Body,
+			CreatedAt: ts,
+			UpdatedAt: ts,
+		}
+		d.chirps = append(d.chirps, chirp)
+		return nil
+	})
+	return chirp, err
+}
+
+func (r memChirps) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	var chirp database.Chirp
+	err := r.m.do(func(d *memData) error {
+		for _, c := range d.chirps {
+			if c.ID == id {
+				chirp = c
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return chirp, err
+}
+
+func (r memChirps) GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]database.Chirp, error) {
+	return r.list(viewerID, func(database.Chirp) bool { return true })
+}
+
+func (r memChirps) GetChirpsByAuthor(ctx context.Context, arg database.GetChirpsByAuthorParams) ([]database.Chirp, error) {
+	return r.list(arg.ViewerID, func(c database.Chirp) bool { return c.UserID == arg.UserID })
+}
+
+func (r memChirps) GetRecentChirpsByAuthor(ctx context.Context, arg database.GetRecentChirpsByAuthorParams) ([]database.Chirp, error) {
+	since := now().Add(-time.Duration(arg.WindowSeconds) * time.Second)
+	var chirps []database.Chirp
+	err := r.m.do(func(d *memData) error {
+		for _, c := range d.chirps {
+			if c.UserID == arg.UserID && c.CreatedAt.After(since) {
+				chirps = append(chirps, c)
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(chirps, func(i, j int) bool {
+		return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
+	})
+	if len(chirps) > int(arg.RowLimit) {
+		chirps = chirps[:arg.RowLimit]
+	}
+	return chirps, err
+}
+
+func (r memChirps) list(viewerID uuid.NullUUID, keep func(database.Chirp) bool) ([]database.Chirp, error) {
+	var chirps []database.Chirp
+	err := r.m.do(func(d *memData) error {
+		for _, c := range d.chirps {
+			hidden := (c.HiddenAt.Valid || d.users[c.UserID].ShadowbannedAt.Valid) && !(viewerID.Valid && viewerID.UUID == c.UserID)
+			if keep(c) && !hidden {
+				chirps = append(chirps, c)
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(chirps, func(i, j int) bool {
+		return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
+	})
+	return chirps, err
+}
+
+func (r memChirps) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	return r.m.do(func(d *memData) error {
+		d.chirps = slices.DeleteFunc(d.chirps, func(c database.Chirp) bool { return c.ID == id })
+		for caseID, c := range d.cases {
+			if c.ChirpID.Valid && c.ChirpID.UUID == id {
+				c.ChirpID = uuid.NullUUID{}
+				d.cases[caseID] = c
+			}
+		}
+		for i, report := range d.reports {
+			if report.ChirpID.Valid && report.ChirpID.UUID == id {
+				d.reports[i].ChirpID = uuid.NullUUID{}
+			}
+		}
+		return nil
+	})
+}
+
+func (r memChirps) HideChirp(ctx context.Context, id uuid.UUID) error {
+	return r.setHidden(id, true)
+}
+
+func (r memChirps) UnhideChirp(ctx context.Context, id uuid.UUID) error {
+	return r.setHidden(id, false)
+}
+
+func (r memChirps) setHidden(id uuid.UUID, hidden bool) error {
+	return r.m.do(func(d *memData) error {
+		for i, c := range d.chirps {
+			switch {
+			case c.ID != id:
+			case !hidden:
+				d.chirps[i].HiddenAt = sql.NullTime{}
+			case !c.HiddenAt.Valid:
+				d.chirps[i].HiddenAt = sql.NullTime{Time: now(), Valid: true}
+			}
+		}
+		return nil
+	})
+}
+
+type memRefreshTokens struct{ m *Memory }
+
+func (r memRefreshTokens) CreateToken(ctx context.Context, arg database.CreateTokenParams) (database.RefreshToken, error) {
+	var token database.RefreshToken
+	err := r.m.do(func(d *memData) error {
+		if _, ok := d.users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.UserID)
+		}
+		if _, ok := d.clients[arg.ClientID.UUID]; arg.ClientID.Valid && !ok {
+			return fmt.Errorf("%w: oauth client %s", ErrMissingReference, arg.ClientID.UUID)
+		}
+		if _, ok := d.tokens[arg.Token]; ok {
+			return fmt.Errorf("%w: refresh token", ErrDuplicate)
+		}
+		ts := now()
+		token = database.RefreshToken{
+			Token:     arg.Token,
+			CreatedAt: ts,
+			UpdatedAt: ts,
+			UserID:    arg.UserID,
+			ExpiresAt: ts.Add(refreshTokenLifetime),
+			ClientID:  arg.ClientID,
+			Scopes:    arg.Scopes,
+		}
+		d.tokens[token.Token] = token
+		return nil
+	})
+	return token, err
+}
+
+func (r memRefreshTokens) GetToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	var refreshToken database.RefreshToken
+	err := r.m.do(func(d *memData) error {
+		t, ok := d.tokens[token]
+		if !ok {
+			return ErrNotFound
+		}
+		refreshToken = t
+		return nil
+	})
+	return refreshToken, err
+}
+
+func (r memRefreshTokens) RevokeToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	var refreshToken database.RefreshToken
+	err := r.m.do(func(d *memData) error {
+		t, ok := d.tokens[token]
+		if !ok {
+			return ErrNotFound
+		}
+		ts := now()
+		t.UpdatedAt = ts
+		t.RevokedAt.Time = ts
+		t.RevokedAt.Valid = true
+		d.tokens[token] = t
+		refreshToken = t
+		return nil
+	})
+	return refreshToken, err
+}
+
+func (r memRefreshTokens) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	return r.m.do(func(d *memData) error {
+		ts := now()
+		for key, t := range d.tokens {
+			if t.UserID == userID && !t.RevokedAt.Valid {
+				t.UpdatedAt = ts
+				t.RevokedAt = sql.NullTime{Time: ts, Valid: true}
+				d.tokens[key] = t
+			}
+		}
+		return nil
+	})
+}
+
+func (r memRefreshTokens) DeleteStaleTokens(ctx context.Context) (int64, error) {
+	var deleted int64
+	err := r.m.do(func(d *memData) error {
+		ts := now()
+		for key, t := range d.tokens {
+			if t.ExpiresAt.Before(ts) || t.RevokedAt.Valid {
+				delete(d.tokens, key)
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+type memPersonalAccessTokens struct{ m *Memory }
+
+func (r memPersonalAccessTokens) CreatePersonalAccessToken(ctx context.Context, arg database.CreatePersonalAccessTokenParams) (database.PersonalAccessToken, error) {
+	var token database.PersonalAccessToken
+	err := r.m.do(func(d *memData) error {
+		if _, ok := d.users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.UserID)
+		}
+		for _, t := range d.pats {
+			if t.TokenHash == arg.TokenHash {
+				return fmt.Errorf("%w: personal access token", ErrDuplicate)
+			}
+		}
+		ts := now()
+		token = database.PersonalAccessToken{
+			ID:        uuid.New(),
+			UserID:    arg.UserID,
+			Name:      arg.Name,
+			TokenHash: arg.TokenHash,
+			Scopes:    arg.Scopes,
+			CreatedAt: ts,
+		}
+		if arg.ExpiresInDays.Valid {
+			token.ExpiresAt.Time = ts.AddDate(0, 0, int(arg.ExpiresInDays.Int32))
+			token.ExpiresAt.Valid = true
+		}
+		d.pats[token.ID] = token
+		return nil
+	})
+	return token, err
+}
+
+func (r memPersonalAccessTokens) GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (database.PersonalAccessToken, error) {
+	var token database.PersonalAccessToken
+	err := r.m.do(func(d *memData) error {
+		for _, t := range d.pats {
+			if t.TokenHash == tokenHash {
+				token = t
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return token, err
+}
+
+func (r memPersonalAccessTokens) ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]database.PersonalAccessToken, error) {
+	var tokens []database.PersonalAccessToken
+	err := r.m.do(func(d *memData) error {
+		for _, t := range d.pats {
+			if t.UserID == userID {
+				tokens = append(tokens, t)
+			}
+		}
+		return nil
+	})
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens, err
+}
+
+func (r memPersonalAccessTokens) DeletePersonalAccessToken(ctx context.Context, arg database.DeletePersonalAccessTokenParams) error {
+	return r.m.do(func(d *memData) error {
+		t, ok := d.pats[arg.ID]
+		if !ok || t.UserID != arg.UserID {
+			return ErrNotFound
+		}
+		delete(d.pats, arg.ID)
+		return nil
+	})
+}
+
+func (r memPersonalAccessTokens) TouchPersonalAccessToken(ctx context.Context, id uuid.UUID) error {
+	return r.m.do(func(d *memData) error {
+		t, ok := d.pats[id]
+		if !ok {
+			return nil
+		}
+		ts := now()
+		if t.LastUsedAt.Valid && ts.Sub(t.LastUsedAt.Time) < time.Minute {
+			return nil
+		}
+		t.LastUsedAt.Time = ts
+		t.LastUsedAt.Valid = true
+		d.pats[id] = t
+		return nil
+	})
+}
+
+type memOAuth struct{ m *Memory }
+
+func (r memOAuth) CreateOAuthClient(ctx context.Context, arg database.CreateOAuthClientParams) (database.OAuthClient, error) {
+	var client database.OAuthClient
+	err := r.m.do(func(d *memData) error {
+		if _, ok := d.users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.UserID)
+		}
+		client = database.OAuthClient{
+			ID:           uuid.New(),
+			UserID:       arg.UserID,
+			Name:         arg.Name,
+			SecretHash:   arg.SecretHash,
+			RedirectUris: arg.RedirectUris,
+			CreatedAt:    now(),
+		}
+		d.clients[client.ID] = client
+		return nil
+	})
+	return client, err
+}
+
+func (r memOAuth) GetOAuthClient(ctx context.Context, id uuid.UUID) (database.OAuthClient, error) {
+	var client database.OAuthClient
+	err := r.m.do(func(d *memData) error {
+		c, ok := d.clients[id]
+		if !ok {
+			return ErrNotFound
+		}
+		client = c
+		return nil
+	})
+	return client, err
+}
+
+func (r memOAuth) ListOAuthClients(ctx context.Context, userID uuid.UUID) ([]database.OAuthClient, error) {
+	var clients []database.OAuthClient
+	err := r.m.do(func(d *memData) error {
+		for _, c := range d.clients {
+			if c.UserID == userID {
+				clients = append(clients, c)
+			}
+		}
+		return nil
+	})
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].CreatedAt.After(clients[j].CreatedAt)
+	})
+	return clients, err
+}
+
+func (r memOAuth) DeleteOAuthClient(ctx context.Context, arg database.DeleteOAuthClientParams) error {
+	return r.m.do(func(d *memData) error {
+		c, ok := d.clients[arg.ID]
+		if !ok || c.UserID != arg.UserID {
+			return ErrNotFound
+		}
+		delete(d.clients, arg.ID)
+		maps.DeleteFunc(d.codes, func(_ string, code database.OAuthAuthorizationCode) bool {
+			return code.ClientID == arg.ID
+		})
+		maps.DeleteFunc(d.tokens, func(_ string, t database.RefreshToken) bool {
+			return t.ClientID.Valid && t.ClientID.UUID == arg.ID
+		})
+		return nil
+	})
+}
+
+func (r memOAuth) CreateOAuthAuthorizationCode(ctx context.Context, arg database.CreateOAuthAuthorizationCodeParams) (database.OAuthAuthorizationCode, error) {
+	var code database.OAuthAuthorizationCode
+	err := r.m.do(func(d *memData) error {
+		if _, ok := d.users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.UserID)
+		}
+		if _, ok := d.clients[arg.ClientID]; !ok {
+			return fmt.Errorf("%w: oauth client %s", ErrMissingReference, arg.ClientID)
+		}
+		if _, ok := d.codes[arg.CodeHash]; ok {
+			return fmt.Errorf("%w: authorization code", ErrDuplicate)
+		}
+		ts := now()
+		code = database.OAuthAuthorizationCode{
+			CodeHash:      arg.CodeHash,
+			ClientID:      arg.ClientID,
+			UserID:        arg.UserID,
+			RedirectUri:   arg.RedirectUri,
+			Scopes:        arg.Scopes,
+			CodeChallenge: arg.CodeChallenge,
+			CreatedAt:     ts,
+			ExpiresAt:     ts.Add(authorizationCodeLifetime),
+		}
+		d.codes[code.CodeHash] = code
+		return nil
+	})
+	return code, err
+}
+
+func (r memOAuth) ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (database.OAuthAuthorizationCode, error) {
+	var code database.OAuthAuthorizationCode
+	err := r.m.do(func(d *memData) error {
+		c, ok := d.codes[codeHash]
+		if !ok {
+			return ErrNotFound
+		}
+		delete(d.codes, codeHash)
+		code = c
+		return nil
+	})
+	return code, err
+}
+
+func (r memOAuth) DeleteExpiredOAuthAuthorizationCodes(ctx context.Context) (int64, error) {
+	var deleted int64
+	err := r.m.do(func(d *memData) error {
+		ts := now()
+		for key, c := range d.codes {
+			if c.ExpiresAt.Before(ts) {
+				delete(d.codes, key)
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+type memModeration struct{ m *Memory }
+
+func (r memModeration) SuspendUser(ctx context.Context, arg database.SuspendUserParams) (database.User, error) {
+	return r.update(arg.ID, func(u *database.User, ts time.Time) {
+		u.SuspendedUntil = sql.NullTime{Time: ts.Add(time.Duration(arg.Hours) * time.Hour), Valid: true}
+	})
+}
+
+func (r memModeration) UnsuspendUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return r.update(id, func(u *database.User, ts time.Time) { u.SuspendedUntil = sql.NullTime{} })
+}
+
+func (r memModeration) BanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return r.update(id, func(u *database.User, ts time.Time) { u.BannedAt = sql.NullTime{Time: ts, Valid: true} })
+}
+
+func (r memModeration) UnbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return r.update(id, func(u *database.User, ts time.Time) { u.BannedAt = sql.NullTime{} })
+}
+
+func (r memModeration) ShadowbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return r.update(id, func(u *database.User, ts time.Time) { u.ShadowbannedAt = sql.NullTime{Time: ts, Valid: true} })
+}
+
+func (r memModeration) UnshadowbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return r.update(id, func(u *database.User, ts time.Time) { u.ShadowbannedAt = sql.NullTime{} })
+}
+
+func (r memModeration) update(id uuid.UUID, fn func(u *database.User, ts time.Time)) (database.User, error) {
+	var user database.User
+	err := r.m.do(func(d *memData) error {
+		u, ok := d.users[id]
+		if !ok {
+			return ErrNotFound
+		}
+		u.UpdatedAt = now()
+		fn(&u, u.UpdatedAt)
+		d.users[id] = u
+		user = u
+		return nil
+	})
+	return user, err
+}
+
+func (r memModeration) CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error) {
+	var action database.ModerationAction
+	err := r.m.do(func(d *memData) error {
+		if !slices.Contains(moderationActionKinds, arg.Action) {
+			return fmt.Errorf("invalid moderation action %q", arg.Action)
+		}
+		if _, ok := d.users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.UserID)
+		}
+		if _, ok := d.users[arg.ModeratorID.UUID]; arg.ModeratorID.Valid && !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.ModeratorID.UUID)
+		}
+		action = database.ModerationAction{
+			ID:          uuid.New(),
+			UserID:      arg.UserID,
+			ModeratorID: arg.ModeratorID,
+			Action:      arg.Action,
+			Reason:      arg.Reason,
+			ExpiresAt:   arg.ExpiresAt,
+			CreatedAt:   now(),
+		}
+		d.actions = append(d.actions, action)
+		return nil
+	})
+	return action, err
+}
+
+func (r memModeration) ListModerationActions(ctx context.Context, userID uuid.UUID) ([]database.ModerationAction, error) {
+	var actions []database.ModerationAction
+	err := r.m.do(func(d *memData) error {
+		for _, a := range slices.Backward(d.actions) {
+			if a.UserID == userID {
+				actions = append(actions, a)
+			}
+		}
+		return nil
+	})
+	return actions, err
+}
+
+type memReports struct{ m *Memory }
+
+func (r memReports) CreateReportCase(ctx context.Context, arg database.CreateReportCaseParams) (database.ReportCase, error) {
+	var c database.ReportCase
+	err := r.m.do(func(d *memData) error {
+		var err error
+		c, err = createCase(d, arg)
+		return err
+	})
+	return c, err
+}
+
+func createCase(d *memData, arg database.CreateReportCaseParams) (database.ReportCase, error) {
+	if _, ok := d.users[arg.AuthorID]; !ok {
+		return database.ReportCase{}, fmt.Errorf("%w: user %s", ErrMissingReference, arg.AuthorID)
+	}
+	if arg.ChirpID.Valid {
+		if !slices.ContainsFunc(d.chirps, func(c database.Chirp) bool { return c.ID == arg.ChirpID.UUID }) {
+			return database.ReportCase{}, fmt.Errorf("%w: chirp %s", ErrMissingReference, arg.ChirpID.UUID)
+		}
+		if _, ok := openCase(d, arg.ChirpID.UUID); ok {
+			return database.ReportCase{}, fmt.Errorf("%w: open report case for chirp %s", ErrDuplicate, arg.ChirpID.UUID)
+		}
+	}
+	ts := now()
+	c := database.ReportCase{
+		ID:         uuid.New(),
+		ChirpID:    arg.ChirpID,
+		AuthorID:   arg.AuthorID,
+		FlagReason: arg.FlagReason,
+		HidChirp:   arg.HidChirp,
+		CreatedAt:  ts,
+		UpdatedAt:  ts,
+	}
+	d.cases[c.ID] = c
+	return c, nil
+}
+
+func (r memReports) OpenReportCase(ctx context.Context, arg database.OpenReportCaseParams) (database.ReportCase, error) {
+	var c database.ReportCase
+	err := r.m.do(func(d *memData) error {
+		if arg.ChirpID.Valid {
+			var ok bool
+			if c, ok = openCase(d, arg.ChirpID.UUID); ok {
+				return nil
+			}
+		}
+		var err error
+		c, err = createCase(d, database.CreateReportCaseParams{ChirpID: arg.ChirpID, AuthorID: arg.AuthorID})
+		return err
+	})
+	return c, err
+}
+
+func openCase(d *memData, chirpID uuid.UUID) (database.ReportCase, bool) {
+	for _, c := range d.cases {
+		if c.ChirpID.Valid && c.ChirpID.UUID == chirpID && !c.ResolvedAt.Valid {
+			return c, true
+		}
+	}
+	return database.ReportCase{}, false
+}
+
+func (r memReports) GetReportCase(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	var c database.ReportCase
+	err := r.m.do(func(d *memData) error {
+		var ok bool
+		if c, ok = d.cases[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return c, err
+}
+
+func (r memReports) IncrementReportCount(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	return r.update(id, func(c *database.ReportCase) bool {
+		c.ReportCount++
+		return true
+	})
+}
+
+func (r memReports) MarkReportCaseHidChirp(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	return r.update(id, func(c *database.ReportCase) bool {
+		c.HidChirp = true
+		return true
+	})
+}
+
+func (r memReports) ListOpenReportCases(ctx context.Context, limit int32) ([]database.ReportCase, error) {
+	return r.list(limit, func(c database.ReportCase) bool { return !c.ResolvedAt.Valid }, func(a, b database.ReportCase) int {
+		if a.ReportCount != b.ReportCount {
+			return int(b.ReportCount - a.ReportCount)
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+func (r memReports) ListResolvedReportCases(ctx context.Context, limit int32) ([]database.ReportCase, error) {
+	return r.list(limit, func(c database.ReportCase) bool { return c.ResolvedAt.Valid }, func(a, b database.ReportCase) int {
+		return b.ResolvedAt.Time.Compare(a.ResolvedAt.Time)
+	})
+}
+
+func (r memReports) list(limit int32, keep func(database.ReportCase) bool, cmp func(a, b database.ReportCase) int) ([]database.ReportCase, error) {
+	var cases []database.ReportCase
+	err := r.m.do(func(d *memData) error {
+		for _, c := range d.cases {
+			if keep(c) {
+				cases = append(cases, c)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(cases, cmp)
+	if len(cases) > int(limit) {
+		cases = cases[:limit]
+	}
+	return cases, err
+}
+
+func (r memReports) ClaimReportCase(ctx context.Context, arg database.ClaimReportCaseParams) (database.ReportCase, error) {
+	return r.update(arg.ID, func(c *database.ReportCase) bool {
+		if c.ResolvedAt.Valid || (c.ClaimedBy.Valid && c.ClaimedBy != arg.ModeratorID) {
+			return false
+		}
+		c.ClaimedBy = arg.ModeratorID
+		c.ClaimedAt = sql.NullTime{Time: c.UpdatedAt, Valid: true}
+		return true
+	})
+}
+
+func (r memReports) ReleaseReportCase(ctx context.Context, arg database.ReleaseReportCaseParams) (database.ReportCase, error) {
+	return r.update(arg.ID, func(c *database.ReportCase) bool {
+		if c.ResolvedAt.Valid || !c.ClaimedBy.Valid || c.ClaimedBy != arg.ModeratorID {
+			return false
+		}
+		c.ClaimedBy = uuid.NullUUID{}
+		c.ClaimedAt = sql.NullTime{}
+		return true
+	})
+}
+
+func (r memReports) ResolveReportCase(ctx context.Context, arg database.ResolveReportCaseParams) (database.ReportCase, error) {
+	if !slices.Contains(reportResolutions, arg.Resolution.String) {
+		return database.ReportCase{}, fmt.Errorf("invalid report resolution %q", arg.Resolution.String)
+	}
+	return r.update(arg.ID, func(c *database.ReportCase) bool {
+		if c.ResolvedAt.Valid || (c.ClaimedBy.Valid && c.ClaimedBy != arg.ModeratorID) {
+			return false
+		}
+		c.Resolution = arg.Resolution
+		c.ResolutionNote = arg.ResolutionNote
+		c.ResolvedBy = arg.ModeratorID
+		c.ResolvedAt = sql.NullTime{Time: c.UpdatedAt, Valid: true}
+		return true
+	})
+}
+
+// update applies fn to the case, returning ErrNotFound when the case doesn't
+// exist or fn reports that it doesn't match.
+func (r memReports) update(id uuid.UUID, fn func(c *database.ReportCase) bool) (database.ReportCase, error) {
+	var c database.ReportCase
+	err := r.m.do(func(d *memData) error {
+		existing, ok := d.cases[id]
+		if !ok {
+			return ErrNotFound
+		}
+		existing.UpdatedAt = now()
+		if !fn(&existing) {
+			return ErrNotFound
+		}
+		d.cases[id] = existing
+		c = existing
+		return nil
+	})
+	return c, err
+}
+
+func (r memReports) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	var report database.Report
+	err := r.m.do(func(d *memData) error {
+		if !slices.Contains(reportReasons, arg.Reason) {
+			return fmt.Errorf("invalid report reason %q", arg.Reason)
+		}
+		if _, ok := d.cases[arg.CaseID]; !ok {
+			return fmt.Errorf("%w: report case %s", ErrMissingReference, arg.CaseID)
+		}
+		if _, ok := d.users[arg.ReporterID]; !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.ReporterID)
+		}
+		if arg.ChirpID.Valid {
+			if !slices.ContainsFunc(d.chirps, func(c database.Chirp) bool { return c.ID == arg.ChirpID.UUID }) {
+				return fmt.Errorf("%w: chirp %s", ErrMissingReference, arg.ChirpID.UUID)
+			}
+		}
+		if slices.ContainsFunc(d.reports, func(existing database.Report) bool {
+			return existing.CaseID == arg.CaseID && existing.ReporterID == arg.ReporterID
+		}) {
+			return fmt.Errorf("%w: report in case %s by %s", ErrDuplicate, arg.CaseID, arg.ReporterID)
+		}
+		report = database.Report{
+			ID:         uuid.New(),
+			CaseID:     arg.CaseID,
+			ChirpID:    arg.ChirpID,
+			ReporterID: arg.ReporterID,
+			Reason:     arg.Reason,
+			Details:    arg.Details,
+			CreatedAt:  now(),
+		}
+		d.reports = append(d.reports, report)
+		return nil
+	})
+	return report, err
+}
+
+func (r memReports) ListCaseReports(ctx context.Context, caseID uuid.UUID) ([]database.Report, error) {
+	var reports []database.Report
+	err := r.m.do(func(d *memData) error {
+		for _, report := range d.reports {
+			if report.CaseID == caseID {
+				reports = append(reports, report)
+			}
+		}
+		return nil
+	})
+	return reports, err
+}
+
+func (r memReports) ListReporterReports(ctx context.Context, reporterID uuid.UUID) ([]database.Report, error) {
+	var reports []database.Report
+	err := r.m.do(func(d *memData) error {
+		for _, report := range slices.Backward(d.reports) {
+			if report.ReporterID == reporterID {
+				reports = append(reports, report)
+			}
+		}
+		return nil
+	})
+	return reports, err
+}
+
+func (r memReports) ResolveReports(ctx context.Context, arg database.ResolveReportsParams) ([]database.Report, error) {
+	var reports []database.Report
+	err := r.m.do(func(d *memData) error {
+		ts := now()
+		for i, report := range d.reports {
+			if report.CaseID == arg.CaseID {
+				d.reports[i].Resolution = arg.Resolution
+				d.reports[i].ResolvedAt = sql.NullTime{Time: ts, Valid: true}
+				reports = append(reports, d.reports[i])
+			}
+		}
+		return nil
+	})
+	return reports, err
+}
+
+type memNotifications struct{ m *Memory }
+
+func (r memNotifications) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error) {
+	var n database.Notification
+	err := r.m.do(func(d *memData) error {
+		if _, ok := d.users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: user %s", ErrMissingReference, arg.UserID)
+		}
+		n = database.Notification{
+			ID:        uuid.New(),
+			UserID:    arg.UserID,
+			Kind:      arg.Kind,
+			Payload:   arg.Payload,
+			CreatedAt: now(),
+		}
+		d.inbox = append(d.inbox, n)
+		return nil
+	})
+	return n, err
+}
+
+func (r memNotifications) ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error) {
+	var notifications []database.Notification
+	err := r.m.do(func(d *memData) error {
+		for _, n := range slices.Backward(d.inbox) {
+			if len(notifications) == int(arg.RowLimit) {
+				break
+			}
+			if n.UserID == arg.UserID {
+				notifications = append(notifications, n)
+			}
+		}
+		return nil
+	})
+	return notifications, err
+}
+
+func (r memNotifications) MarkNotificationRead(ctx context.Context, arg database.MarkNotificationReadParams) (database.Notification, error) {
+	var n database.Notification
+	err := r.m.do(func(d *memData) error {
+		for i := range d.inbox {
+			if d.inbox[i].ID != arg.ID || d.inbox[i].UserID != arg.UserID {
+				continue
+			}
+			if !d.inbox[i].ReadAt.Valid {
+				d.inbox[i].ReadAt = sql.NullTime{Time: now(), Valid: true}
+			}
+			n = d.inbox[i]
+			return nil
+		}
+		return ErrNotFound
+	})
+	return n, err
+}
+
+type memOutbox struct{ m *Memory }
+
+func (r memOutbox) CreateOutboxEvent(ctx context.Context, arg database.CreateOutboxEventParams) (database.OutboxEvent, error) {
+	var event database.OutboxEvent
+	err := r.m.do(func(d *memData) error {
+		d.outboxID++
+		event = database.OutboxEvent{
+			ID:            d.outboxID,
+			AggregateType: arg.AggregateType,
+			AggregateID:   arg.AggregateID,
+			EventType:     arg.EventType,
+			Payload:       arg.Payload,
+			CreatedAt:     now(),
+			MaxAttempts:   defaultOutboxMaxAttempts,
+		}
+		d.outbox = append(d.outbox, event)
+		return nil
+	})
+	return event, err
+}
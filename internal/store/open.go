@@ -0,0 +1,61 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite3"
+)
+
+// ParseURL picks a driver from the scheme of DB_URL. postgres:// and
+// postgresql:// URLs are passed to lib/pq unchanged; sqlite:path and
+// sqlite:///abs/path open a SQLite file with foreign keys enforced so deletes
+// cascade like they do in Postgres.
+func ParseURL(dbURL string) (driver, dsn string, err error) {
+	scheme, rest, ok := strings.Cut(dbURL, ":")
+	if !ok {
+		return "", "", fmt.Errorf("DB_URL %q has no scheme", dbURL)
+	}
+
+	switch scheme {
+	case "postgres", "postgresql":
+		return DriverPostgres, dbURL, nil
+	case "sqlite", "sqlite3":
+		path, query, _ := strings.Cut(strings.TrimPrefix(rest, "//"), "?")
+		if path == "" {
+			return "", "", fmt.Errorf("DB_URL %q has no database path", dbURL)
+		}
+		params, err := url.ParseQuery(query)
+		if err != nil {
+			return "", "", err
+		}
+		params.Set("_foreign_keys", "on")
+		params.Set("_txlock", "immediate")
+		if !params.Has("_busy_timeout") {
+			params.Set("_busy_timeout", "5000")
+		}
+		if !params.Has("_journal_mode") {
+			params.Set("_journal_mode", "WAL")
+		}
+		return DriverSQLite, "file:" + path + "?" + params.Encode(), nil
+	default:
+		return "", "", fmt.Errorf("unsupported DB_URL scheme %q", scheme)
+	}
+}
+
+// New wraps db in the Store for driver.
+func New(driver string, db *sql.DB) (Store, error) {
+	switch driver {
+	case DriverPostgres:
+		return NewPostgres(db), nil
+	case DriverSQLite:
+		return NewSQLite(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported driver %q", driver)
+	}
+}
@@ -0,0 +1,389 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+type Postgres struct {
+	db *sql.DB
+	q  *database.Queries
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db, q: database.New(tracing.WrapDB(db, "postgresql"))}
+}
+
+func (p *Postgres) Users() UserRepository                 { return pgUsers{p.q} }
+func (p *Postgres) Chirps() ChirpRepository               { return pgChirps{p.q} }
+func (p *Postgres) RefreshTokens() RefreshTokenRepository { return pgRefreshTokens{p.q} }
+func (p *Postgres) Outbox() OutboxRepository              { return pgOutbox{p.q} }
+
+func (p *Postgres) OAuth() OAuthRepository { return pgOAuth{p.q} }
+
+func (p *Postgres) Moderation() ModerationRepository { return pgModeration{p.q} }
+
+func (p *Postgres) Reports() ReportRepository { return pgReports{p.q} }
+
+func (p *Postgres) Notifications() NotificationRepository { return pgNotifications{p.q} }
+
+func (p *Postgres) PersonalAccessTokens() PersonalAccessTokenRepository {
+	return pgPersonalAccessTokens{p.q}
+}
+
+func (p *Postgres) WithTx(ctx context.Context, fn func(s Store) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Postgres{db: p.db, q: database.New(tracing.WrapDB(tx, "postgresql"))}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pgError wraps driver errors in the store's sentinel errors while keeping the
+// original error in the chain.
+func pgError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqUniqueViolation:
+			return fmt.Errorf("%w: %w", ErrDuplicate, err)
+		case pqForeignKeyViolation:
+			return fmt.Errorf("%w: %w", ErrMissingReference, err)
+		}
+	}
+	return err
+}
+
+type pgUsers struct{ q *database.Queries }
+
+func (r pgUsers) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	user, err := r.q.CreateUser(ctx, arg)
+	return user, pgError(err)
+}
+
+func (r pgUsers) GetUser(ctx context.Context, email string) (database.User, error) {
+	user, err := r.q.GetUser(ctx, email)
+	return user, pgError(err)
+}
+
+func (r pgUsers) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.GetUserByID(ctx, id)
+	return user, pgError(err)
+}
+
+func (r pgUsers) UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error) {
+	user, err := r.q.UpdateUserRole(ctx, arg)
+	return user, pgError(err)
+}
+
+func (r pgUsers) UpdateUserCreds(ctx context.Context, arg database.UpdateUserCredsParams) (database.User, error) {
+	user, err := r.q.UpdateUserCreds(ctx, arg)
+	return user, pgError(err)
+}
+
+func (r pgUsers) UpdateUserChirpyRedStatus(ctx context.Context, id uuid.UUID) error {
+	return pgError(r.q.UpdateUserChirpyRedStatus(ctx, id))
+}
+
+func (r pgUsers) DeleteUsers(ctx context.Context) error {
+	return pgError(r.q.DeleteUsers(ctx))
+}
+
+type pgChirps struct{ q *database.Queries }
+
+func (r pgChirps) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	chirp, err := r.q.CreateChirp(ctx, arg)
+	return chirp, pgError(err)
+}
+
+func (r pgChirps) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := r.q.GetChirp(ctx, id)
+	return chirp, pgError(err)
+}
+
+func (r pgChirps) GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]database.Chirp, error) {
+	chirps, err := r.q.GetChirps(ctx, viewerID)
+	return chirps, pgError(err)
+}
+
+func (r pgChirps) GetChirpsByAuthor(ctx context.Context, arg database.GetChirpsByAuthorParams) ([]database.Chirp, error) {
+	chirps, err := r.q.GetChirpsByAuthor(ctx, arg)
+	return chirps, pgError(err)
+}
+
+func (r pgChirps) GetRecentChirpsByAuthor(ctx context.Context, arg database.GetRecentChirpsByAuthorParams) ([]database.Chirp, error) {
+	chirps, err := r.q.GetRecentChirpsByAuthor(ctx, arg)
+	return chirps, pgError(err)
+}
+
+func (r pgChirps) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	return pgError(r.q.DeleteChirp(ctx, id))
+}
+
+func (r pgChirps) HideChirp(ctx context.Context, id uuid.UUID) error {
+	return pgError(r.q.HideChirp(ctx, id))
+}
+
+func (r pgChirps) UnhideChirp(ctx context.Context, id uuid.UUID) error {
+	return pgError(r.q.UnhideChirp(ctx, id))
+}
+
+type pgRefreshTokens struct{ q *database.Queries }
+
+func (r pgRefreshTokens) CreateToken(ctx context.Context, arg database.CreateTokenParams) (database.RefreshToken, error) {
+	token, err := r.q.CreateToken(ctx, arg)
+	return token, pgError(err)
+}
+
+func (r pgRefreshTokens) GetToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	refreshToken, err := r.q.GetToken(ctx, token)
+	return refreshToken, pgError(err)
+}
+
+func (r pgRefreshTokens) RevokeToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	refreshToken, err := r.q.RevokeToken(ctx, token)
+	return refreshToken, pgError(err)
+}
+
+func (r pgRefreshTokens) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	return pgError(r.q.RevokeUserTokens(ctx, userID))
+}
+
+func (r pgRefreshTokens) DeleteStaleTokens(ctx context.Context) (int64, error) {
+	deleted, err := r.q.DeleteStaleTokens(ctx)
+	return deleted, pgError(err)
+}
+
+type pgPersonalAccessTokens struct{ q *database.Queries }
+
+func (r pgPersonalAccessTokens) CreatePersonalAccessToken(ctx context.Context, arg database.CreatePersonalAccessTokenParams) (database.PersonalAccessToken, error) {
+	token, err := r.q.CreatePersonalAccessToken(ctx, arg)
+	return token, pgError(err)
+}
+
+func (r pgPersonalAccessTokens) GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (database.PersonalAccessToken, error) {
+	token, err := r.q.GetPersonalAccessTokenByHash(ctx, tokenHash)
+	return token, pgError(err)
+}
+
+func (r pgPersonalAccessTokens) ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]database.PersonalAccessToken, error) {
+	tokens, err := r.q.ListPersonalAccessTokens(ctx, userID)
+	return tokens, pgError(err)
+}
+
+func (r pgPersonalAccessTokens) DeletePersonalAccessToken(ctx context.Context, arg database.DeletePersonalAccessTokenParams) error {
+	deleted, err := r.q.DeletePersonalAccessToken(ctx, arg)
+	if err != nil {
+		return pgError(err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r pgPersonalAccessTokens) TouchPersonalAccessToken(ctx context.Context, id uuid.UUID) error {
+	return pgError(r.q.TouchPersonalAccessToken(ctx, id))
+}
+
+type pgOAuth struct{ q *database.Queries }
+
+func (r pgOAuth) CreateOAuthClient(ctx context.Context, arg database.CreateOAuthClientParams) (database.OAuthClient, error) {
+	client, err := r.q.CreateOAuthClient(ctx, arg)
+	return client, pgError(err)
+}
+
+func (r pgOAuth) GetOAuthClient(ctx context.Context, id uuid.UUID) (database.OAuthClient, error) {
+	client, err := r.q.GetOAuthClient(ctx, id)
+	return client, pgError(err)
+}
+
+func (r pgOAuth) ListOAuthClients(ctx context.Context, userID uuid.UUID) ([]database.OAuthClient, error) {
+	clients, err := r.q.ListOAuthClients(ctx, userID)
+	return clients, pgError(err)
+}
+
+func (r pgOAuth) DeleteOAuthClient(ctx context.Context, arg database.DeleteOAuthClientParams) error {
+	deleted, err := r.q.DeleteOAuthClient(ctx, arg)
+	if err != nil {
+		return pgError(err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r pgOAuth) CreateOAuthAuthorizationCode(ctx context.Context, arg database.CreateOAuthAuthorizationCodeParams) (database.OAuthAuthorizationCode, error) {
+	code, err := r.q.CreateOAuthAuthorizationCode(ctx, arg)
+	return code, pgError(err)
+}
+
+func (r pgOAuth) ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (database.OAuthAuthorizationCode, error) {
+	code, err := r.q.ConsumeOAuthAuthorizationCode(ctx, codeHash)
+	return code, pgError(err)
+}
+
+func (r pgOAuth) DeleteExpiredOAuthAuthorizationCodes(ctx context.Context) (int64, error) {
+	deleted, err := r.q.DeleteExpiredOAuthAuthorizationCodes(ctx)
+	return deleted, pgError(err)
+}
+
+type pgModeration struct{ q *database.Queries }
+
+func (r pgModeration) SuspendUser(ctx context.Context, arg database.SuspendUserParams) (database.User, error) {
+	user, err := r.q.SuspendUser(ctx, arg)
+	return user, pgError(err)
+}
+
+func (r pgModeration) UnsuspendUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.UnsuspendUser(ctx, id)
+	return user, pgError(err)
+}
+
+func (r pgModeration) BanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.BanUser(ctx, id)
+	return user, pgError(err)
+}
+
+func (r pgModeration) UnbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.UnbanUser(ctx, id)
+	return user, pgError(err)
+}
+
+func (r pgModeration) ShadowbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.ShadowbanUser(ctx, id)
+	return user, pgError(err)
+}
+
+func (r pgModeration) UnshadowbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.UnshadowbanUser(ctx, id)
+	return user, pgError(err)
+}
+
+func (r pgModeration) CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error) {
+	action, err := r.q.CreateModerationAction(ctx, arg)
+	return action, pgError(err)
+}
+
+func (r pgModeration) ListModerationActions(ctx context.Context, userID uuid.UUID) ([]database.ModerationAction, error) {
+	actions, err := r.q.ListModerationActions(ctx, userID)
+	return actions, pgError(err)
+}
+
+type pgReports struct{ q *database.Queries }
+
+func (r pgReports) CreateReportCase(ctx context.Context, arg database.CreateReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.CreateReportCase(ctx, arg)
+	return c, pgError(err)
+}
+
+func (r pgReports) GetReportCase(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	c, err := r.q.GetReportCase(ctx, id)
+	return c, pgError(err)
+}
+
+func (r pgReports) OpenReportCase(ctx context.Context, arg database.OpenReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.OpenReportCase(ctx, arg)
+	return c, pgError(err)
+}
+
+func (r pgReports) IncrementReportCount(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	c, err := r.q.IncrementReportCount(ctx, id)
+	return c, pgError(err)
+}
+
+func (r pgReports) MarkReportCaseHidChirp(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	c, err := r.q.MarkReportCaseHidChirp(ctx, id)
+	return c, pgError(err)
+}
+
+func (r pgReports) ListOpenReportCases(ctx context.Context, limit int32) ([]database.ReportCase, error) {
+	cases, err := r.q.ListOpenReportCases(ctx, limit)
+	return cases, pgError(err)
+}
+
+func (r pgReports) ListResolvedReportCases(ctx context.Context, limit int32) ([]database.ReportCase, error) {
+	cases, err := r.q.ListResolvedReportCases(ctx, limit)
+	return cases, pgError(err)
+}
+
+func (r pgReports) ClaimReportCase(ctx context.Context, arg database.ClaimReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.ClaimReportCase(ctx, arg)
+	return c, pgError(err)
+}
+
+func (r pgReports) ReleaseReportCase(ctx context.Context, arg database.ReleaseReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.ReleaseReportCase(ctx, arg)
+	return c, pgError(err)
+}
+
+func (r pgReports) ResolveReportCase(ctx context.Context, arg database.ResolveReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.ResolveReportCase(ctx, arg)
+	return c, pgError(err)
+}
+
+func (r pgReports) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	report, err := r.q.CreateReport(ctx, arg)
+	return report, pgError(err)
+}
+
+func (r pgReports) ListCaseReports(ctx context.Context, caseID uuid.UUID) ([]database.Report, error) {
+	reports, err := r.q.ListCaseReports(ctx, caseID)
+	return reports, pgError(err)
+}
+
+func (r pgReports) ListReporterReports(ctx context.Context, reporterID uuid.UUID) ([]database.Report, error) {
+	reports, err := r.q.ListReporterReports(ctx, reporterID)
+	return reports, pgError(err)
+}
+
+func (r pgReports) ResolveReports(ctx context.Context, arg database.ResolveReportsParams) ([]database.Report, error) {
+	reports, err := r.q.ResolveReports(ctx, arg)
+	return reports, pgError(err)
+}
+
+type pgNotifications struct{ q *database.Queries }
+
+func (r pgNotifications) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error) {
+	n, err := r.q.CreateNotification(ctx, arg)
+	return n, pgError(err)
+}
+
+func (r pgNotifications) ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error) {
+	notifications, err := r.q.ListNotifications(ctx, arg)
+	return notifications, pgError(err)
+}
+
+func (r pgNotifications) MarkNotificationRead(ctx context.Context, arg database.MarkNotificationReadParams) (database.Notification, error) {
+	n, err := r.q.MarkNotificationRead(ctx, arg)
+	return n, pgError(err)
+}
+
+type pgOutbox struct{ q *database.Queries }
+
+func (r pgOutbox) CreateOutboxEvent(ctx context.Context, arg database.CreateOutboxEventParams) (database.OutboxEvent, error) {
+	event, err := r.q.CreateOutboxEvent(ctx, arg)
+	return event, pgError(err)
+}
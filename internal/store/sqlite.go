@@ -0,0 +1,524 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/database/sqlite"
+	"github.com/d-shames3/chirpy/internal/tracing"
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLite is a Store for single-node deployments. The schema lives in
+// sql/sqlite/schema and mirrors the Postgres one, except that ids are
+// generated here rather than by gen_random_uuid().
+type SQLite struct {
+	db *sql.DB
+	q  *sqlite.Queries
+}
+
+func NewSQLite(db *sql.DB) *SQLite {
+	return &SQLite{db: db, q: sqlite.New(tracing.WrapDB(db, "sqlite"))}
+}
+
+func (s *SQLite) Users() UserRepository                 { return liteUsers{s.q} }
+func (s *SQLite) Chirps() ChirpRepository               { return liteChirps{s.q} }
+func (s *SQLite) RefreshTokens() RefreshTokenRepository { return liteRefreshTokens{s.q} }
+func (s *SQLite) Outbox() OutboxRepository              { return liteOutbox{} }
+
+func (s *SQLite) OAuth() OAuthRepository { return liteOAuth{s.q} }
+
+func (s *SQLite) Moderation() ModerationRepository { return liteModeration{s.q} }
+
+func (s *SQLite) Reports() ReportRepository { return liteReports{s.q} }
+
+func (s *SQLite) Notifications() NotificationRepository { return liteNotifications{s.q} }
+
+func (s *SQLite) PersonalAccessTokens() PersonalAccessTokenRepository {
+	return litePersonalAccessTokens{s.q}
+}
+
+func (s *SQLite) WithTx(ctx context.Context, fn func(s Store) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SQLite{db: s.db, q: sqlite.New(tracing.WrapDB(tx, "sqlite"))}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func liteError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var liteErr sqlite3.Error
+	if errors.As(err, &liteErr) {
+		switch liteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return fmt.Errorf("%w: %w", ErrDuplicate, err)
+		case sqlite3.ErrConstraintForeignKey:
+			return fmt.Errorf("%w: %w", ErrMissingReference, err)
+		}
+	}
+	return err
+}
+
+type liteUsers struct{ q *sqlite.Queries }
+
+func (r liteUsers) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	user, err := r.q.CreateUser(ctx, sqlite.CreateUserParams{
+		ID:             uuid.New(),
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+	})
+	return database.User(user), liteError(err)
+}
+
+func (r liteUsers) GetUser(ctx context.Context, email string) (database.User, error) {
+	user, err := r.q.GetUser(ctx, email)
+	return database.User(user), liteError(err)
+}
+
+func (r liteUsers) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.GetUserByID(ctx, id)
+	return database.User(user), liteError(err)
+}
+
+func (r liteUsers) UpdateUserCreds(ctx context.Context, arg database.UpdateUserCredsParams) (database.User, error) {
+	user, err := r.q.UpdateUserCreds(ctx, sqlite.UpdateUserCredsParams(arg))
+	return database.User(user), liteError(err)
+}
+
+func (r liteUsers) UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error) {
+	user, err := r.q.UpdateUserRole(ctx, sqlite.UpdateUserRoleParams(arg))
+	return database.User(user), liteError(err)
+}
+
+func (r liteUsers) UpdateUserChirpyRedStatus(ctx context.Context, id uuid.UUID) error {
+	return liteError(r.q.UpdateUserChirpyRedStatus(ctx, id))
+}
+
+func (r liteUsers) DeleteUsers(ctx context.Context) error {
+	return liteError(r.q.DeleteUsers(ctx))
+}
+
+type liteChirps struct{ q *sqlite.Queries }
+
+func (r liteChirps) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	chirp, err := r.q.CreateChirp(ctx, sqlite.CreateChirpParams{
+		ID:     uuid.New(),
+		UserID: arg.UserID,
+		Body:   arg.Body,
+	})
+	return database.Chirp(chirp), liteError(err)
+}
+
+func (r liteChirps) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := r.q.GetChirp(ctx, id)
+	return database.Chirp(chirp), liteError(err)
+}
+
+func (r liteChirps) GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]database.Chirp, error) {
+	chirps, err := r.q.GetChirps(ctx, viewerID)
+	return liteChirpList(chirps), liteError(err)
+}
+
+func (r liteChirps) GetChirpsByAuthor(ctx context.Context, arg database.GetChirpsByAuthorParams) ([]database.Chirp, error) {
+	chirps, err := r.q.GetChirpsByAuthor(ctx, sqlite.GetChirpsByAuthorParams(arg))
+	return liteChirpList(chirps), liteError(err)
+}
+
+func (r liteChirps) GetRecentChirpsByAuthor(ctx context.Context, arg database.GetRecentChirpsByAuthorParams) ([]database.Chirp, error) {
+	chirps, err := r.q.GetRecentChirpsByAuthor(ctx, sqlite.GetRecentChirpsByAuthorParams{
+		UserID:        arg.UserID,
+		WindowSeconds: int64(arg.WindowSeconds),
+		RowLimit:      int64(arg.RowLimit),
+	})
+	return liteChirpList(chirps), liteError(err)
+}
+
+func (r liteChirps) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	return liteError(r.q.DeleteChirp(ctx, id))
+}
+
+func (r liteChirps) HideChirp(ctx context.Context, id uuid.UUID) error {
+	return liteError(r.q.HideChirp(ctx, id))
+}
+
+func (r liteChirps) UnhideChirp(ctx context.Context, id uuid.UUID) error {
+	return liteError(r.q.UnhideChirp(ctx, id))
+}
+
+func liteChirpList(chirps []sqlite.Chirp) []database.Chirp {
+	if chirps == nil {
+		return nil
+	}
+	converted := make([]database.Chirp, len(chirps))
+	for i, c := range chirps {
+		converted[i] = database.Chirp(c)
+	}
+	return converted
+}
+
+type liteRefreshTokens struct{ q *sqlite.Queries }
+
+func (r liteRefreshTokens) CreateToken(ctx context.Context, arg database.CreateTokenParams) (database.RefreshToken, error) {
+	token, err := r.q.CreateToken(ctx, sqlite.CreateTokenParams(arg))
+	return database.RefreshToken(token), liteError(err)
+}
+
+func (r liteRefreshTokens) GetToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	refreshToken, err := r.q.GetToken(ctx, token)
+	return database.RefreshToken(refreshToken), liteError(err)
+}
+
+func (r liteRefreshTokens) RevokeToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	refreshToken, err := r.q.RevokeToken(ctx, token)
+	return database.RefreshToken(refreshToken), liteError(err)
+}
+
+func (r liteRefreshTokens) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	return liteError(r.q.RevokeUserTokens(ctx, userID))
+}
+
+func (r liteRefreshTokens) DeleteStaleTokens(ctx context.Context) (int64, error) {
+	deleted, err := r.q.DeleteStaleTokens(ctx)
+	return deleted, liteError(err)
+}
+
+type litePersonalAccessTokens struct{ q *sqlite.Queries }
+
+func (r litePersonalAccessTokens) CreatePersonalAccessToken(ctx context.Context, arg database.CreatePersonalAccessTokenParams) (database.PersonalAccessToken, error) {
+	token, err := r.q.CreatePersonalAccessToken(ctx, sqlite.CreatePersonalAccessTokenParams{
+		ID:            uuid.New(),
+		UserID:        arg.UserID,
+		Name:          arg.Name,
+		TokenHash:     arg.TokenHash,
+		Scopes:        arg.Scopes,
+		ExpiresInDays: sql.NullInt64{Int64: int64(arg.ExpiresInDays.Int32), Valid: arg.ExpiresInDays.Valid},
+	})
+	return database.PersonalAccessToken(token), liteError(err)
+}
+
+func (r litePersonalAccessTokens) GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (database.PersonalAccessToken, error) {
+	token, err := r.q.GetPersonalAccessTokenByHash(ctx, tokenHash)
+	return database.PersonalAccessToken(token), liteError(err)
+}
+
+func (r litePersonalAccessTokens) ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]database.PersonalAccessToken, error) {
+	tokens, err := r.q.ListPersonalAccessTokens(ctx, userID)
+	if tokens == nil {
+		return nil, liteError(err)
+	}
+	converted := make([]database.PersonalAccessToken, len(tokens))
+	for i, t := range tokens {
+		converted[i] = database.PersonalAccessToken(t)
+	}
+	return converted, liteError(err)
+}
+
+func (r litePersonalAccessTokens) DeletePersonalAccessToken(ctx context.Context, arg database.DeletePersonalAccessTokenParams) error {
+	deleted, err := r.q.DeletePersonalAccessToken(ctx, sqlite.DeletePersonalAccessTokenParams(arg))
+	if err != nil {
+		return liteError(err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r litePersonalAccessTokens) TouchPersonalAccessToken(ctx context.Context, id uuid.UUID) error {
+	return liteError(r.q.TouchPersonalAccessToken(ctx, id))
+}
+
+type liteOAuth struct{ q *sqlite.Queries }
+
+func (r liteOAuth) CreateOAuthClient(ctx context.Context, arg database.CreateOAuthClientParams) (database.OAuthClient, error) {
+	client, err := r.q.CreateOAuthClient(ctx, sqlite.CreateOAuthClientParams{
+		ID:           uuid.New(),
+		UserID:       arg.UserID,
+		Name:         arg.Name,
+		SecretHash:   arg.SecretHash,
+		RedirectUris: arg.RedirectUris,
+	})
+	return database.OAuthClient(client), liteError(err)
+}
+
+func (r liteOAuth) GetOAuthClient(ctx context.Context, id uuid.UUID) (database.OAuthClient, error) {
+	client, err := r.q.GetOAuthClient(ctx, id)
+	return database.OAuthClient(client), liteError(err)
+}
+
+func (r liteOAuth) ListOAuthClients(ctx context.Context, userID uuid.UUID) ([]database.OAuthClient, error) {
+	clients, err := r.q.ListOAuthClients(ctx, userID)
+	if clients == nil {
+		return nil, liteError(err)
+	}
+	converted := make([]database.OAuthClient, len(clients))
+	for i, c := range clients {
+		converted[i] = database.OAuthClient(c)
+	}
+	return converted, liteError(err)
+}
+
+func (r liteOAuth) DeleteOAuthClient(ctx context.Context, arg database.DeleteOAuthClientParams) error {
+	deleted, err := r.q.DeleteOAuthClient(ctx, sqlite.DeleteOAuthClientParams(arg))
+	if err != nil {
+		return liteError(err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r liteOAuth) CreateOAuthAuthorizationCode(ctx context.Context, arg database.CreateOAuthAuthorizationCodeParams) (database.OAuthAuthorizationCode, error) {
+	code, err := r.q.CreateOAuthAuthorizationCode(ctx, sqlite.CreateOAuthAuthorizationCodeParams(arg))
+	return database.OAuthAuthorizationCode(code), liteError(err)
+}
+
+func (r liteOAuth) ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (database.OAuthAuthorizationCode, error) {
+	code, err := r.q.ConsumeOAuthAuthorizationCode(ctx, codeHash)
+	return database.OAuthAuthorizationCode(code), liteError(err)
+}
+
+func (r liteOAuth) DeleteExpiredOAuthAuthorizationCodes(ctx context.Context) (int64, error) {
+	deleted, err := r.q.DeleteExpiredOAuthAuthorizationCodes(ctx)
+	return deleted, liteError(err)
+}
+
+type liteModeration struct{ q *sqlite.Queries }
+
+func (r liteModeration) SuspendUser(ctx context.Context, arg database.SuspendUserParams) (database.User, error) {
+	user, err := r.q.SuspendUser(ctx, sqlite.SuspendUserParams{Hours: int64(arg.Hours), ID: arg.ID})
+	return database.User(user), liteError(err)
+}
+
+func (r liteModeration) UnsuspendUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.UnsuspendUser(ctx, id)
+	return database.User(user), liteError(err)
+}
+
+func (r liteModeration) BanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.BanUser(ctx, id)
+	return database.User(user), liteError(err)
+}
+
+func (r liteModeration) UnbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.UnbanUser(ctx, id)
+	return database.User(user), liteError(err)
+}
+
+func (r liteModeration) ShadowbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.ShadowbanUser(ctx, id)
+	return database.User(user), liteError(err)
+}
+
+func (r liteModeration) UnshadowbanUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := r.q.UnshadowbanUser(ctx, id)
+	return database.User(user), liteError(err)
+}
+
+func (r liteModeration) CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error) {
+	action, err := r.q.CreateModerationAction(ctx, sqlite.CreateModerationActionParams{
+		ID:          uuid.New(),
+		UserID:      arg.UserID,
+		ModeratorID: arg.ModeratorID,
+		Action:      arg.Action,
+		Reason:      arg.Reason,
+		ExpiresAt:   arg.ExpiresAt,
+	})
+	return database.ModerationAction(action), liteError(err)
+}
+
+func (r liteModeration) ListModerationActions(ctx context.Context, userID uuid.UUID) ([]database.ModerationAction, error) {
+	actions, err := r.q.ListModerationActions(ctx, userID)
+	if actions == nil {
+		return nil, liteError(err)
+	}
+	converted := make([]database.ModerationAction, len(actions))
+	for i, a := range actions {
+		converted[i] = database.ModerationAction(a)
+	}
+	return converted, liteError(err)
+}
+
+type liteReports struct{ q *sqlite.Queries }
+
+func (r liteReports) CreateReportCase(ctx context.Context, arg database.CreateReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.CreateReportCase(ctx, sqlite.CreateReportCaseParams{
+		ID:         uuid.New(),
+		ChirpID:    arg.ChirpID,
+		AuthorID:   arg.AuthorID,
+		FlagReason: arg.FlagReason,
+		HidChirp:   arg.HidChirp,
+	})
+	return liteReportCase(c), liteError(err)
+}
+
+func (r liteReports) GetReportCase(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	c, err := r.q.GetReportCase(ctx, id)
+	return liteReportCase(c), liteError(err)
+}
+
+func (r liteReports) OpenReportCase(ctx context.Context, arg database.OpenReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.OpenReportCase(ctx, sqlite.OpenReportCaseParams{
+		ID:       uuid.New(),
+		ChirpID:  arg.ChirpID,
+		AuthorID: arg.AuthorID,
+	})
+	return liteReportCase(c), liteError(err)
+}
+
+func (r liteReports) IncrementReportCount(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	c, err := r.q.IncrementReportCount(ctx, id)
+	return liteReportCase(c), liteError(err)
+}
+
+func (r liteReports) MarkReportCaseHidChirp(ctx context.Context, id uuid.UUID) (database.ReportCase, error) {
+	c, err := r.q.MarkReportCaseHidChirp(ctx, id)
+	return liteReportCase(c), liteError(err)
+}
+
+func (r liteReports) ListOpenReportCases(ctx context.Context, limit int32) ([]database.ReportCase, error) {
+	cases, err := r.q.ListOpenReportCases(ctx, int64(limit))
+	return liteReportCaseList(cases), liteError(err)
+}
+
+func (r liteReports) ListResolvedReportCases(ctx context.Context, limit int32) ([]database.ReportCase, error) {
+	cases, err := r.q.ListResolvedReportCases(ctx, int64(limit))
+	return liteReportCaseList(cases), liteError(err)
+}
+
+func (r liteReports) ClaimReportCase(ctx context.Context, arg database.ClaimReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.ClaimReportCase(ctx, sqlite.ClaimReportCaseParams(arg))
+	return liteReportCase(c), liteError(err)
+}
+
+func (r liteReports) ReleaseReportCase(ctx context.Context, arg database.ReleaseReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.ReleaseReportCase(ctx, sqlite.ReleaseReportCaseParams(arg))
+	return liteReportCase(c), liteError(err)
+}
+
+func (r liteReports) ResolveReportCase(ctx context.Context, arg database.ResolveReportCaseParams) (database.ReportCase, error) {
+	c, err := r.q.ResolveReportCase(ctx, sqlite.ResolveReportCaseParams(arg))
+	return liteReportCase(c), liteError(err)
+}
+
+func (r liteReports) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	report, err := r.q.CreateReport(ctx, sqlite.CreateReportParams{
+		ID:         uuid.New(),
+		CaseID:     arg.CaseID,
+		ChirpID:    arg.ChirpID,
+		ReporterID: arg.ReporterID,
+		Reason:     arg.Reason,
+		Details:    arg.Details,
+	})
+	return database.Report(report), liteError(err)
+}
+
+func (r liteReports) ListCaseReports(ctx context.Context, caseID uuid.UUID) ([]database.Report, error) {
+	reports, err := r.q.ListCaseReports(ctx, caseID)
+	return liteReportList(reports), liteError(err)
+}
+
+func (r liteReports) ListReporterReports(ctx context.Context, reporterID uuid.UUID) ([]database.Report, error) {
+	reports, err := r.q.ListReporterReports(ctx, reporterID)
+	return liteReportList(reports), liteError(err)
+}
+
+func (r liteReports) ResolveReports(ctx context.Context, arg database.ResolveReportsParams) ([]database.Report, error) {
+	reports, err := r.q.ResolveReports(ctx, sqlite.ResolveReportsParams(arg))
+	return liteReportList(reports), liteError(err)
+}
+
+func liteReportCase(c sqlite.ReportCase) database.ReportCase {
+	return database.ReportCase{
+		ID:             c.ID,
+		ChirpID:        c.ChirpID,
+		AuthorID:       c.AuthorID,
+		ReportCount:    int32(c.ReportCount),
+		ClaimedBy:      c.ClaimedBy,
+		ClaimedAt:      c.ClaimedAt,
+		Resolution:     c.Resolution,
+		ResolutionNote: c.ResolutionNote,
+		ResolvedBy:     c.ResolvedBy,
+		ResolvedAt:     c.ResolvedAt,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+		FlagReason:     c.FlagReason,
+		HidChirp:       c.HidChirp,
+	}
+}
+
+func liteReportCaseList(cases []sqlite.ReportCase) []database.ReportCase {
+	if cases == nil {
+		return nil
+	}
+	converted := make([]database.ReportCase, len(cases))
+	for i, c := range cases {
+		converted[i] = liteReportCase(c)
+	}
+	return converted
+}
+
+func liteReportList(reports []sqlite.Report) []database.Report {
+	if reports == nil {
+		return nil
+	}
+	converted := make([]database.Report, len(reports))
+	for i, report := range reports {
+		converted[i] = database.Report(report)
+	}
+	return converted
+}
+
+type liteNotifications struct{ q *sqlite.Queries }
+
+func (r liteNotifications) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error) {
+	n, err := r.q.CreateNotification(ctx, sqlite.CreateNotificationParams{
+		ID:      uuid.New(),
+		UserID:  arg.UserID,
+		Kind:    arg.Kind,
+		Payload: arg.Payload,
+	})
+	return database.Notification(n), liteError(err)
+}
+
+func (r liteNotifications) ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error) {
+	notifications, err := r.q.ListNotifications(ctx, sqlite.ListNotificationsParams{
+		UserID:   arg.UserID,
+		RowLimit: int64(arg.RowLimit),
+	})
+	if notifications == nil {
+		return nil, liteError(err)
+	}
+	converted := make([]database.Notification, len(notifications))
+	for i, n := range notifications {
+		converted[i] = database.Notification(n)
+	}
+	return converted, liteError(err)
+}
+
+func (r liteNotifications) MarkNotificationRead(ctx context.Context, arg database.MarkNotificationReadParams) (database.Notification, error) {
+	n, err := r.q.MarkNotificationRead(ctx, sqlite.MarkNotificationReadParams(arg))
+	return database.Notification(n), liteError(err)
+}
+
+// liteOutbox discards events. Nothing dispatches or prunes the outbox on
+// SQLite, so recording them would only grow the table forever.
+type liteOutbox struct{}
+
+func (liteOutbox) CreateOutboxEvent(ctx context.Context, arg database.CreateOutboxEventParams) (database.OutboxEvent, error) {
+	return database.OutboxEvent{}, nil
+}
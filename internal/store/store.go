@@ -0,0 +1,154 @@
+// Package store defines the persistence interfaces Chirpy's handlers depend
+// on, along with a Postgres implementation backed by the sqlc queries and an
+// in-memory implementation for tests and local experiments.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound         = errors.New("store: not found")
+	ErrDuplicate        = errors.New("store: duplicate value")
+	ErrMissingReference = errors.New("store: referenced row does not exist")
+)
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	GetUser(ctx context.Context, email string) (database.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	UpdateUserCreds(ctx context.Context, arg database.UpdateUserCredsParams) (database.User, error)
+	UpdateUserChirpyRedStatus(ctx context.Context, id uuid.UUID) error
+	UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error)
+	DeleteUsers(ctx context.Context) error
+}
+
+type ChirpRepository interface {
+	CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error)
+	GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	// GetChirps and GetChirpsByAuthor leave out hidden chirps and chirps by
+	// shadowbanned users unless the viewer wrote them.
+	GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]database.Chirp, error)
+	GetChirpsByAuthor(ctx context.Context, arg database.GetChirpsByAuthorParams) ([]database.Chirp, error)
+	// GetRecentChirpsByAuthor returns the author's chirps from the last
+	// WindowSeconds, newest first, hidden ones included.
+	GetRecentChirpsByAuthor(ctx context.Context, arg database.GetRecentChirpsByAuthorParams) ([]database.Chirp, error)
+	DeleteChirp(ctx context.Context, id uuid.UUID) error
+	HideChirp(ctx context.Context, id uuid.UUID) error
+	UnhideChirp(ctx context.Context, id uuid.UUID) error
+}
+
+type RefreshTokenRepository interface {
+	CreateToken(ctx context.Context, arg database.CreateTokenParams) (database.RefreshToken, error)
+	GetToken(ctx context.Context, token string) (database.RefreshToken, error)
+	RevokeToken(ctx context.Context, token string) (database.RefreshToken, error)
+	// RevokeUserTokens revokes every unrevoked refresh token the user holds.
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID) error
+	DeleteStaleTokens(ctx context.Context) (int64, error)
+}
+
+type PersonalAccessTokenRepository interface {
+	CreatePersonalAccessToken(ctx context.Context, arg database.CreatePersonalAccessTokenParams) (database.PersonalAccessToken, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (database.PersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]database.PersonalAccessToken, error)
+	// DeletePersonalAccessToken returns ErrNotFound unless the token exists
+	// and belongs to arg.UserID.
+	DeletePersonalAccessToken(ctx context.Context, arg database.DeletePersonalAccessTokenParams) error
+	// TouchPersonalAccessToken records a use of the token, at most once a
+	// minute.
+	TouchPersonalAccessToken(ctx context.Context, id uuid.UUID) error
+}
+
+type OAuthRepository interface {
+	CreateOAuthClient(ctx context.Context, arg database.CreateOAuthClientParams) (database.OAuthClient, error)
+	GetOAuthClient(ctx context.Context, id uuid.UUID) (database.OAuthClient, error)
+	ListOAuthClients(ctx context.Context, userID uuid.UUID) ([]database.OAuthClient, error)
+	// DeleteOAuthClient returns ErrNotFound unless the client exists and
+	// belongs to arg.UserID. Its codes and refresh tokens go with it.
+	DeleteOAuthClient(ctx context.Context, arg database.DeleteOAuthClientParams) error
+	CreateOAuthAuthorizationCode(ctx context.Context, arg database.CreateOAuthAuthorizationCodeParams) (database.OAuthAuthorizationCode, error)
+	// ConsumeOAuthAuthorizationCode deletes and returns the code, so each
+	// code can be exchanged at most once.
+	ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (database.OAuthAuthorizationCode, error)
+	DeleteExpiredOAuthAuthorizationCodes(ctx context.Context) (int64, error)
+}
+
+// ModerationRepository sets and lifts sanctions on users and keeps the log
+// of moderation actions. The sanction methods return ErrNotFound for
+// unknown users.
+type ModerationRepository interface {
+	SuspendUser(ctx context.Context, arg database.SuspendUserParams) (database.User, error)
+	UnsuspendUser(ctx context.Context, id uuid.UUID) (database.User, error)
+	BanUser(ctx context.Context, id uuid.UUID) (database.User, error)
+	UnbanUser(ctx context.Context, id uuid.UUID) (database.User, error)
+	ShadowbanUser(ctx context.Context, id uuid.UUID) (database.User, error)
+	UnshadowbanUser(ctx context.Context, id uuid.UUID) (database.User, error)
+	CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error)
+	// ListModerationActions returns the actions taken on a user, newest
+	// first.
+	ListModerationActions(ctx context.Context, userID uuid.UUID) ([]database.ModerationAction, error)
+}
+
+// ReportRepository keeps users' reports on chirps and the cases moderators
+// work through. All the reports on a chirp collect in its one open case.
+type ReportRepository interface {
+	CreateReportCase(ctx context.Context, arg database.CreateReportCaseParams) (database.ReportCase, error)
+	GetReportCase(ctx context.Context, id uuid.UUID) (database.ReportCase, error)
+	// OpenReportCase returns the chirp's unresolved case, creating it if
+	// there is none. Concurrent calls for the same chirp get the same case.
+	OpenReportCase(ctx context.Context, arg database.OpenReportCaseParams) (database.ReportCase, error)
+	IncrementReportCount(ctx context.Context, id uuid.UUID) (database.ReportCase, error)
+	// MarkReportCaseHidChirp records that the case hid its chirp, so
+	// dismissing the case should put it back.
+	MarkReportCaseHidChirp(ctx context.Context, id uuid.UUID) (database.ReportCase, error)
+	// ListOpenReportCases returns unresolved cases, most reported first.
+	ListOpenReportCases(ctx context.Context, limit int32) ([]database.ReportCase, error)
+	// ListResolvedReportCases returns resolved cases, most recent first.
+	ListResolvedReportCases(ctx context.Context, limit int32) ([]database.ReportCase, error)
+	// ClaimReportCase, ReleaseReportCase and ResolveReportCase return
+	// ErrNotFound if the case is resolved or claimed by another moderator.
+	ClaimReportCase(ctx context.Context, arg database.ClaimReportCaseParams) (database.ReportCase, error)
+	ReleaseReportCase(ctx context.Context, arg database.ReleaseReportCaseParams) (database.ReportCase, error)
+	ResolveReportCase(ctx context.Context, arg database.ResolveReportCaseParams) (database.ReportCase, error)
+	// CreateReport returns ErrDuplicate if the reporter already has a report
+	// in the case.
+	CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error)
+	ListCaseReports(ctx context.Context, caseID uuid.UUID) ([]database.Report, error)
+	// ListReporterReports returns a user's reports, newest first.
+	ListReporterReports(ctx context.Context, reporterID uuid.UUID) ([]database.Report, error)
+	// ResolveReports marks every report in a case resolved and returns them.
+	ResolveReports(ctx context.Context, arg database.ResolveReportsParams) ([]database.Report, error)
+}
+
+// NotificationRepository keeps the messages shown in a user's inbox.
+type NotificationRepository interface {
+	CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error)
+	// ListNotifications returns a user's notifications, newest first.
+	ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error)
+	// MarkNotificationRead returns ErrNotFound if the notification isn't the
+	// user's. Marking it read again keeps the first read time.
+	MarkNotificationRead(ctx context.Context, arg database.MarkNotificationReadParams) (database.Notification, error)
+}
+
+type OutboxRepository interface {
+	CreateOutboxEvent(ctx context.Context, arg database.CreateOutboxEventParams) (database.OutboxEvent, error)
+}
+
+type Store interface {
+	Users() UserRepository
+	Chirps() ChirpRepository
+	RefreshTokens() RefreshTokenRepository
+	PersonalAccessTokens() PersonalAccessTokenRepository
+	OAuth() OAuthRepository
+	Moderation() ModerationRepository
+	Reports() ReportRepository
+	Notifications() NotificationRepository
+	Outbox() OutboxRepository
+	// WithTx runs fn against a Store whose writes commit together if fn
+	// returns nil and are discarded otherwise.
+	WithTx(ctx context.Context, fn func(s Store) error) error
+}
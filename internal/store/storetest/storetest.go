@@ -0,0 +1,1085 @@
+// Package storetest is a conformance suite that every store.Store
+// implementation must pass.
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+// Run exercises s against the behaviour the handlers rely on. newStore must
+// return an empty store each time it is called.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"UniqueEmails", testUniqueEmails},
+		{"GetUserNotFound", testGetUserNotFound},
+		{"GetUserByID", testGetUserByID},
+		{"UpdateUserCreds", testUpdateUserCreds},
+		{"ChirpyRed", testChirpyRed},
+		{"UserRole", testUserRole},
+		{"UserSanctions", testUserSanctions},
+		{"ModerationActions", testModerationActions},
+		{"ChirpOrdering", testChirpOrdering},
+		{"ShadowbannedChirps", testShadowbannedChirps},
+		{"HiddenChirps", testHiddenChirps},
+		{"ChirpNotFound", testChirpNotFound},
+		{"ChirpRequiresUser", testChirpRequiresUser},
+		{"DeleteChirp", testDeleteChirp},
+		{"RecentChirpsByAuthor", testRecentChirpsByAuthor},
+		{"RefreshTokens", testRefreshTokens},
+		{"DeleteStaleTokens", testDeleteStaleTokens},
+		{"RevokeUserTokens", testRevokeUserTokens},
+		{"PersonalAccessTokens", testPersonalAccessTokens},
+		{"OAuthClients", testOAuthClients},
+		{"OAuthAuthorizationCodes", testOAuthAuthorizationCodes},
+		{"Reports", testReports},
+		{"ReportCaseClaims", testReportCaseClaims},
+		{"ReportsOutliveChirp", testReportsOutliveChirp},
+		{"Notifications", testNotifications},
+		{"CascadingDelete", testCascadingDelete},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, s store.Store, email string) database.User {
+	t.Helper()
+	user, err := s.Users().CreateUser(context.Background(), database.CreateUserParams{
+		Email:          email,
+		HashedPassword: "hash",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser(%q) error = %v", email, err)
+	}
+	return user
+}
+
+func createChirp(t *testing.T, s store.Store, userID uuid.UUID, body string) database.Chirp {
+	t.Helper()
+	chirp, err := s.Chirps().CreateChirp(context.Background(), database.CreateChirpParams{
+		UserID: userID,
+		Body:   body,
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp(%q) error = %v", body, err)
+	}
+	return chirp
+}
+
+func testUniqueEmails(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	if user.ID == uuid.Nil || user.CreatedAt.IsZero() || user.IsChirpyRed {
+		t.Errorf("CreateUser returned %+v, want generated id, timestamps and no Chirpy Red", user)
+	}
+
+	_, err := s.Users().CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com", HashedPassword: "hash"})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Errorf("CreateUser with taken email error = %v, want %v", err, store.ErrDuplicate)
+	}
+}
+
+func testGetUserNotFound(t *testing.T, s store.Store) {
+	_, err := s.Users().GetUser(context.Background(), "nobody@example.com")
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetUser error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testGetUserByID(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+
+	got, err := s.Users().GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID error = %v", err)
+	}
+	if got.Email != user.Email || !got.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("GetUserByID = %+v, want %+v", got, user)
+	}
+
+	if _, err := s.Users().GetUserByID(ctx, uuid.New()); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetUserByID for missing user error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testUserRole(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	if user.Role != "user" {
+		t.Errorf("new user role = %q, want %q", user.Role, "user")
+	}
+
+	updated, err := s.Users().UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: user.ID, Role: "admin"})
+	if err != nil {
+		t.Fatalf("UpdateUserRole error = %v", err)
+	}
+	if updated.Role != "admin" {
+		t.Errorf("UpdateUserRole returned role %q, want %q", updated.Role, "admin")
+	}
+	got, err := s.Users().GetUser(ctx, user.Email)
+	if err != nil || got.Role != "admin" {
+		t.Errorf("GetUser after role update = %+v, %v", got, err)
+	}
+
+	if _, err := s.Users().UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: user.ID, Role: "root"}); err == nil {
+		t.Error("UpdateUserRole accepted an unknown role")
+	}
+	if _, err := s.Users().UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: uuid.New(), Role: "admin"}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("UpdateUserRole for missing user error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testUpdateUserCreds(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	other := createUser(t, s, "jesse@example.com")
+
+	updated, err := s.Users().UpdateUserCreds(ctx, database.UpdateUserCredsParams{
+		ID:             user.ID,
+		Email:          "heisenberg@example.com",
+		HashedPassword: "newhash",
+	})
+	if err != nil {
+		t.Fatalf("UpdateUserCreds error = %v", err)
+	}
+	if updated.Email != "heisenberg@example.com" || updated.HashedPassword != "newhash" {
+		t.Errorf("UpdateUserCreds returned %+v", updated)
+	}
+	if _, err := s.Users().GetUser(ctx, "heisenberg@example.com"); err != nil {
+		t.Errorf("GetUser after update error = %v", err)
+	}
+
+	_, err = s.Users().UpdateUserCreds(ctx, database.UpdateUserCredsParams{
+		ID:             other.ID,
+		Email:          "heisenberg@example.com",
+		HashedPassword: "hash",
+	})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Errorf("UpdateUserCreds to taken email error = %v, want %v", err, store.ErrDuplicate)
+	}
+
+	_, err = s.Users().UpdateUserCreds(ctx, database.UpdateUserCredsParams{
+		ID:             uuid.New(),
+		Email:          "ghost@example.com",
+		HashedPassword: "hash",
+	})
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("UpdateUserCreds for missing user error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testChirpyRed(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+
+	if err := s.Users().UpdateUserChirpyRedStatus(ctx, user.ID); err != nil {
+		t.Fatalf("UpdateUserChirpyRedStatus error = %v", err)
+	}
+	got, err := s.Users().GetUser(ctx, user.Email)
+	if err != nil {
+		t.Fatalf("GetUser error = %v", err)
+	}
+	if !got.IsChirpyRed {
+		t.Errorf("IsChirpyRed = false after upgrade")
+	}
+
+	if err := s.Users().UpdateUserChirpyRedStatus(ctx, uuid.New()); err != nil {
+		t.Errorf("UpdateUserChirpyRedStatus for missing user error = %v, want nil", err)
+	}
+}
+
+func testUserSanctions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	mod := s.Moderation()
+
+	suspended, err := mod.SuspendUser(ctx, database.SuspendUserParams{ID: user.ID, Hours: 24})
+	if err != nil {
+		t.Fatalf("SuspendUser error = %v", err)
+	}
+	wantUntil := time.Now().Add(24 * time.Hour)
+	if !suspended.SuspendedUntil.Valid || suspended.SuspendedUntil.Time.Sub(wantUntil).Abs() > time.Minute {
+		t.Errorf("SuspendedUntil = %+v, want about %v", suspended.SuspendedUntil, wantUntil)
+	}
+	if _, err := mod.BanUser(ctx, user.ID); err != nil {
+		t.Fatalf("BanUser error = %v", err)
+	}
+	if _, err := mod.ShadowbanUser(ctx, user.ID); err != nil {
+		t.Fatalf("ShadowbanUser error = %v", err)
+	}
+
+	got, err := s.Users().GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID error = %v", err)
+	}
+	if !got.SuspendedUntil.Valid || !got.BannedAt.Valid || !got.ShadowbannedAt.Valid {
+		t.Errorf("GetUserByID = %+v, want suspended, banned and shadowbanned", got)
+	}
+
+	mod.UnsuspendUser(ctx, user.ID)
+	mod.UnbanUser(ctx, user.ID)
+	lifted, err := mod.UnshadowbanUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("UnshadowbanUser error = %v", err)
+	}
+	if lifted.SuspendedUntil.Valid || lifted.BannedAt.Valid || lifted.ShadowbannedAt.Valid {
+		t.Errorf("after lifting sanctions = %+v, want none", lifted)
+	}
+
+	if _, err := mod.BanUser(ctx, uuid.New()); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("BanUser for missing user error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testModerationActions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	moderator := createUser(t, s, "hank@example.com")
+	mod := s.Moderation()
+
+	for _, action := range []string{"suspend", "unsuspend"} {
+		_, err := mod.CreateModerationAction(ctx, database.CreateModerationActionParams{
+			UserID:      user.ID,
+			ModeratorID: uuid.NullUUID{UUID: moderator.ID, Valid: true},
+			Action:      action,
+			Reason:      "spam",
+		})
+		if err != nil {
+			t.Fatalf("CreateModerationAction(%q) error = %v", action, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	actions, err := mod.ListModerationActions(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListModerationActions error = %v", err)
+	}
+	if len(actions) != 2 || actions[0].Action != "unsuspend" || actions[1].Action != "suspend" {
+		t.Fatalf("ListModerationActions = %+v, want unsuspend then suspend", actions)
+	}
+	if actions[0].ModeratorID.UUID != moderator.ID || actions[0].Reason != "spam" {
+		t.Errorf("ListModerationActions[0] = %+v, want moderator %s and reason spam", actions[0], moderator.ID)
+	}
+
+	if others, err := mod.ListModerationActions(ctx, moderator.ID); err != nil || len(others) != 0 {
+		t.Errorf("ListModerationActions for moderator = %+v, %v, want none", others, err)
+	}
+
+	_, err = mod.CreateModerationAction(ctx, database.CreateModerationActionParams{UserID: user.ID, Action: "smite"})
+	if err == nil {
+		t.Errorf("CreateModerationAction with unknown action succeeded")
+	}
+}
+
+func testChirpOrdering(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+
+	var want []uuid.UUID
+	for _, author := range []uuid.UUID{walt.ID, jesse.ID, walt.ID} {
+		want = append(want, createChirp(t, s, author, "chirp").ID)
+		time.Sleep(time.Millisecond)
+	}
+
+	chirps, err := s.Chirps().GetChirps(ctx, uuid.NullUUID{})
+	if err != nil {
+		t.Fatalf("GetChirps error = %v", err)
+	}
+	if len(chirps) != len(want) {
+		t.Fatalf("GetChirps returned %d chirps, want %d", len(chirps), len(want))
+	}
+	for i, c := range chirps {
+		if c.ID != want[i] {
+			t.Errorf("GetChirps[%d] = %s, want %s", i, c.ID, want[i])
+		}
+	}
+
+	byAuthor, err := s.Chirps().GetChirpsByAuthor(ctx, database.GetChirpsByAuthorParams{UserID: walt.ID})
+	if err != nil {
+		t.Fatalf("GetChirpsByAuthor error = %v", err)
+	}
+	if len(byAuthor) != 2 || byAuthor[0].ID != want[0] || byAuthor[1].ID != want[2] {
+		t.Errorf("GetChirpsByAuthor returned %+v, want chirps %s and %s", byAuthor, want[0], want[2])
+	}
+}
+
+func testShadowbannedChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	createChirp(t, s, walt.ID, "visible")
+	hidden := createChirp(t, s, jesse.ID, "hidden")
+	if _, err := s.Moderation().ShadowbanUser(ctx, jesse.ID); err != nil {
+		t.Fatalf("ShadowbanUser error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		viewer uuid.NullUUID
+		want   int
+	}{
+		{"anonymous", uuid.NullUUID{}, 1},
+		{"other user", uuid.NullUUID{UUID: walt.ID, Valid: true}, 1},
+		{"author", uuid.NullUUID{UUID: jesse.ID, Valid: true}, 2},
+	} {
+		chirps, err := s.Chirps().GetChirps(ctx, tt.viewer)
+		if err != nil || len(chirps) != tt.want {
+			t.Errorf("GetChirps as %s = %d chirps, %v, want %d", tt.name, len(chirps), err, tt.want)
+		}
+		byAuthor, err := s.Chirps().GetChirpsByAuthor(ctx, database.GetChirpsByAuthorParams{UserID: jesse.ID, ViewerID: tt.viewer})
+		if err != nil || len(byAuthor) != tt.want-1 {
+			t.Errorf("GetChirpsByAuthor as %s = %d chirps, %v, want %d", tt.name, len(byAuthor), err, tt.want-1)
+		}
+	}
+
+	// A single chirp is still found; handlers decide who may see it.
+	if _, err := s.Chirps().GetChirp(ctx, hidden.ID); err != nil {
+		t.Errorf("GetChirp for shadowbanned author error = %v", err)
+	}
+}
+
+func testHiddenChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	createChirp(t, s, walt.ID, "visible")
+	hidden := createChirp(t, s, jesse.ID, "hidden")
+
+	if err := s.Chirps().HideChirp(ctx, hidden.ID); err != nil {
+		t.Fatalf("HideChirp error = %v", err)
+	}
+	got, err := s.Chirps().GetChirp(ctx, hidden.ID)
+	if err != nil || !got.HiddenAt.Valid {
+		t.Fatalf("GetChirp after HideChirp = %+v, %v, want hidden_at set", got, err)
+	}
+	// Hiding again keeps the original time.
+	if err := s.Chirps().HideChirp(ctx, hidden.ID); err != nil {
+		t.Fatalf("second HideChirp error = %v", err)
+	}
+	if again, _ := s.Chirps().GetChirp(ctx, hidden.ID); !again.HiddenAt.Time.Equal(got.HiddenAt.Time) {
+		t.Errorf("second HideChirp moved hidden_at from %v to %v", got.HiddenAt.Time, again.HiddenAt.Time)
+	}
+
+	if chirps, err := s.Chirps().GetChirps(ctx, uuid.NullUUID{UUID: walt.ID, Valid: true}); err != nil || len(chirps) != 1 {
+		t.Errorf("GetChirps as other user = %d chirps, %v, want 1", len(chirps), err)
+	}
+	if chirps, err := s.Chirps().GetChirps(ctx, uuid.NullUUID{UUID: jesse.ID, Valid: true}); err != nil || len(chirps) != 2 {
+		t.Errorf("GetChirps as author = %d chirps, %v, want 2", len(chirps), err)
+	}
+
+	if err := s.Chirps().UnhideChirp(ctx, hidden.ID); err != nil {
+		t.Fatalf("UnhideChirp error = %v", err)
+	}
+	if chirps, err := s.Chirps().GetChirps(ctx, uuid.NullUUID{}); err != nil || len(chirps) != 2 {
+		t.Errorf("GetChirps after UnhideChirp = %d chirps, %v, want 2", len(chirps), err)
+	}
+}
+
+func testChirpNotFound(t *testing.T, s store.Store) {
+	_, err := s.Chirps().GetChirp(context.Background(), uuid.New())
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetChirp error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testChirpRequiresUser(t *testing.T, s store.Store) {
+	_, err := s.Chirps().CreateChirp(context.Background(), database.CreateChirpParams{
+		UserID: uuid.New(),
+		Body:   "orphan",
+	})
+	if !errors.Is(err, store.ErrMissingReference) {
+		t.Errorf("CreateChirp for missing user error = %v, want %v", err, store.ErrMissingReference)
+	}
+}
+
+func testDeleteChirp(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	chirp := createChirp(t, s, user.ID, "say my name")
+
+	got, err := s.Chirps().GetChirp(ctx, chirp.ID)
+	if err != nil || got.Body != "say my name" {
+		t.Fatalf("GetChirp = %+v, %v", got, err)
+	}
+
+	if err := s.Chirps().DeleteChirp(ctx, chirp.ID); err != nil {
+		t.Fatalf("DeleteChirp error = %v", err)
+	}
+	if _, err := s.Chirps().GetChirp(ctx, chirp.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetChirp after delete error = %v, want %v", err, store.ErrNotFound)
+	}
+	if err := s.Chirps().DeleteChirp(ctx, chirp.ID); err != nil {
+		t.Errorf("DeleteChirp of missing chirp error = %v, want nil", err)
+	}
+}
+
+func testRecentChirpsByAuthor(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+
+	var want []uuid.UUID
+	for _, body := range []string{"one", "two", "three"} {
+		want = append([]uuid.UUID{createChirp(t, s, walt.ID, body).ID}, want...)
+		time.Sleep(time.Millisecond)
+	}
+	createChirp(t, s, jesse.ID, "not walt's")
+	if err := s.Chirps().HideChirp(ctx, want[1]); err != nil {
+		t.Fatalf("HideChirp error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		window int32
+		limit  int32
+		want   []uuid.UUID
+	}{
+		{"newest first, hidden included", 3600, 10, want},
+		{"limited", 3600, 2, want[:2]},
+		{"empty window", 0, 10, nil},
+	}
+	for _, tt := range tests {
+		chirps, err := s.Chirps().GetRecentChirpsByAuthor(ctx, database.GetRecentChirpsByAuthorParams{
+			UserID:        walt.ID,
+			WindowSeconds: tt.window,
+			RowLimit:      tt.limit,
+		})
+		if err != nil {
+			t.Fatalf("GetRecentChirpsByAuthor %s error = %v", tt.name, err)
+		}
+		var got []uuid.UUID
+		for _, c := range chirps {
+			got = append(got, c.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("GetRecentChirpsByAuthor %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testRefreshTokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+
+	token, err := s.RefreshTokens().CreateToken(ctx, database.CreateTokenParams{Token: "abc", UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateToken error = %v", err)
+	}
+	lifetime := token.ExpiresAt.Sub(token.CreatedAt)
+	if lifetime < 59*24*time.Hour || lifetime > 61*24*time.Hour || token.RevokedAt.Valid {
+		t.Errorf("CreateToken returned %+v, want unrevoked token valid for 60 days", token)
+	}
+
+	_, err = s.RefreshTokens().CreateToken(ctx, database.CreateTokenParams{Token: "abc", UserID: user.ID})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Errorf("CreateToken with duplicate token error = %v, want %v", err, store.ErrDuplicate)
+	}
+	_, err = s.RefreshTokens().CreateToken(ctx, database.CreateTokenParams{Token: "def", UserID: uuid.New()})
+	if !errors.Is(err, store.ErrMissingReference) {
+		t.Errorf("CreateToken for missing user error = %v, want %v", err, store.ErrMissingReference)
+	}
+
+	revoked, err := s.RefreshTokens().RevokeToken(ctx, "abc")
+	if err != nil || !revoked.RevokedAt.Valid {
+		t.Errorf("RevokeToken = %+v, %v, want revoked token", revoked, err)
+	}
+	got, err := s.RefreshTokens().GetToken(ctx, "abc")
+	if err != nil || !got.RevokedAt.Valid {
+		t.Errorf("GetToken after revoke = %+v, %v, want revoked token", got, err)
+	}
+
+	if _, err := s.RefreshTokens().RevokeToken(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("RevokeToken of missing token error = %v, want %v", err, store.ErrNotFound)
+	}
+	if _, err := s.RefreshTokens().GetToken(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetToken of missing token error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testDeleteStaleTokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	for _, token := range []string{"keep", "revoke"} {
+		if _, err := s.RefreshTokens().CreateToken(ctx, database.CreateTokenParams{Token: token, UserID: user.ID}); err != nil {
+			t.Fatalf("CreateToken error = %v", err)
+		}
+	}
+	if _, err := s.RefreshTokens().RevokeToken(ctx, "revoke"); err != nil {
+		t.Fatalf("RevokeToken error = %v", err)
+	}
+
+	deleted, err := s.RefreshTokens().DeleteStaleTokens(ctx)
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteStaleTokens = %d, %v, want 1", deleted, err)
+	}
+	if _, err := s.RefreshTokens().GetToken(ctx, "keep"); err != nil {
+		t.Errorf("GetToken of live token error = %v", err)
+	}
+}
+
+func testRevokeUserTokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	for token, user := range map[string]uuid.UUID{"walt1": walt.ID, "walt2": walt.ID, "jesse": jesse.ID} {
+		if _, err := s.RefreshTokens().CreateToken(ctx, database.CreateTokenParams{Token: token, UserID: user}); err != nil {
+			t.Fatalf("CreateToken error = %v", err)
+		}
+	}
+
+	if err := s.RefreshTokens().RevokeUserTokens(ctx, walt.ID); err != nil {
+		t.Fatalf("RevokeUserTokens error = %v", err)
+	}
+	for token, wantRevoked := range map[string]bool{"walt1": true, "walt2": true, "jesse": false} {
+		got, err := s.RefreshTokens().GetToken(ctx, token)
+		if err != nil {
+			t.Fatalf("GetToken(%q) error = %v", token, err)
+		}
+		if got.RevokedAt.Valid != wantRevoked {
+			t.Errorf("GetToken(%q).RevokedAt = %+v, want revoked %t", token, got.RevokedAt, wantRevoked)
+		}
+	}
+}
+
+func testPersonalAccessTokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	other := createUser(t, s, "jesse@example.com")
+	pats := s.PersonalAccessTokens()
+
+	forever, err := pats.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID: user.ID, Name: "ci", TokenHash: "hash1", Scopes: "chirps:read",
+	})
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken error = %v", err)
+	}
+	if forever.ID == uuid.Nil || forever.CreatedAt.IsZero() || forever.ExpiresAt.Valid || forever.LastUsedAt.Valid {
+		t.Errorf("CreatePersonalAccessToken returned %+v, want generated id and no expiry or last use", forever)
+	}
+	expiring, err := pats.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID: user.ID, Name: "bot", TokenHash: "hash2", Scopes: "chirps:read chirps:write",
+		ExpiresInDays: sql.NullInt32{Int32: 30, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken with expiry error = %v", err)
+	}
+	lifetime := expiring.ExpiresAt.Time.Sub(expiring.CreatedAt)
+	if !expiring.ExpiresAt.Valid || lifetime < 29*24*time.Hour || lifetime > 31*24*time.Hour {
+		t.Errorf("CreatePersonalAccessToken returned %+v, want token valid for 30 days", expiring)
+	}
+
+	_, err = pats.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID: other.ID, Name: "dup", TokenHash: "hash1", Scopes: "chirps:read",
+	})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Errorf("CreatePersonalAccessToken with duplicate hash error = %v, want %v", err, store.ErrDuplicate)
+	}
+	_, err = pats.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID: uuid.New(), Name: "ghost", TokenHash: "hash3", Scopes: "chirps:read",
+	})
+	if !errors.Is(err, store.ErrMissingReference) {
+		t.Errorf("CreatePersonalAccessToken for missing user error = %v, want %v", err, store.ErrMissingReference)
+	}
+
+	got, err := pats.GetPersonalAccessTokenByHash(ctx, "hash2")
+	if err != nil || got.ID != expiring.ID || got.Scopes != "chirps:read chirps:write" {
+		t.Errorf("GetPersonalAccessTokenByHash = %+v, %v, want %+v", got, err, expiring)
+	}
+	if _, err := pats.GetPersonalAccessTokenByHash(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetPersonalAccessTokenByHash of missing token error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	if err := pats.TouchPersonalAccessToken(ctx, forever.ID); err != nil {
+		t.Fatalf("TouchPersonalAccessToken error = %v", err)
+	}
+	touched, err := pats.GetPersonalAccessTokenByHash(ctx, "hash1")
+	if err != nil || !touched.LastUsedAt.Valid {
+		t.Errorf("GetPersonalAccessTokenByHash after touch = %+v, %v, want last use recorded", touched, err)
+	}
+
+	list, err := pats.ListPersonalAccessTokens(ctx, user.ID)
+	if err != nil || len(list) != 2 {
+		t.Errorf("ListPersonalAccessTokens = %+v, %v, want 2 tokens", list, err)
+	}
+	if list, err := pats.ListPersonalAccessTokens(ctx, other.ID); err != nil || len(list) != 0 {
+		t.Errorf("ListPersonalAccessTokens for user without tokens = %+v, %v, want none", list, err)
+	}
+
+	err = pats.DeletePersonalAccessToken(ctx, database.DeletePersonalAccessTokenParams{ID: forever.ID, UserID: other.ID})
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeletePersonalAccessToken by another user error = %v, want %v", err, store.ErrNotFound)
+	}
+	if err := pats.DeletePersonalAccessToken(ctx, database.DeletePersonalAccessTokenParams{ID: forever.ID, UserID: user.ID}); err != nil {
+		t.Fatalf("DeletePersonalAccessToken error = %v", err)
+	}
+	if _, err := pats.GetPersonalAccessTokenByHash(ctx, "hash1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetPersonalAccessTokenByHash after delete error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func createOAuthClient(t *testing.T, s store.Store, userID uuid.UUID) database.OAuthClient {
+	t.Helper()
+	client, err := s.OAuth().CreateOAuthClient(context.Background(), database.CreateOAuthClientParams{
+		UserID:       userID,
+		Name:         "Partner App",
+		SecretHash:   sql.NullString{String: "secret-hash", Valid: true},
+		RedirectUris: "https://partner.example/callback",
+	})
+	if err != nil {
+		t.Fatalf("CreateOAuthClient error = %v", err)
+	}
+	return client
+}
+
+func testOAuthClients(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	other := createUser(t, s, "jesse@example.com")
+
+	client := createOAuthClient(t, s, user.ID)
+	if client.ID == uuid.Nil || client.CreatedAt.IsZero() || client.SecretHash.String != "secret-hash" {
+		t.Errorf("CreateOAuthClient returned %+v, want generated id and the secret hash", client)
+	}
+	public, err := s.OAuth().CreateOAuthClient(ctx, database.CreateOAuthClientParams{
+		UserID: user.ID, Name: "Mobile", RedirectUris: "chirpy-mobile://callback",
+	})
+	if err != nil || public.SecretHash.Valid {
+		t.Errorf("CreateOAuthClient for public client = %+v, %v, want no secret", public, err)
+	}
+	_, err = s.OAuth().CreateOAuthClient(ctx, database.CreateOAuthClientParams{
+		UserID: uuid.New(), Name: "Ghost", RedirectUris: "https://ghost.example/cb",
+	})
+	if !errors.Is(err, store.ErrMissingReference) {
+		t.Errorf("CreateOAuthClient for missing user error = %v, want %v", err, store.ErrMissingReference)
+	}
+
+	got, err := s.OAuth().GetOAuthClient(ctx, client.ID)
+	if err != nil || got != client {
+		t.Errorf("GetOAuthClient = %+v, %v, want %+v", got, err, client)
+	}
+	if _, err := s.OAuth().GetOAuthClient(ctx, uuid.New()); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetOAuthClient of missing client error = %v, want %v", err, store.ErrNotFound)
+	}
+	if list, err := s.OAuth().ListOAuthClients(ctx, user.ID); err != nil || len(list) != 2 {
+		t.Errorf("ListOAuthClients = %+v, %v, want 2 clients", list, err)
+	}
+
+	_, err = s.RefreshTokens().CreateToken(ctx, database.CreateTokenParams{
+		Token:    "client-token",
+		UserID:   other.ID,
+		ClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+		Scopes:   sql.NullString{String: "chirps:read", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateToken for client error = %v", err)
+	}
+	token, err := s.RefreshTokens().GetToken(ctx, "client-token")
+	if err != nil || token.ClientID.UUID != client.ID || token.Scopes.String != "chirps:read" {
+		t.Errorf("GetToken = %+v, %v, want token bound to client with its scopes", token, err)
+	}
+	_, err = s.RefreshTokens().CreateToken(ctx, database.CreateTokenParams{
+		Token: "orphan", UserID: other.ID, ClientID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	})
+	if !errors.Is(err, store.ErrMissingReference) {
+		t.Errorf("CreateToken for missing client error = %v, want %v", err, store.ErrMissingReference)
+	}
+
+	err = s.OAuth().DeleteOAuthClient(ctx, database.DeleteOAuthClientParams{ID: client.ID, UserID: other.ID})
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteOAuthClient by another user error = %v, want %v", err, store.ErrNotFound)
+	}
+	if err := s.OAuth().DeleteOAuthClient(ctx, database.DeleteOAuthClientParams{ID: client.ID, UserID: user.ID}); err != nil {
+		t.Fatalf("DeleteOAuthClient error = %v", err)
+	}
+	if _, err := s.OAuth().GetOAuthClient(ctx, client.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetOAuthClient after delete error = %v, want %v", err, store.ErrNotFound)
+	}
+	if _, err := s.RefreshTokens().GetToken(ctx, "client-token"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetToken after deleting its client error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testOAuthAuthorizationCodes(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	client := createOAuthClient(t, s, user.ID)
+
+	arg := database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      "code-hash",
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectUri:   "https://partner.example/callback",
+		Scopes:        "chirps:read",
+		CodeChallenge: "challenge",
+	}
+	code, err := s.OAuth().CreateOAuthAuthorizationCode(ctx, arg)
+	if err != nil {
+		t.Fatalf("CreateOAuthAuthorizationCode error = %v", err)
+	}
+	lifetime := code.ExpiresAt.Sub(code.CreatedAt)
+	if lifetime < 9*time.Minute || lifetime > 11*time.Minute {
+		t.Errorf("CreateOAuthAuthorizationCode returned %+v, want code valid for 10 minutes", code)
+	}
+	if _, err := s.OAuth().CreateOAuthAuthorizationCode(ctx, arg); !errors.Is(err, store.ErrDuplicate) {
+		t.Errorf("CreateOAuthAuthorizationCode with duplicate hash error = %v, want %v", err, store.ErrDuplicate)
+	}
+
+	if deleted, err := s.OAuth().DeleteExpiredOAuthAuthorizationCodes(ctx); err != nil || deleted != 0 {
+		t.Errorf("DeleteExpiredOAuthAuthorizationCodes = %d, %v, want 0", deleted, err)
+	}
+
+	got, err := s.OAuth().ConsumeOAuthAuthorizationCode(ctx, "code-hash")
+	if err != nil || got.UserID != user.ID || got.CodeChallenge != "challenge" || got.RedirectUri != arg.RedirectUri {
+		t.Errorf("ConsumeOAuthAuthorizationCode = %+v, %v, want %+v", got, err, code)
+	}
+	if _, err := s.OAuth().ConsumeOAuthAuthorizationCode(ctx, "code-hash"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("second ConsumeOAuthAuthorizationCode error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func createReport(t *testing.T, s store.Store, chirp database.Chirp, reporterID uuid.UUID) (database.Report, database.ReportCase) {
+	t.Helper()
+	ctx := context.Background()
+	chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	c, err := s.Reports().OpenReportCase(ctx, database.OpenReportCaseParams{ChirpID: chirpID, AuthorID: chirp.UserID})
+	if err != nil {
+		t.Fatalf("finding a case for chirp %s: %v", chirp.ID, err)
+	}
+	report, err := s.Reports().CreateReport(ctx, database.CreateReportParams{
+		CaseID:     c.ID,
+		ChirpID:    chirpID,
+		ReporterID: reporterID,
+		Reason:     "spam",
+	})
+	if err != nil {
+		t.Fatalf("CreateReport error = %v", err)
+	}
+	c, err = s.Reports().IncrementReportCount(ctx, c.ID)
+	if err != nil {
+		t.Fatalf("IncrementReportCount error = %v", err)
+	}
+	// Keep creation times distinct at SQLite's millisecond precision.
+	time.Sleep(time.Millisecond)
+	return report, c
+}
+
+func testReports(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	hank := createUser(t, s, "hank@example.com")
+	busy := createChirp(t, s, walt.ID, "blue sky")
+	quiet := createChirp(t, s, walt.ID, "tread lightly")
+	reports := s.Reports()
+
+	first, c := createReport(t, s, busy, jesse.ID)
+	_, c2 := createReport(t, s, busy, hank.ID)
+	if c2.ID != c.ID || c2.ReportCount != 2 {
+		t.Fatalf("second report on chirp went to case %s with count %d, want %s with 2", c2.ID, c2.ReportCount, c.ID)
+	}
+	_, quietCase := createReport(t, s, quiet, jesse.ID)
+
+	_, err := reports.CreateReport(ctx, database.CreateReportParams{
+		CaseID:     c.ID,
+		ChirpID:    uuid.NullUUID{UUID: busy.ID, Valid: true},
+		ReporterID: jesse.ID,
+		Reason:     "hate",
+	})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Errorf("second report by the same user error = %v, want %v", err, store.ErrDuplicate)
+	}
+	_, err = reports.CreateReportCase(ctx, database.CreateReportCaseParams{ChirpID: uuid.NullUUID{UUID: busy.ID, Valid: true}, AuthorID: walt.ID})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Errorf("second open case for a chirp error = %v, want %v", err, store.ErrDuplicate)
+	}
+	_, err = reports.CreateReport(ctx, database.CreateReportParams{CaseID: quietCase.ID, ReporterID: hank.ID, Reason: "boring"})
+	if err == nil {
+		t.Errorf("CreateReport with unknown reason succeeded")
+	}
+
+	open, err := reports.ListOpenReportCases(ctx, 10)
+	if err != nil || len(open) != 2 || open[0].ID != c.ID || open[1].ID != quietCase.ID {
+		t.Fatalf("ListOpenReportCases = %+v, %v, want the busier case first", open, err)
+	}
+	if limited, err := reports.ListOpenReportCases(ctx, 1); err != nil || len(limited) != 1 {
+		t.Errorf("ListOpenReportCases(1) = %d cases, %v", len(limited), err)
+	}
+	if caseReports, err := reports.ListCaseReports(ctx, c.ID); err != nil || len(caseReports) != 2 || caseReports[0].ID != first.ID {
+		t.Errorf("ListCaseReports = %+v, %v, want 2 oldest first", caseReports, err)
+	}
+	if mine, err := reports.ListReporterReports(ctx, jesse.ID); err != nil || len(mine) != 2 || mine[1].ID != first.ID {
+		t.Errorf("ListReporterReports = %+v, %v, want 2 newest first", mine, err)
+	}
+
+	resolved, err := reports.ResolveReportCase(ctx, database.ResolveReportCaseParams{
+		Resolution:     sql.NullString{String: "dismiss", Valid: true},
+		ResolutionNote: "satire",
+		ModeratorID:    uuid.NullUUID{UUID: hank.ID, Valid: true},
+		ID:             c.ID,
+	})
+	if err != nil || !resolved.ResolvedAt.Valid || resolved.Resolution.String != "dismiss" || resolved.ResolvedBy.UUID != hank.ID {
+		t.Fatalf("ResolveReportCase = %+v, %v", resolved, err)
+	}
+	_, err = reports.ResolveReportCase(ctx, database.ResolveReportCaseParams{
+		Resolution:  sql.NullString{String: "hide_chirp", Valid: true},
+		ModeratorID: uuid.NullUUID{UUID: hank.ID, Valid: true},
+		ID:          c.ID,
+	})
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("resolving a resolved case error = %v, want %v", err, store.ErrNotFound)
+	}
+	notified, err := reports.ResolveReports(ctx, database.ResolveReportsParams{
+		Resolution: sql.NullString{String: "dismiss", Valid: true},
+		CaseID:     c.ID,
+	})
+	if err != nil || len(notified) != 2 || notified[0].Resolution.String != "dismiss" || !notified[0].ResolvedAt.Valid {
+		t.Errorf("ResolveReports = %+v, %v, want both reports resolved", notified, err)
+	}
+
+	if done, err := reports.ListResolvedReportCases(ctx, 10); err != nil || len(done) != 1 || done[0].ID != c.ID {
+		t.Errorf("ListResolvedReportCases = %+v, %v", done, err)
+	}
+	// A resolved case doesn't stop a new one opening for the chirp.
+	flagged, err := reports.CreateReportCase(ctx, database.CreateReportCaseParams{
+		ChirpID:    uuid.NullUUID{UUID: busy.ID, Valid: true},
+		AuthorID:   walt.ID,
+		FlagReason: "words: kerfuffle",
+		HidChirp:   true,
+	})
+	if err != nil {
+		t.Fatalf("CreateReportCase after resolving error = %v", err)
+	}
+	if got, err := reports.GetReportCase(ctx, flagged.ID); err != nil || got.FlagReason != "words: kerfuffle" || got.ReportCount != 0 || !got.HidChirp {
+		t.Errorf("GetReportCase(flagged) = %+v, %v, want the flag reason, the hide and no reports", got, err)
+	}
+	// Reports on a flagged chirp join the case the flag opened.
+	reopened, err := reports.OpenReportCase(ctx, database.OpenReportCaseParams{ChirpID: uuid.NullUUID{UUID: busy.ID, Valid: true}, AuthorID: walt.ID})
+	if err != nil || reopened.ID != flagged.ID || reopened.FlagReason != "words: kerfuffle" {
+		t.Errorf("OpenReportCase on a flagged chirp = %+v, %v, want case %s", reopened, err, flagged.ID)
+	}
+	// Reporters whose earlier report was resolved can report the chirp again.
+	if _, err := reports.CreateReport(ctx, database.CreateReportParams{
+		CaseID:     flagged.ID,
+		ChirpID:    uuid.NullUUID{UUID: busy.ID, Valid: true},
+		ReporterID: jesse.ID,
+		Reason:     "spam",
+	}); err != nil {
+		t.Errorf("reporting again after the first case was resolved error = %v", err)
+	}
+
+	if c.HidChirp {
+		t.Errorf("case %s hid its chirp before being marked", c.ID)
+	}
+	if marked, err := reports.MarkReportCaseHidChirp(ctx, quietCase.ID); err != nil || !marked.HidChirp {
+		t.Errorf("MarkReportCaseHidChirp = %+v, %v", marked, err)
+	}
+	if _, err := reports.MarkReportCaseHidChirp(ctx, uuid.New()); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("MarkReportCaseHidChirp on a missing case error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testReportCaseClaims(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	hank := createUser(t, s, "hank@example.com")
+	gomez := createUser(t, s, "gomez@example.com")
+	_, c := createReport(t, s, createChirp(t, s, walt.ID, "blue sky"), hank.ID)
+	reports := s.Reports()
+	asHank := uuid.NullUUID{UUID: hank.ID, Valid: true}
+	asGomez := uuid.NullUUID{UUID: gomez.ID, Valid: true}
+
+	claimed, err := reports.ClaimReportCase(ctx, database.ClaimReportCaseParams{ModeratorID: asHank, ID: c.ID})
+	if err != nil || claimed.ClaimedBy != asHank || !claimed.ClaimedAt.Valid {
+		t.Fatalf("ClaimReportCase = %+v, %v", claimed, err)
+	}
+	if _, err := reports.ClaimReportCase(ctx, database.ClaimReportCaseParams{ModeratorID: asHank, ID: c.ID}); err != nil {
+		t.Errorf("reclaiming own case error = %v", err)
+	}
+	if _, err := reports.ClaimReportCase(ctx, database.ClaimReportCaseParams{ModeratorID: asGomez, ID: c.ID}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("claiming another moderator's case error = %v, want %v", err, store.ErrNotFound)
+	}
+	if _, err := reports.ReleaseReportCase(ctx, database.ReleaseReportCaseParams{ID: c.ID, ModeratorID: asGomez}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("releasing another moderator's case error = %v, want %v", err, store.ErrNotFound)
+	}
+	_, err = reports.ResolveReportCase(ctx, database.ResolveReportCaseParams{
+		Resolution:  sql.NullString{String: "dismiss", Valid: true},
+		ModeratorID: asGomez,
+		ID:          c.ID,
+	})
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("resolving another moderator's case error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	released, err := reports.ReleaseReportCase(ctx, database.ReleaseReportCaseParams{ID: c.ID, ModeratorID: asHank})
+	if err != nil || released.ClaimedBy.Valid || released.ClaimedAt.Valid {
+		t.Fatalf("ReleaseReportCase = %+v, %v", released, err)
+	}
+	if _, err := reports.ClaimReportCase(ctx, database.ClaimReportCaseParams{ModeratorID: asGomez, ID: c.ID}); err != nil {
+		t.Errorf("claiming a released case error = %v", err)
+	}
+	if _, err := reports.ClaimReportCase(ctx, database.ClaimReportCaseParams{ModeratorID: asGomez, ID: uuid.New()}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("claiming a missing case error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testReportsOutliveChirp(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	chirp := createChirp(t, s, walt.ID, "blue sky")
+	report, c := createReport(t, s, chirp, jesse.ID)
+
+	if err := s.Chirps().DeleteChirp(ctx, chirp.ID); err != nil {
+		t.Fatalf("DeleteChirp error = %v", err)
+	}
+	got, err := s.Reports().GetReportCase(ctx, c.ID)
+	if err != nil || got.ChirpID.Valid {
+		t.Errorf("GetReportCase after deleting the chirp = %+v, %v, want chirp_id cleared", got, err)
+	}
+	mine, err := s.Reports().ListReporterReports(ctx, jesse.ID)
+	if err != nil || len(mine) != 1 || mine[0].ID != report.ID || mine[0].ChirpID.Valid {
+		t.Errorf("ListReporterReports after deleting the chirp = %+v, %v, want the report with chirp_id cleared", mine, err)
+	}
+}
+
+func testNotifications(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	notifications := s.Notifications()
+
+	var created []database.Notification
+	for range 3 {
+		n, err := notifications.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID:  jesse.ID,
+			Kind:    "report.resolved",
+			Payload: []byte(`{"resolution":"dismiss"}`),
+		})
+		if err != nil {
+			t.Fatalf("CreateNotification error = %v", err)
+		}
+		created = append(created, n)
+		time.Sleep(time.Millisecond)
+	}
+
+	got, err := notifications.ListNotifications(ctx, database.ListNotificationsParams{UserID: jesse.ID, RowLimit: 2})
+	if err != nil {
+		t.Fatalf("ListNotifications error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != created[2].ID || got[1].ID != created[1].ID {
+		t.Fatalf("ListNotifications = %+v, want the two newest, newest first", got)
+	}
+	if got[0].ReadAt.Valid || string(got[0].Payload) != `{"resolution":"dismiss"}` {
+		t.Errorf("ListNotifications[0] = %+v, want unread with the payload kept", got[0])
+	}
+	if others, err := notifications.ListNotifications(ctx, database.ListNotificationsParams{UserID: walt.ID, RowLimit: 10}); err != nil || len(others) != 0 {
+		t.Errorf("ListNotifications for another user = %+v, %v, want none", others, err)
+	}
+
+	read, err := notifications.MarkNotificationRead(ctx, database.MarkNotificationReadParams{ID: created[0].ID, UserID: jesse.ID})
+	if err != nil || !read.ReadAt.Valid {
+		t.Fatalf("MarkNotificationRead = %+v, %v, want it read", read, err)
+	}
+	time.Sleep(time.Millisecond)
+	again, err := notifications.MarkNotificationRead(ctx, database.MarkNotificationReadParams{ID: created[0].ID, UserID: jesse.ID})
+	if err != nil || !again.ReadAt.Time.Equal(read.ReadAt.Time) {
+		t.Errorf("MarkNotificationRead again = %+v, %v, want read at %v", again, err, read.ReadAt.Time)
+	}
+	if _, err := notifications.MarkNotificationRead(ctx, database.MarkNotificationReadParams{ID: created[1].ID, UserID: walt.ID}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("MarkNotificationRead by another user error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	_, err = notifications.CreateNotification(ctx, database.CreateNotificationParams{UserID: uuid.New(), Kind: "report.resolved", Payload: []byte(`{}`)})
+	if !errors.Is(err, store.ErrMissingReference) {
+		t.Errorf("CreateNotification for a missing user error = %v, want %v", err, store.ErrMissingReference)
+	}
+}
+
+func testCascadingDelete(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "walt@example.com")
+	chirp := createChirp(t, s, user.ID, "cascade")
+	if _, err := s.RefreshTokens().CreateToken(ctx, database.CreateTokenParams{Token: "abc", UserID: user.ID}); err != nil {
+		t.Fatalf("CreateToken error = %v", err)
+	}
+	pat, err := s.PersonalAccessTokens().CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID: user.ID, Name: "ci", TokenHash: "hash", Scopes: "chirps:read",
+	})
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken error = %v", err)
+	}
+	if _, err := s.Notifications().CreateNotification(ctx, database.CreateNotificationParams{
+		UserID: user.ID, Kind: "report.resolved", Payload: []byte(`{}`),
+	}); err != nil {
+		t.Fatalf("CreateNotification error = %v", err)
+	}
+
+	if err := s.Users().DeleteUsers(ctx); err != nil {
+		t.Fatalf("DeleteUsers error = %v", err)
+	}
+
+	if _, err := s.Chirps().GetChirp(ctx, chirp.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetChirp after DeleteUsers error = %v, want %v", err, store.ErrNotFound)
+	}
+	if _, err := s.RefreshTokens().GetToken(ctx, "abc"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetToken after DeleteUsers error = %v, want %v", err, store.ErrNotFound)
+	}
+	if _, err := s.PersonalAccessTokens().GetPersonalAccessTokenByHash(ctx, pat.TokenHash); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetPersonalAccessTokenByHash after DeleteUsers error = %v, want %v", err, store.ErrNotFound)
+	}
+	if got, err := s.Notifications().ListNotifications(ctx, database.ListNotificationsParams{UserID: user.ID, RowLimit: 10}); err != nil || len(got) != 0 {
+		t.Errorf("ListNotifications after DeleteUsers = %+v, %v, want none", got, err)
+	}
+}
+
+func testTxCommit(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	err := s.WithTx(ctx, func(tx store.Store) error {
+		user := createUser(t, tx, "walt@example.com")
+		createChirp(t, tx, user.ID, "committed")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx error = %v", err)
+	}
+
+	chirps, err := s.Chirps().GetChirps(ctx, uuid.NullUUID{})
+	if err != nil || len(chirps) != 1 {
+		t.Errorf("GetChirps after commit = %+v, %v, want 1 chirp", chirps, err)
+	}
+}
+
+func testTxRollback(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+	err := s.WithTx(ctx, func(tx store.Store) error {
+		createUser(t, tx, "walt@example.com")
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTx error = %v, want %v", err, rollback)
+	}
+
+	if _, err := s.Users().GetUser(ctx, "walt@example.com"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetUser after rollback error = %v, want %v", err, store.ErrNotFound)
+	}
+}
@@ -0,0 +1,146 @@
+// Package tracing configures OpenTelemetry tracing for Chirpy and provides
+// the HTTP and database instrumentation the rest of the server uses.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+const serviceName = "chirpy"
+
+// Tracer returns the tracer Chirpy's own spans are created with.
+func Tracer() trace.Tracer {
+	return otel.Tracer("github.com/d-shames3/chirpy")
+}
+
+// CheckExporter reports whether exporter names a supported exporter and has
+// the settings it needs.
+func CheckExporter(exporter, file string) error {
+	switch exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLP:
+		return nil
+	case ExporterFile:
+		if file == "" {
+			return errors.New("trace_file is required when trace_exporter is \"file\"")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown trace exporter %q (want none, stdout, file or otlp)", exporter)
+	}
+}
+
+// Setup installs the global tracer provider and W3C trace context
+// propagator. The OTLP exporter takes its endpoint and headers from the
+// standard OTEL_EXPORTER_OTLP_* environment variables. The returned function
+// flushes buffered spans and must be called on shutdown.
+func Setup(ctx context.Context, exporter, file string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if err := CheckExporter(exporter, file); err != nil {
+		return nil, err
+	}
+	if exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var (
+		spanExporter sdktrace.SpanExporter
+		closer       io.Closer
+		err          error
+	)
+	switch exporter {
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		var f *os.File
+		f, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening trace file: %w", err)
+		}
+		closer = f
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// Handler starts a server span for every request, continuing any trace
+// named in an incoming traceparent header. It should be the outermost
+// middleware so everything else runs inside the span; pair it with Route
+// around the ServeMux to name spans after the matched pattern.
+func Handler(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.server",
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			if r.Pattern != "" {
+				return r.Pattern
+			}
+			return r.Method
+		}),
+	)
+}
+
+// Route renames the current server span after the route pattern the
+// ServeMux matched. Unlike Handler it passes r through unchanged, so
+// middleware outside it still sees r.Pattern too.
+func Route(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+		if r.Pattern == "" {
+			return
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Pattern)
+		span.SetAttributes(attribute.String("http.route", r.Pattern))
+	})
+}
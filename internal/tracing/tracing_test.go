@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "sqlcComment",
+			query: "-- name: CreateUser :one\nINSERT INTO users (id) VALUES ($1)",
+			want:  "CreateUser",
+		},
+		{
+			name:  "leadingWhitespace",
+			query: "\n  -- name: GetChirps :many\nSELECT * FROM chirps",
+			want:  "GetChirps",
+		},
+		{
+			name:  "plainSQL",
+			query: "select pg_try_advisory_xact_lock($1)",
+			want:  "SELECT",
+		},
+		{
+			name:  "empty",
+			query: "",
+			want:  "query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QueryName(tt.query); got != tt.want {
+				t.Errorf("QueryName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteSpanName(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpId}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := Handler(Route(mux))
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "matched",
+			path: "/api/chirps/123",
+			want: "GET /api/chirps/{chirpId}",
+		},
+		{
+			name: "unmatched",
+			path: "/nope",
+			want: "GET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.Reset()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			if got := spans[0].Name(); got != tt.want {
+				t.Errorf("span name = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
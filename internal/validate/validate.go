@@ -0,0 +1,164 @@
+// Package validate checks request structs against rules declared in
+// `validate` struct tags, e.g.
+//
+//	type userParams struct {
+//		Email    string `json:"email" validate:"required,email"`
+//		Password string `json:"password" validate:"required,min=8"`
+//	}
+//
+// Supported rules are required, email, uuid, min=N, max=N and oneof=a b c.
+// min and max compare the length of strings (in characters) and slices, and
+// the value of numbers. Fields are reported by their JSON names; nested
+// structs are walked and reported as parent.child.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/google/uuid"
+)
+
+// Struct validates v, which must be a struct or pointer to one, and returns
+// every failing rule. It panics on a malformed tag, since that is a
+// programming error rather than bad input.
+func Struct(v any) []apierror.FieldError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: Struct called with %T", v))
+	}
+	var errs []apierror.FieldError
+	walk(rv, "", &errs)
+	return errs
+}
+
+func walk(rv reflect.Value, prefix string, errs *[]apierror.FieldError) {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := fieldName(sf)
+		if name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fv := rv.Field(i)
+		if tag, ok := sf.Tag.Lookup("validate"); ok {
+			for _, rule := range strings.Split(tag, ",") {
+				if fe, failed := check(fv, name, rule); failed {
+					*errs = append(*errs, fe)
+					// Later rules on the same field are usually noise once
+					// one has failed, e.g. "email" after "required".
+					break
+				}
+			}
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(uuid.UUID{}) {
+			walk(fv, name, errs)
+		}
+	}
+}
+
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+func check(fv reflect.Value, field, rule string) (apierror.FieldError, bool) {
+	name, arg, _ := strings.Cut(rule, "=")
+	fail := func(code, format string, args ...any) (apierror.FieldError, bool) {
+		return apierror.FieldError{Field: field, Code: code, Message: field + " " + fmt.Sprintf(format, args...)}, true
+	}
+
+	switch name {
+	case "required":
+		if fv.IsZero() || (fv.Kind() == reflect.String && strings.TrimSpace(fv.String()) == "") {
+			return fail("required", "is required")
+		}
+	case "email":
+		s := mustString(fv, rule)
+		if s == "" {
+			break
+		}
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return fail("email", "must be a valid email address")
+		}
+	case "uuid":
+		s := mustString(fv, rule)
+		if s == "" {
+			break
+		}
+		if _, err := uuid.Parse(s); err != nil {
+			return fail("uuid", "must be a UUID")
+		}
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: bad %s rule %q on %s", name, rule, field))
+		}
+		size, isLength := measure(fv, rule)
+		if name == "min" && size < limit {
+			if isLength {
+				return fail("min_length", "must be at least %s characters long", arg)
+			}
+			return fail("min", "must be at least %s", arg)
+		}
+		if name == "max" && size > limit {
+			if isLength {
+				return fail("max_length", "must be at most %s characters long", arg)
+			}
+			return fail("max", "must be at most %s", arg)
+		}
+	case "oneof":
+		s := mustString(fv, rule)
+		options := strings.Fields(arg)
+		for _, o := range options {
+			if s == o {
+				return apierror.FieldError{}, false
+			}
+		}
+		return fail("one_of", "must be one of %s", strings.Join(options, ", "))
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q on %s", rule, field))
+	}
+	return apierror.FieldError{}, false
+}
+
+// measure returns the value min and max compare against, and whether it is
+// a length.
+func measure(fv reflect.Value, rule string) (float64, bool) {
+	switch fv.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(fv.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(fv.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), false
+	}
+	panic(fmt.Sprintf("validate: rule %q does not apply to %s", rule, fv.Type()))
+}
+
+func mustString(fv reflect.Value, rule string) string {
+	if fv.Kind() != reflect.String {
+		panic(fmt.Sprintf("validate: rule %q needs a string, got %s", rule, fv.Type()))
+	}
+	return fv.String()
+}
@@ -0,0 +1,129 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type payload struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+}
+
+type request struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Password string   `json:"password" validate:"required,min=8"`
+	Body     string   `json:"body" validate:"max=5"`
+	Sort     string   `json:"sort" validate:"oneof=asc desc"`
+	Limit    int      `json:"limit" validate:"min=1,max=100"`
+	AuthorID string   `json:"author_id" validate:"uuid"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Data     payload  `json:"data"`
+	internal string
+}
+
+func valid() request {
+	return request{
+		Email:    "walt@breakingbad.com",
+		Password: "04234abcd",
+		Body:     "héllo",
+		Sort:     "asc",
+		Limit:    10,
+		Data:     payload{UserID: uuid.New()},
+	}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(r *request)
+		wantField map[string]string
+	}{
+		{
+			name:      "valid",
+			modify:    func(r *request) {},
+			wantField: map[string]string{},
+		},
+		{
+			name:      "missingEmailStopsAtRequired",
+			modify:    func(r *request) { r.Email = "  " },
+			wantField: map[string]string{"email": "required"},
+		},
+		{
+			name:      "badEmail",
+			modify:    func(r *request) { r.Email = "Walt <walt@breakingbad.com>" },
+			wantField: map[string]string{"email": "email"},
+		},
+		{
+			name:      "shortPassword",
+			modify:    func(r *request) { r.Password = "short" },
+			wantField: map[string]string{"password": "min_length"},
+		},
+		{
+			name:      "bodyCountsCharactersNotBytes",
+			modify:    func(r *request) { r.Body = "héllo!" },
+			wantField: map[string]string{"body": "max_length"},
+		},
+		{
+			name:      "oneOf",
+			modify:    func(r *request) { r.Sort = "sideways" },
+			wantField: map[string]string{"sort": "one_of"},
+		},
+		{
+			name:      "numberRange",
+			modify:    func(r *request) { r.Limit = 0 },
+			wantField: map[string]string{"limit": "min"},
+		},
+		{
+			name:      "uuidString",
+			modify:    func(r *request) { r.AuthorID = "nope" },
+			wantField: map[string]string{"author_id": "uuid"},
+		},
+		{
+			name:      "sliceLength",
+			modify:    func(r *request) { r.Tags = []string{"a", "b", "c"} },
+			wantField: map[string]string{"tags": "max_length"},
+		},
+		{
+			name:      "nestedField",
+			modify:    func(r *request) { r.Data.UserID = uuid.Nil },
+			wantField: map[string]string{"data.user_id": "required"},
+		},
+		{
+			name: "aggregatesErrors",
+			modify: func(r *request) {
+				r.Email = ""
+				r.Password = ""
+				r.Limit = 500
+			},
+			wantField: map[string]string{"email": "required", "password": "required", "limit": "max"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+
+			got := map[string]string{}
+			for _, fe := range Struct(&r) {
+				got[fe.Field] = fe.Code
+			}
+			if !reflect.DeepEqual(got, tt.wantField) {
+				t.Errorf("Struct() = %v, want %v", got, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestStructPanicsOnBadTag(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an unknown rule")
+		}
+	}()
+	Struct(struct {
+		Name string `validate:"shiny"`
+	}{})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/jobs"
+	"github.com/google/uuid"
+)
+
+type jobResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Kind        string     `json:"kind"`
+	Status      string     `json:"status"`
+	Attempts    int32      `json:"attempts"`
+	MaxAttempts int32      `json:"max_attempts"`
+	RunAt       time.Time  `json:"run_at"`
+	LockedAt    *time.Time `json:"locked_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+const defaultJobsLimit = 100
+
+var errJobsUnavailable = apierror.NotImplemented("background jobs require Postgres")
+
+func newJobResponse(job database.Job) jobResponse {
+	response := jobResponse{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		LastError:   job.LastError.String,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+	}
+	if job.LockedAt.Valid {
+		response.LockedAt = &job.LockedAt.Time
+	}
+	return response
+}
+
+func (cfg *apiConfig) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.jobs == nil {
+		respondWithError(w, r, errJobsUnavailable)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", jobs.StatusPending, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusDead:
+	default:
+		respondWithError(w, r, apierror.Validation(apierror.FieldError{
+			Field:   "status",
+			Code:    "one_of",
+			Message: "status must be one of pending, running, succeeded or dead",
+		}))
+		return
+	}
+
+	limit := defaultJobsLimit
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			respondWithError(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "limit",
+				Code:    "positive_integer",
+				Message: "limit must be a positive integer",
+			}))
+			return
+		}
+	}
+
+	rows, err := cfg.jobs.List(r.Context(), status, int32(limit))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	jobResponses := []jobResponse{}
+	for _, job := range rows {
+		jobResponses = append(jobResponses, newJobResponse(job))
+	}
+
+	respondWithJSON(w, http.StatusOK, jobResponses)
+}
+
+func (cfg *apiConfig) retryJobHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.jobs == nil {
+		respondWithError(w, r, errJobsUnavailable)
+		return
+	}
+
+	jobId, err := uuid.Parse(r.PathValue("jobId"))
+	if err != nil {
+		respondWithError(w, r, apierror.BadRequest("malformed job id"))
+		return
+	}
+
+	job, err := cfg.jobs.Retry(r.Context(), jobId)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, apierror.NotFound("job not found or currently running").Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newJobResponse(job))
+}
@@ -1,10 +1,45 @@
 package main
 
 import (
+	"database/sql"
+	"encoding"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/d-shames3/chirpy/internal/validate"
 )
 
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+const problemTypePrefix = "urn:chirpy:problem:"
+
+// maxBodyBytes caps JSON request bodies. The largest legitimate body, a
+// chirp, is well under a kilobyte.
+const maxBodyBytes = 64 << 10
+
+// problem is an RFC 7807 problem details body.
+type problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      apierror.Code         `json:"code"`
+	Errors    []apierror.FieldError `json:"errors,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+	// Error repeats Detail for clients written against the original
+	// {"error": "..."} body.
+	Error string `json:"error"`
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) error {
 	response, err := json.Marshal(payload)
 	if err != nil {
@@ -16,6 +51,159 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) error
 	return nil
 }
 
-func respondWithError(w http.ResponseWriter, code int, message string) error {
-	return respondWithJSON(w, code, map[string]string{"error": message})
+// respondWithError writes err as application/problem+json. Errors that are
+// not already an *apierror.Error are mapped by storeError and apierror.From,
+// so raw database and driver messages never reach the client; the full error,
+// cause included, goes to the request log instead.
+func respondWithError(w http.ResponseWriter, r *http.Request, err error) error {
+	apiErr := apierror.From(storeError(err))
+	recordError(w, apiErr.Error())
+
+	body, err := json.Marshal(problem{
+		Type:      problemTypePrefix + string(apiErr.Code),
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Message,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		Errors:    apiErr.Fields,
+		RequestID: w.Header().Get(requestIDHeader),
+		Error:     apiErr.Message,
+	})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(apiErr.Status)
+	w.Write(body)
+	return nil
+}
+
+// storeError maps missing rows and constraint violations reported by the
+// store to 404 and 409. Errors that already are an *apierror.Error pass
+// through unchanged, since handlers use them to say which resource was
+// missing.
+func storeError(err error) error {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, sql.ErrNoRows):
+		return apierror.NotFound("resource not found").Wrap(err)
+	case errors.Is(err, store.ErrDuplicate):
+		return apierror.Conflict("resource already exists").Wrap(err)
+	case errors.Is(err, store.ErrMissingReference):
+		return apierror.NotFound("referenced resource not found").Wrap(err)
+	default:
+		return err
+	}
+}
+
+// decodeJSON reads a single JSON object from the request body into dst and
+// checks it against dst's validate tags. Bodies must be application/json (or
+// have no Content-Type), at most maxBodyBytes, contain exactly one value and
+// no fields dst doesn't declare. Malformed bodies are 400s and rule
+// violations are 422s listing every failing field.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	return decodeJSONBody(w, r, dst, true)
+}
+
+// decodeJSONBody is decodeJSON with control over unknown fields, which
+// third-party webhooks need to tolerate as providers add to their payloads.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst any, strict bool) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return apierror.New(http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMedia,
+				"Content-Type must be application/json")
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	defer r.Body.Close()
+
+	decoder := json.NewDecoder(r.Body)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return apierror.BadRequest("request body must contain a single JSON object")
+	}
+
+	if fields := validate.Struct(dst); len(fields) > 0 {
+		return apierror.Validation(fields...)
+	}
+	return nil
+}
+
+// decodeError turns a json.Decoder error into a message that points at the
+// problem without echoing Go type names back to the client.
+func decodeError(err error) error {
+	var (
+		syntaxErr    *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		maxBytesErr  *http.MaxBytesError
+		unknownField = "json: unknown field "
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return apierror.BadRequest("request body must not be empty").Wrap(err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return apierror.BadRequest("request body is truncated JSON").Wrap(err)
+	case errors.As(err, &syntaxErr):
+		return apierror.BadRequest(fmt.Sprintf("request body has malformed JSON at offset %d", syntaxErr.Offset)).Wrap(err)
+	case errors.As(err, &typeErr):
+		return apierror.Validation(apierror.FieldError{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: fmt.Sprintf("%s must be a %s", typeErr.Field, jsonKind(typeErr.Type)),
+		})
+	case strings.HasPrefix(err.Error(), unknownField):
+		return apierror.BadRequest("request body has unknown field " + strings.TrimPrefix(err.Error(), unknownField)).Wrap(err)
+	case errors.As(err, &maxBytesErr):
+		return apierror.New(http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit))
+	default:
+		return errInvalidJSON.Wrap(err)
+	}
+}
+
+func jsonKind(t reflect.Type) string {
+	if t.Implements(textUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "string"
+	}
+	goKind := t.Kind().String()
+	switch {
+	case goKind == "string":
+		return "string"
+	case goKind == "bool":
+		return "boolean"
+	case strings.HasPrefix(goKind, "int"), strings.HasPrefix(goKind, "uint"), strings.HasPrefix(goKind, "float"):
+		return "number"
+	case goKind == "slice", goKind == "array":
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// recordError hands message to the logging middleware, looking through any
+// ResponseWriter wrappers added after it.
+func recordError(w http.ResponseWriter, message string) {
+	for {
+		if rec, ok := w.(interface{ recordError(string) }); ok {
+			rec.recordError(message)
+			return
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = unwrapper.Unwrap()
+	}
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/store"
+)
+
+func TestRespondWithError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   apierror.Code
+		wantDetail string
+	}{
+		{
+			name:       "apiError",
+			err:        apierror.Forbidden("user is not author of chirp"),
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeForbidden,
+			wantDetail: "user is not author of chirp",
+		},
+		{
+			name:       "apiErrorWrappingStoreError",
+			err:        errNotificationNotFound.Wrap(store.ErrNotFound),
+			wantStatus: http.StatusNotFound,
+			wantCode:   apierror.CodeNotFound,
+			wantDetail: "notification not found",
+		},
+		{
+			name:       "noRows",
+			err:        sql.ErrNoRows,
+			wantStatus: http.StatusNotFound,
+			wantCode:   apierror.CodeNotFound,
+			wantDetail: "resource not found",
+		},
+		{
+			name:       "storeNotFound",
+			err:        fmt.Errorf("%w: %w", store.ErrNotFound, sql.ErrNoRows),
+			wantStatus: http.StatusNotFound,
+			wantCode:   apierror.CodeNotFound,
+			wantDetail: "resource not found",
+		},
+		{
+			name:       "storeDuplicate",
+			err:        store.ErrDuplicate,
+			wantStatus: http.StatusConflict,
+			wantCode:   apierror.CodeConflict,
+			wantDetail: "resource already exists",
+		},
+		{
+			name:       "storeMissingReference",
+			err:        fmt.Errorf("%w: user", store.ErrMissingReference),
+			wantStatus: http.StatusNotFound,
+			wantCode:   apierror.CodeNotFound,
+			wantDetail: "referenced resource not found",
+		},
+		{
+			name:       "otherError",
+			err:        errors.New(`pq: relation "users" does not exist`),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   apierror.CodeInternal,
+			wantDetail: "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+			if err := respondWithError(w, r, tt.err); err != nil {
+				t.Fatal(err)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var body problem
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.wantCode || body.Detail != tt.wantDetail || body.Instance != "/api/chirps" {
+				t.Errorf("body = %+v, want code %q and detail %q", body, tt.wantCode, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    apierror.Code
+		wantDetail  string
+		wantFields  []string
+	}{
+		{
+			name:       "valid",
+			body:       `{"email": "new@example.com", "password": "long enough"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "unknownField",
+			body:       `{"email": "new@example.com", "password": "long enough", "role": "admin"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeBadRequest,
+			wantDetail: `request body has unknown field "role"`,
+		},
+		{
+			name:       "wrongType",
+			body:       `{"email": 42, "password": "long enough"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   apierror.CodeValidation,
+			wantFields: []string{"email"},
+		},
+		{
+			name:       "failsRules",
+			body:       `{"email": "not an email", "password": "short"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   apierror.CodeValidation,
+			wantFields: []string{"email", "password"},
+		},
+		{
+			name:       "malformed",
+			body:       `{"email": }`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeBadRequest,
+			wantDetail: "request body has malformed JSON at offset 11",
+		},
+		{
+			name:       "empty",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeBadRequest,
+			wantDetail: "request body must not be empty",
+		},
+		{
+			name:       "twoObjects",
+			body:       `{"email": "new@example.com", "password": "long enough"} {}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeBadRequest,
+			wantDetail: "request body must contain a single JSON object",
+		},
+		{
+			name:        "wrongContentType",
+			contentType: "text/plain",
+			body:        `{"email": "new@example.com", "password": "long enough"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    apierror.CodeUnsupportedMedia,
+		},
+		{
+			name:       "tooLarge",
+			body:       `{"email": "` + strings.Repeat("a", maxBodyBytes) + `@example.com", "password": "long enough"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   apierror.CodePayloadTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(tt.body))
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			r.Header.Set("Content-Type", contentType)
+
+			w := ts.serve(r)
+			if tt.wantCode == "" {
+				checkStatus(t, w, tt.wantStatus)
+				return
+			}
+			body := checkProblem(t, w, tt.wantStatus, tt.wantCode)
+			if body.Type != problemTypePrefix+string(tt.wantCode) || body.Title != http.StatusText(tt.wantStatus) || body.Instance != "/api/users" {
+				t.Errorf("problem = %+v", body)
+			}
+			if tt.wantDetail != "" && body.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", body.Detail, tt.wantDetail)
+			}
+			var fields []string
+			for _, f := range body.Errors {
+				fields = append(fields, f.Field)
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/logging"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// requestLog collects fields that handlers learn while serving a request so
+// the logging middleware can include them in the access log.
+type requestLog struct {
+	userID uuid.UUID
+}
+
+type requestLogKey struct{}
+
+// logUser records the authenticated user for the current request's log line.
+func logUser(r *http.Request, userID uuid.UUID) {
+	if rl, ok := r.Context().Value(requestLogKey{}).(*requestLog); ok {
+		rl.userID = userID
+	}
+}
+
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status     int
+	bytes      int
+	errMessage string
+}
+
+func (w *loggingResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *loggingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *loggingResponseWriter) recordError(message string) {
+	w.errMessage = message
+}
+
+// middlewareLogging assigns every request an id, taken from X-Request-ID when
+// the client sent a usable one, and writes one access log line per request.
+func middlewareLogging(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		rl := &requestLog{}
+		ctx := logging.WithRequestID(r.Context(), requestID)
+		ctx = context.WithValue(ctx, requestLogKey{}, rl)
+		r = r.WithContext(ctx)
+
+		lw := &loggingResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(lw, r)
+
+		if lw.status == 0 {
+			lw.status = http.StatusOK
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", r.Pattern),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", lw.status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", lw.bytes),
+		}
+		if rl.userID != uuid.Nil {
+			attrs = append(attrs, slog.String("user_id", rl.userID.String()))
+		}
+
+		if lw.errMessage != "" {
+			attrs = append(attrs, slog.String("error", lw.errMessage))
+		}
+
+		level := slog.LevelInfo
+		if lw.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(ctx, level, "request", attrs...)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -1,75 +1,359 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
 	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/config"
+	"github.com/d-shames3/chirpy/internal/contentfilter"
 	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/events"
+	"github.com/d-shames3/chirpy/internal/health"
+	"github.com/d-shames3/chirpy/internal/httpheaders"
+	"github.com/d-shames3/chirpy/internal/jobs"
+	"github.com/d-shames3/chirpy/internal/logging"
+	"github.com/d-shames3/chirpy/internal/metrics"
+	"github.com/d-shames3/chirpy/internal/migrate"
+	"github.com/d-shames3/chirpy/internal/ratelimit"
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/d-shames3/chirpy/internal/spam"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/d-shames3/chirpy/internal/tracing"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
 	godotenv.Load()
-	dbUrl := os.Getenv("DB_URL")
-	platform := os.Getenv("PLATFORM")
-	serverSecret := os.Getenv("SERVER_SECRET")
-	apiKey := os.Getenv("POLKA_KEY")
-	db, err := sql.Open("postgres", dbUrl)
+	conf, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	logger, err := logging.New(os.Stderr, conf.LogLevel, conf.LogFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.TraceExporter, conf.TraceFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	driver, dsn, err := store.ParseURL(conf.DBURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dataStore, err := store.New(driver, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrator, err := migrate.New(driver, db)
 	if err != nil {
 		log.Fatal(err)
 	}
-	dbQueries := database.New(db)
 
-	const filepathRoot = "."
-	const port = "8080"
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			err = runMigrate(context.Background(), migrator, args[1:])
+		case "admin":
+			if err = migrator.Check(context.Background()); err == nil {
+				err = runAdmin(context.Background(), dataStore, args[1:], os.Stdin)
+			}
+		default:
+			err = fmt.Errorf("unknown command %q", args[0])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	slog.Info("effective configuration", "config", conf)
+
+	if conf.AutoMigrate {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("auto-migrate: %v", err)
+		}
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		log.Fatalf("refusing to serve: %v (run `chirpy migrate up` or start with -auto-migrate)", err)
+	}
+
+	// The outbox dispatcher and job queue rely on Postgres advisory locks and
+	// SKIP LOCKED, so SQLite deployments run without them and don't record
+	// domain events at all.
+	var (
+		dispatcher *events.Dispatcher
+		queue      *jobs.Queue
+	)
+	if driver == store.DriverPostgres {
+		dbQueries := database.New(tracing.WrapDB(db, "postgresql"))
+
+		// Nothing in-process subscribes yet: inbox notifications are written
+		// in the same transaction as the change they describe. The
+		// dispatcher still drains the outbox so the retention job can prune
+		// it, and logs each delivery at debug level.
+		dispatcher = events.NewDispatcher(db, events.NewBus(), time.Second)
+		dispatcher.Start(context.Background())
+
+		queue = jobs.NewQueue(dbQueries, 2, time.Second)
+		jobs.RegisterBuiltins(queue, dataStore.RefreshTokens(), dataStore.OAuth(), dbQueries)
+		if conf.RateLimitBackend == ratelimit.BackendPostgres {
+			jobs.RegisterRateLimitPrune(queue, dbQueries)
+		}
+		queue.Start(context.Background())
+	} else {
+		slog.Info("event dispatch and background jobs are disabled", "driver", driver)
+	}
+
+	readiness := health.NewChecker(conf.ReadinessTimeout, conf.ReadinessCacheTTL)
+	readiness.Add("database", db.PingContext)
+	readiness.Add("migrations", migrator.Check)
+	if dispatcher != nil {
+		readiness.Add("outbox_dispatcher", func(context.Context) error { return dispatcher.Healthy() })
+	}
+	if queue != nil {
+		readiness.Add("job_queue", func(context.Context) error { return queue.Healthy() })
+	}
+
+	var limiter ratelimit.Limiter
+	switch conf.RateLimitBackend {
+	case ratelimit.BackendMemory:
+		limiter = ratelimit.NewMemory()
+	case ratelimit.BackendPostgres:
+		limiter = ratelimit.NewPostgres(database.New(tracing.WrapDB(db, "postgresql")))
+	}
+	// Validated with the rest of the config.
+	trustedProxies, _ := ratelimit.ParseTrustedProxies(conf.TrustedProxies)
+
+	contentFilter, err := contentfilter.Open(conf.ContentFilterFile)
+	if err != nil {
+		log.Fatalf("content filter: %v", err)
+	}
 
 	cfg := apiConfig{
-		fileServerHits: atomic.Int32{},
-		db:             *dbQueries,
-		platform:       platform,
-		serverSecret:   serverSecret,
-		apiKey:         apiKey,
+		fileServerHits:      atomic.Int32{},
+		store:               dataStore,
+		jobs:                queue,
+		metrics:             metrics.New(db),
+		readiness:           readiness,
+		limiter:             limiter,
+		trustedProxies:      trustedProxies,
+		platform:            conf.Platform,
+		serverSecret:        conf.ServerSecret,
+		apiKey:              conf.PolkaKey,
+		accessTokenTTL:      conf.AccessTokenTTL,
+		reportHideThreshold: conf.ReportHideThreshold,
+		contentFilter:       contentFilter,
+		spamRules:           conf.SpamRules(),
 	}
 
 	mux := http.NewServeMux()
 
-	fileServerHandler := http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot)))
+	corsOptions := conf.CORS()
+	corsOptions.ExposedHeaders = []string{
+		requestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+	}
+	// Validated with the rest of the config.
+	apiCORS, _ := httpheaders.NewCORS(corsOptions)
+	apiHeaders := httpheaders.Policy{
+		CORS: apiCORS,
+		Security: httpheaders.Security{
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "no-referrer",
+			HSTSMaxAge:            conf.HSTSMaxAge,
+		},
+	}
+	// Server-to-server and operator routes are never called from browsers on
+	// other origins.
+	internalHeaders := apiHeaders
+	internalHeaders.CORS = nil
+	appHeaders := internalHeaders
+	appHeaders.Security.ContentSecurityPolicy = conf.AppContentSecurityPolicy
+	appHeaders.Security.ReferrerPolicy = "strict-origin-when-cross-origin"
+
+	headers := httpheaders.New(mux, apiHeaders, func(r *http.Request) bool {
+		return r.TLS != nil || (ratelimit.FromTrustedProxy(r, trustedProxies) && r.Header.Get("X-Forwarded-Proto") == "https")
+	})
+	// handleInternal registers a route that only servers, operators and
+	// Chirpy's own pages call, so it gets internalHeaders instead of CORS.
+	handleInternal := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, handler)
+		headers.Override(pattern, internalHeaders)
+	}
+
+	fileServerHandler := http.StripPrefix("/app", http.FileServer(http.Dir(conf.FilepathRoot)))
 
 	mux.Handle("/app/", cfg.middlewareMetricsInc(fileServerHandler))
-	mux.HandleFunc("GET /api/healthz", healthHandlerFunc)
-	mux.HandleFunc("POST /api/chirps", cfg.createChirpHandler)
-	mux.HandleFunc("GET /api/chirps", cfg.getChirpsHandler)
-	mux.HandleFunc("GET /api/chirps/{chirpId}", cfg.getChirpHandler)
-	mux.HandleFunc("DELETE /api/chirps/{chirpId}", cfg.deleteChirpHandler)
-	mux.HandleFunc("POST /api/users", cfg.createUserHandler)
-	mux.HandleFunc("PUT /api/users", cfg.updateUserCredsHandler)
-	mux.HandleFunc("POST /api/login", cfg.loginHandler)
-	mux.HandleFunc("POST /api/refresh", cfg.refreshTokenHandler)
-	mux.HandleFunc("POST /api/revoke", cfg.revokeTokenHandler)
-	mux.HandleFunc("POST /api/polka/webhooks", cfg.polkaPaidUserWebhookHandler)
-	mux.HandleFunc("GET /admin/metrics", cfg.metricsHandler)
-	mux.HandleFunc("POST /admin/reset", cfg.resetHandler)
+	headers.Override("/app/", appHeaders)
+	mux.HandleFunc("GET /api/healthz", cfg.healthHandler)
+	mux.HandleFunc("GET /api/livez", livezHandler)
+	mux.HandleFunc("GET /api/readyz", cfg.readyzHandler)
+	mux.HandleFunc("POST /api/chirps", cfg.requireAuth(auth.ScopeChirpsWrite, cfg.rateLimit(writePolicy, cfg.createChirpHandler)))
+	mux.HandleFunc("GET /api/chirps", cfg.optionalAuth(auth.ScopeChirpsRead, cfg.rateLimit(readPolicy, cfg.getChirpsHandler)))
+	mux.HandleFunc("GET /api/chirps/{chirpId}", cfg.optionalAuth(auth.ScopeChirpsRead, cfg.rateLimit(readPolicy, cfg.getChirpHandler)))
+	mux.HandleFunc("DELETE /api/chirps/{chirpId}", cfg.requireAuth(auth.ScopeChirpsWrite, cfg.rateLimit(writePolicy, cfg.deleteChirpHandler)))
+	mux.HandleFunc("POST /api/chirps/{chirpId}/report", cfg.requireAuth(auth.ScopeChirpsWrite, cfg.rateLimit(writePolicy, cfg.reportChirpHandler)))
+	mux.HandleFunc("POST /api/users", cfg.rateLimit(signupPolicy, cfg.createUserHandler))
+	mux.HandleFunc("PUT /api/users", cfg.requireAuth(auth.ScopeProfileWrite, cfg.rateLimit(writePolicy, cfg.updateUserCredsHandler)))
+	mux.HandleFunc("GET /api/users/me/reports", cfg.requireAuth(auth.ScopeChirpsRead, cfg.rateLimit(readPolicy, cfg.listOwnReportsHandler)))
+	mux.HandleFunc("GET /api/notifications", cfg.requireAuth(auth.ScopeChirpsRead, cfg.rateLimit(readPolicy, cfg.listNotificationsHandler)))
+	mux.HandleFunc("POST /api/notifications/{notificationId}/read", cfg.requireAuth(auth.ScopeChirpsRead, cfg.rateLimit(writePolicy, cfg.readNotificationHandler)))
+	mux.HandleFunc("POST /api/users/me/tokens", cfg.requireAuth(auth.ScopeTokens, cfg.rateLimit(writePolicy, cfg.createPersonalAccessTokenHandler)))
+	mux.HandleFunc("GET /api/users/me/tokens", cfg.requireAuth(auth.ScopeTokens, cfg.rateLimit(readPolicy, cfg.listPersonalAccessTokensHandler)))
+	mux.HandleFunc("DELETE /api/users/me/tokens/{tokenId}", cfg.requireAuth(auth.ScopeTokens, cfg.rateLimit(writePolicy, cfg.deletePersonalAccessTokenHandler)))
+	mux.HandleFunc("POST /api/oauth/clients", cfg.requireAuth(auth.ScopeTokens, cfg.rateLimit(writePolicy, cfg.createOAuthClientHandler)))
+	mux.HandleFunc("GET /api/oauth/clients", cfg.requireAuth(auth.ScopeTokens, cfg.rateLimit(readPolicy, cfg.listOAuthClientsHandler)))
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientId}", cfg.requireAuth(auth.ScopeTokens, cfg.rateLimit(writePolicy, cfg.deleteOAuthClientHandler)))
+	mux.HandleFunc("POST /api/login", cfg.rateLimit(loginPolicy, cfg.loginHandler))
+	mux.HandleFunc("POST /api/refresh", cfg.rateLimit(tokenPolicy, cfg.refreshTokenHandler))
+	mux.HandleFunc("POST /api/revoke", cfg.rateLimit(tokenPolicy, cfg.revokeTokenHandler))
+	handleInternal("GET /oauth/authorize", cfg.rateLimit(readPolicy, cfg.authorizeHandler))
+	handleInternal("POST /oauth/authorize", cfg.rateLimit(loginPolicy, cfg.approveAuthorizationHandler))
+	handleInternal("GET /oauth/clients/{clientId}", cfg.rateLimit(readPolicy, cfg.publicOAuthClientHandler))
+	mux.HandleFunc("POST /oauth/token", cfg.rateLimit(tokenPolicy, cfg.oauthTokenHandler))
+	mux.HandleFunc("POST /oauth/introspect", cfg.rateLimit(tokenPolicy, cfg.oauthIntrospectHandler))
+	mux.HandleFunc("POST /oauth/revoke", cfg.rateLimit(tokenPolicy, cfg.oauthRevokeHandler))
+	handleInternal("POST /api/polka/webhooks", cfg.polkaPaidUserWebhookHandler)
+	handleInternal("GET /admin/metrics", cfg.requirePermission(rbac.ViewMetrics, cfg.metricsHandler))
+	handleInternal("GET /metrics", cfg.requirePermission(rbac.ViewMetrics, cfg.metrics.Handler().ServeHTTP))
+	handleInternal("POST /admin/reset", cfg.requirePermission(rbac.ResetData, cfg.resetHandler))
+	handleInternal("GET /admin/jobs", cfg.requirePermission(rbac.ManageJobs, cfg.listJobsHandler))
+	handleInternal("POST /admin/jobs/{jobId}/retry", cfg.requirePermission(rbac.ManageJobs, cfg.retryJobHandler))
+	handleInternal("GET /admin/users/{userId}/moderation", cfg.requirePermission(rbac.ModerateUsers, cfg.getModerationHandler))
+	handleInternal("POST /admin/users/{userId}/suspension", cfg.requirePermission(rbac.ModerateUsers, cfg.suspendUserHandler))
+	handleInternal("DELETE /admin/users/{userId}/suspension", cfg.requirePermission(rbac.ModerateUsers, cfg.unsuspendUserHandler))
+	handleInternal("POST /admin/users/{userId}/ban", cfg.requirePermission(rbac.ModerateUsers, cfg.banUserHandler))
+	handleInternal("DELETE /admin/users/{userId}/ban", cfg.requirePermission(rbac.ModerateUsers, cfg.unbanUserHandler))
+	handleInternal("POST /admin/users/{userId}/shadowban", cfg.requirePermission(rbac.ModerateUsers, cfg.shadowbanUserHandler))
+	handleInternal("DELETE /admin/users/{userId}/shadowban", cfg.requirePermission(rbac.ModerateUsers, cfg.unshadowbanUserHandler))
+	handleInternal("GET /admin/reports", cfg.requirePermission(rbac.ModerateContent, cfg.listReportCasesHandler))
+	handleInternal("GET /admin/reports/{caseId}", cfg.requirePermission(rbac.ModerateContent, cfg.getReportCaseHandler))
+	handleInternal("POST /admin/reports/{caseId}/claim", cfg.requirePermission(rbac.ModerateContent, cfg.claimReportCaseHandler))
+	handleInternal("DELETE /admin/reports/{caseId}/claim", cfg.requirePermission(rbac.ModerateContent, cfg.releaseReportCaseHandler))
+	handleInternal("POST /admin/reports/{caseId}/resolve", cfg.requirePermission(rbac.ModerateContent, cfg.resolveReportCaseHandler))
+	handleInternal("GET /admin/content-filter", cfg.requirePermission(rbac.ManageFilters, cfg.getContentFilterHandler))
+	handleInternal("PUT /admin/content-filter", cfg.requirePermission(rbac.ManageFilters, cfg.replaceContentFilterHandler))
+	handleInternal("POST /admin/content-filter/reload", cfg.requirePermission(rbac.ManageFilters, cfg.reloadContentFilterHandler))
+	handleInternal("POST /admin/content-filter/test", cfg.requirePermission(rbac.ManageFilters, cfg.testContentFilterHandler))
 
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+		Addr:              fmt.Sprintf(":%d", conf.Port),
+		Handler:           tracing.Handler(middlewareLogging(logger, cfg.metrics.Instrument(tracing.Route(headers)))),
+		ReadTimeout:       conf.ReadTimeout,
+		ReadHeaderTimeout: conf.ReadHeaderTimeout,
+		WriteTimeout:      conf.WriteTimeout,
+		IdleTimeout:       conf.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Scrapers can't log in, so METRICS_ADDR serves /metrics without a token
+	// on an address that should only be reachable from inside the network.
+	var metricsServer *http.Server
+	if conf.MetricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", cfg.metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:              conf.MetricsAddr,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: conf.ReadHeaderTimeout,
+		}
+		go func() {
+			if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics server stopped", "error", err)
+			}
+		}()
 	}
 
-	log.Fatal(server.ListenAndServe())
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
+	select {
+	case err := <-serverErr:
+		slog.Error("server stopped", "error", err)
+	case <-ctx.Done():
+		slog.Info("shutting down", "drain_timeout", conf.DrainDelay+conf.ShutdownTimeout)
+		cfg.draining.Store(true)
+		time.Sleep(conf.DrainDelay)
+	}
+
+	// Stop in dependency order: no new requests, then no new background work,
+	// then the connection pool everything else was using.
+	drainCtx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		slog.Error("server shutdown", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(drainCtx); err != nil {
+			slog.Error("metrics server shutdown", "error", err)
+		}
+	}
+	if err := dispatcher.Shutdown(drainCtx); err != nil {
+		slog.Error("event dispatcher shutdown", "error", err)
+	}
+	if err := queue.Shutdown(drainCtx); err != nil {
+		slog.Error("job queue shutdown", "error", err)
+	}
+	if err := db.Close(); err != nil {
+		slog.Error("closing database", "error", err)
+	}
+	if err := shutdownTracing(drainCtx); err != nil {
+		slog.Error("flushing traces", "error", err)
+	}
 }
 
 type apiConfig struct {
 	fileServerHits atomic.Int32
-	db             database.Queries
+	store          store.Store
+	jobs           *jobs.Queue
+	metrics        *metrics.Metrics
+	readiness      *health.Checker
+	limiter        ratelimit.Limiter
+	trustedProxies []netip.Prefix
 	platform       string
 	serverSecret   string
 	apiKey         string
+	accessTokenTTL time.Duration
+	// reportHideThreshold is how many reports hide a chirp pending review;
+	// zero disables auto-hiding.
+	reportHideThreshold int
+	contentFilter       *contentfilter.Manager
+	spamRules           spam.Rules
+	// draining is set once shutdown starts so health checks fail while
+	// in-flight requests finish.
+	draining atomic.Bool
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -95,24 +379,62 @@ func (cfg *apiConfig) metricsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	if cfg.platform != "dev" {
-		w.WriteHeader(http.StatusForbidden)
+	if cfg.platform != config.PlatformDev {
+		respondWithError(w, r, apierror.Forbidden("reset is only available on the dev platform"))
 		return
 	}
 
-	if err := cfg.db.DeleteUsers(r.Context()); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if err := cfg.store.Users().DeleteUsers(r.Context()); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	cfg.fileServerHits.Store(0)
 	w.Write([]byte("Reset hits to 0 and deleted all users"))
 }
 
-func healthHandlerFunc(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if cfg.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("draining"))
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(http.StatusText(http.StatusOK)))
 }
+
+// livezHandler reports that the process is up and serving HTTP. It checks no
+// dependencies, so a database outage makes the instance unready rather than
+// getting it restarted.
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, health.Report{
+		Status:    health.StatusOK,
+		Checks:    map[string]health.Result{},
+		CheckedAt: time.Now().UTC(),
+	})
+}
+
+func (cfg *apiConfig) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.draining.Load() {
+		respondWithJSON(w, http.StatusServiceUnavailable, health.Report{
+			Status: health.StatusFail,
+			Checks: map[string]health.Result{
+				"shutdown": {Status: health.StatusFail, Error: "draining"},
+			},
+			CheckedAt: time.Now().UTC(),
+		})
+		return
+	}
+
+	// A probe that gives up early shouldn't cache a cancelled result for the
+	// next one; each check still has its own timeout.
+	report := cfg.readiness.Run(context.WithoutCancel(r.Context()))
+	code := http.StatusOK
+	if !report.OK() {
+		code = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, code, report)
+}
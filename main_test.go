@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/config"
+	"github.com/d-shames3/chirpy/internal/contentfilter"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/metrics"
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	testSecret   = "0123456789abcdef0123456789abcdef"
+	testPassword = "correct horse battery"
+)
+
+// testServer serves the routes the handler tests exercise, wrapped the same
+// way main wraps them, from an in-memory store.
+type testServer struct {
+	cfg   *apiConfig
+	store *store.Memory
+	mux   *http.ServeMux
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	contentFilter, err := contentfilter.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := store.NewMemory()
+	cfg := &apiConfig{
+		store:               mem,
+		metrics:             metrics.New(nil),
+		platform:            config.PlatformDev,
+		serverSecret:        testSecret,
+		accessTokenTTL:      time.Hour,
+		reportHideThreshold: 2,
+		contentFilter:       contentFilter,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chirps", cfg.requireAuth(auth.ScopeChirpsWrite, cfg.rateLimit(writePolicy, cfg.createChirpHandler)))
+	mux.HandleFunc("GET /api/chirps/{chirpId}", cfg.optionalAuth(auth.ScopeChirpsRead, cfg.rateLimit(readPolicy, cfg.getChirpHandler)))
+	mux.HandleFunc("POST /api/chirps/{chirpId}/report", cfg.requireAuth(auth.ScopeChirpsWrite, cfg.rateLimit(writePolicy, cfg.reportChirpHandler)))
+	mux.HandleFunc("POST /api/users", cfg.rateLimit(signupPolicy, cfg.createUserHandler))
+	mux.HandleFunc("PUT /api/users", cfg.requireAuth(auth.ScopeProfileWrite, cfg.rateLimit(writePolicy, cfg.updateUserCredsHandler)))
+	mux.HandleFunc("GET /api/notifications", cfg.requireAuth(auth.ScopeChirpsRead, cfg.rateLimit(readPolicy, cfg.listNotificationsHandler)))
+	mux.HandleFunc("GET /api/users/me/tokens", cfg.requireAuth(auth.ScopeTokens, cfg.rateLimit(readPolicy, cfg.listPersonalAccessTokensHandler)))
+	mux.HandleFunc("POST /api/oauth/clients", cfg.requireAuth(auth.ScopeTokens, cfg.rateLimit(writePolicy, cfg.createOAuthClientHandler)))
+	mux.HandleFunc("POST /api/login", cfg.rateLimit(loginPolicy, cfg.loginHandler))
+	mux.HandleFunc("POST /api/refresh", cfg.rateLimit(tokenPolicy, cfg.refreshTokenHandler))
+	mux.HandleFunc("GET /oauth/authorize", cfg.rateLimit(readPolicy, cfg.authorizeHandler))
+	mux.HandleFunc("POST /oauth/authorize", cfg.rateLimit(loginPolicy, cfg.approveAuthorizationHandler))
+	mux.HandleFunc("POST /oauth/token", cfg.rateLimit(tokenPolicy, cfg.oauthTokenHandler))
+	mux.HandleFunc("POST /oauth/introspect", cfg.rateLimit(tokenPolicy, cfg.oauthIntrospectHandler))
+	mux.HandleFunc("POST /oauth/revoke", cfg.rateLimit(tokenPolicy, cfg.oauthRevokeHandler))
+	mux.HandleFunc("GET /admin/jobs", cfg.requirePermission(rbac.ManageJobs, cfg.listJobsHandler))
+	mux.HandleFunc("POST /admin/users/{userId}/suspension", cfg.requirePermission(rbac.ModerateUsers, cfg.suspendUserHandler))
+	mux.HandleFunc("DELETE /admin/users/{userId}/suspension", cfg.requirePermission(rbac.ModerateUsers, cfg.unsuspendUserHandler))
+	mux.HandleFunc("POST /admin/users/{userId}/ban", cfg.requirePermission(rbac.ModerateUsers, cfg.banUserHandler))
+	mux.HandleFunc("GET /admin/reports", cfg.requirePermission(rbac.ModerateContent, cfg.listReportCasesHandler))
+	mux.HandleFunc("POST /admin/reports/{caseId}/claim", cfg.requirePermission(rbac.ModerateContent, cfg.claimReportCaseHandler))
+	mux.HandleFunc("POST /admin/reports/{caseId}/resolve", cfg.requirePermission(rbac.ModerateContent, cfg.resolveReportCaseHandler))
+
+	return &testServer{cfg: cfg, store: mem, mux: mux}
+}
+
+// newUser creates a user with testPassword and role.
+func (ts *testServer) newUser(t *testing.T, email string, role rbac.Role) database.User {
+	t.Helper()
+	ctx := t.Context()
+	hash, err := auth.HashPassword(ctx, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := ts.store.Users().CreateUser(ctx, database.CreateUserParams{Email: email, HashedPassword: hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != rbac.RoleUser {
+		user, err = ts.store.Users().UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: user.ID, Role: string(role)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return user
+}
+
+// token issues an access token for userID, limited to scopes if any are
+// given.
+func (ts *testServer) token(t *testing.T, userID uuid.UUID, scopes ...string) string {
+	t.Helper()
+	token, err := auth.MakeJWT(userID, testSecret, time.Hour, scopes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// do sends a JSON request, authenticated with token when it isn't empty.
+func (ts *testServer) do(t *testing.T, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return ts.serve(r)
+}
+
+func (ts *testServer) serve(r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ts.mux.ServeHTTP(w, r)
+	return w
+}
+
+func checkStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Errorf("status = %d, want %d; body %s", w.Code, want, w.Body)
+	}
+}
+
+// decodeResponse checks the response status and decodes its JSON body.
+func decodeResponse[T any](t *testing.T, w *httptest.ResponseRecorder, wantStatus int) T {
+	t.Helper()
+	var v T
+	if w.Code != wantStatus {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, wantStatus, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+	return v
+}
+
+// checkProblem checks that the response is a problem details body with
+// status and code, and returns it.
+func checkProblem(t *testing.T, w *httptest.ResponseRecorder, wantStatus int, wantCode apierror.Code) problem {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", ct)
+	}
+	body := decodeResponse[problem](t, w, wantStatus)
+	if body.Code != wantCode || body.Status != wantStatus {
+		t.Errorf("problem = %+v, want code %q and status %d", body, wantCode, wantStatus)
+	}
+	return body
+}
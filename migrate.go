@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/migrate"
+	"github.com/pressly/goose/v3"
+)
+
+const migrateUsage = "usage: chirpy migrate up|down|status|redo"
+
+func runMigrate(ctx context.Context, m *migrate.Migrator, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := m.Up(ctx)
+		printMigrationResults(results)
+		if err == nil && len(results) == 0 {
+			fmt.Println("no migrations to apply")
+		}
+		return err
+	case "down":
+		result, err := m.Down(ctx)
+		if result != nil {
+			printMigrationResults([]*goose.MigrationResult{result})
+		}
+		return err
+	case "redo":
+		results, err := m.Redo(ctx)
+		printMigrationResults(results)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tMIGRATION\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.State == goose.StateApplied {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Source.Version, s.Source.Path, appliedAt)
+		}
+		return tw.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+func printMigrationResults(results []*goose.MigrationResult) {
+	for _, r := range results {
+		fmt.Println(r)
+	}
+}
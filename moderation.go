@@ -0,0 +1,244 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	actionSuspend     = "suspend"
+	actionUnsuspend   = "unsuspend"
+	actionBan         = "ban"
+	actionUnban       = "unban"
+	actionShadowban   = "shadowban"
+	actionUnshadowban = "unshadowban"
+	actionWarn        = "warn"
+)
+
+type sanctionParams struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type suspensionParams struct {
+	Reason        string `json:"reason" validate:"required,max=500"`
+	DurationHours int32  `json:"duration_hours" validate:"required,min=1,max=8760"`
+}
+
+type moderationActionResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Action      string     `json:"action"`
+	Reason      string     `json:"reason"`
+	ModeratorID *uuid.UUID `json:"moderator_id"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type moderationResponse struct {
+	UserID         uuid.UUID                  `json:"user_id"`
+	SuspendedUntil *time.Time                 `json:"suspended_until"`
+	BannedAt       *time.Time                 `json:"banned_at"`
+	ShadowbannedAt *time.Time                 `json:"shadowbanned_at"`
+	Actions        []moderationActionResponse `json:"actions"`
+}
+
+var (
+	errUserNotFound       = apierror.NotFound("user not found")
+	errMalformedUserID    = apierror.BadRequest("user id is not in UUID format")
+	errModerateStaff      = apierror.Forbidden("moderators and admins can't be moderated; change their role first")
+	errAccountBanned      = apierror.New(http.StatusForbidden, apierror.CodeAccountBanned, "this account has been banned")
+	errModerateOwnAccount = apierror.Forbidden("you can't moderate your own account")
+)
+
+func errAccountSuspended(until time.Time) *apierror.Error {
+	return apierror.New(http.StatusForbidden, apierror.CodeAccountSuspended,
+		"this account is suspended until "+until.UTC().Format(time.RFC3339))
+}
+
+// checkStanding returns an error if user is banned or currently suspended.
+func checkStanding(user database.User) error {
+	if user.BannedAt.Valid {
+		return errAccountBanned
+	}
+	if user.SuspendedUntil.Valid && time.Now().UTC().Before(user.SuspendedUntil.Time) {
+		return errAccountSuspended(user.SuspendedUntil.Time)
+	}
+	return nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (cfg *apiConfig) getModerationHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		respondWithError(w, r, errMalformedUserID)
+		return
+	}
+	user, err := cfg.store.Users().GetUserByID(r.Context(), userId)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errUserNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.respondWithModeration(w, r, user)
+}
+
+func (cfg *apiConfig) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := suspensionParams{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.moderate(w, r, actionSuspend, params.Reason, func(tx store.Store, id uuid.UUID) (database.User, error) {
+		return tx.Moderation().SuspendUser(r.Context(), database.SuspendUserParams{ID: id, Hours: params.DurationHours})
+	})
+}
+
+// banUserHandler bans a user and revokes their refresh tokens. Their access
+// tokens and personal access tokens are refused from then on.
+func (cfg *apiConfig) banUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := sanctionParams{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.moderate(w, r, actionBan, params.Reason, func(tx store.Store, id uuid.UUID) (database.User, error) {
+		user, err := tx.Moderation().BanUser(r.Context(), id)
+		if err != nil {
+			return user, err
+		}
+		return user, tx.RefreshTokens().RevokeUserTokens(r.Context(), id)
+	})
+}
+
+func (cfg *apiConfig) shadowbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := sanctionParams{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.moderate(w, r, actionShadowban, params.Reason, func(tx store.Store, id uuid.UUID) (database.User, error) {
+		return tx.Moderation().ShadowbanUser(r.Context(), id)
+	})
+}
+
+func (cfg *apiConfig) unsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.moderate(w, r, actionUnsuspend, "", func(tx store.Store, id uuid.UUID) (database.User, error) {
+		return tx.Moderation().UnsuspendUser(r.Context(), id)
+	})
+}
+
+func (cfg *apiConfig) unbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.moderate(w, r, actionUnban, "", func(tx store.Store, id uuid.UUID) (database.User, error) {
+		return tx.Moderation().UnbanUser(r.Context(), id)
+	})
+}
+
+func (cfg *apiConfig) unshadowbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.moderate(w, r, actionUnshadowban, "", func(tx store.Store, id uuid.UUID) (database.User, error) {
+		return tx.Moderation().UnshadowbanUser(r.Context(), id)
+	})
+}
+
+// moderate applies a sanction, or lifts one, on the user in the path and
+// records it in the moderation log in the same transaction.
+func (cfg *apiConfig) moderate(w http.ResponseWriter, r *http.Request, action, reason string, apply func(tx store.Store, id uuid.UUID) (database.User, error)) {
+	userId, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		respondWithError(w, r, errMalformedUserID)
+		return
+	}
+	moderatorId := principal(r).UserID
+	if userId == moderatorId {
+		respondWithError(w, r, errModerateOwnAccount)
+		return
+	}
+
+	target, err := cfg.store.Users().GetUserByID(r.Context(), userId)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errUserNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	if rbac.Role(target.Role) != rbac.RoleUser {
+		respondWithError(w, r, errModerateStaff)
+		return
+	}
+
+	var user database.User
+	err = cfg.store.WithTx(r.Context(), func(tx store.Store) error {
+		var err error
+		user, err = apply(tx, userId)
+		if err != nil {
+			return err
+		}
+		params := database.CreateModerationActionParams{
+			UserID:      userId,
+			ModeratorID: uuid.NullUUID{UUID: moderatorId, Valid: true},
+			Action:      action,
+			Reason:      reason,
+		}
+		if action == actionSuspend {
+			params.ExpiresAt = user.SuspendedUntil
+		}
+		_, err = tx.Moderation().CreateModerationAction(r.Context(), params)
+		return err
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errUserNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.respondWithModeration(w, r, user)
+}
+
+func (cfg *apiConfig) respondWithModeration(w http.ResponseWriter, r *http.Request, user database.User) {
+	actions, err := cfg.store.Moderation().ListModerationActions(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	resp := moderationResponse{
+		UserID:         user.ID,
+		SuspendedUntil: nullTimePtr(user.SuspendedUntil),
+		BannedAt:       nullTimePtr(user.BannedAt),
+		ShadowbannedAt: nullTimePtr(user.ShadowbannedAt),
+		Actions:        make([]moderationActionResponse, 0, len(actions)),
+	}
+	for _, a := range actions {
+		action := moderationActionResponse{
+			ID:        a.ID,
+			Action:    a.Action,
+			Reason:    a.Reason,
+			ExpiresAt: nullTimePtr(a.ExpiresAt),
+			CreatedAt: a.CreatedAt,
+		}
+		if a.ModeratorID.Valid {
+			action.ModeratorID = &a.ModeratorID.UUID
+		}
+		resp.Actions = append(resp.Actions, action)
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
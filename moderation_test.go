@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/google/uuid"
+)
+
+func TestSanctions(t *testing.T) {
+	ts := newTestServer(t)
+	moderator := ts.newUser(t, "moderator@example.com", rbac.RoleModerator)
+	user := ts.newUser(t, "user@example.com", rbac.RoleUser)
+	modToken := ts.token(t, moderator.ID)
+	userToken := ts.token(t, user.ID)
+	login := `{"email": "user@example.com", "password": "` + testPassword + `"}`
+
+	t.Run("suspend", func(t *testing.T) {
+		w := ts.do(t, http.MethodPost, "/admin/users/"+user.ID.String()+"/suspension", modToken,
+			`{"reason": "flooding", "duration_hours": 1}`)
+		got := decodeResponse[moderationResponse](t, w, http.StatusOK)
+		if got.SuspendedUntil == nil || len(got.Actions) != 1 || got.Actions[0].Action != actionSuspend || got.Actions[0].ExpiresAt == nil {
+			t.Fatalf("moderation = %+v, want a suspension", got)
+		}
+
+		w = ts.do(t, http.MethodGet, "/api/notifications", userToken, "")
+		checkProblem(t, w, http.StatusForbidden, apierror.CodeAccountSuspended)
+		w = ts.do(t, http.MethodPost, "/api/login", "", login)
+		checkProblem(t, w, http.StatusForbidden, apierror.CodeAccountSuspended)
+	})
+
+	t.Run("unsuspend", func(t *testing.T) {
+		w := ts.do(t, http.MethodDelete, "/admin/users/"+user.ID.String()+"/suspension", modToken, "")
+		got := decodeResponse[moderationResponse](t, w, http.StatusOK)
+		if got.SuspendedUntil != nil || len(got.Actions) != 2 {
+			t.Fatalf("moderation = %+v, want the suspension lifted", got)
+		}
+
+		checkStatus(t, ts.do(t, http.MethodGet, "/api/notifications", userToken, ""), http.StatusOK)
+	})
+
+	t.Run("ban", func(t *testing.T) {
+		w := ts.do(t, http.MethodPost, "/api/login", "", login)
+		session := decodeResponse[userData](t, w, http.StatusOK)
+
+		w = ts.do(t, http.MethodPost, "/admin/users/"+user.ID.String()+"/ban", modToken, `{"reason": "abuse"}`)
+		got := decodeResponse[moderationResponse](t, w, http.StatusOK)
+		if got.BannedAt == nil {
+			t.Fatalf("moderation = %+v, want a ban", got)
+		}
+
+		w = ts.do(t, http.MethodGet, "/api/notifications", session.Token, "")
+		checkProblem(t, w, http.StatusForbidden, apierror.CodeAccountBanned)
+		w = ts.do(t, http.MethodPost, "/api/refresh", session.RefreshToken, "")
+		checkProblem(t, w, http.StatusUnauthorized, apierror.CodeUnauthenticated)
+		w = ts.do(t, http.MethodPost, "/api/login", "", login)
+		checkProblem(t, w, http.StatusForbidden, apierror.CodeAccountBanned)
+	})
+}
+
+func TestSanctionErrors(t *testing.T) {
+	ts := newTestServer(t)
+	moderator := ts.newUser(t, "moderator@example.com", rbac.RoleModerator)
+	other := ts.newUser(t, "other@example.com", rbac.RoleModerator)
+	user := ts.newUser(t, "user@example.com", rbac.RoleUser)
+	modToken := ts.token(t, moderator.ID)
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		{
+			name:       "ownAccount",
+			path:       "/admin/users/" + moderator.ID.String() + "/ban",
+			body:       `{"reason": "testing"}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeForbidden,
+		},
+		{
+			name:       "staff",
+			path:       "/admin/users/" + other.ID.String() + "/ban",
+			body:       `{"reason": "testing"}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeForbidden,
+		},
+		{
+			name:       "unknownUser",
+			path:       "/admin/users/" + uuid.NewString() + "/ban",
+			body:       `{"reason": "testing"}`,
+			wantStatus: http.StatusNotFound,
+			wantCode:   apierror.CodeNotFound,
+		},
+		{
+			name:       "malformedUserID",
+			path:       "/admin/users/nope/ban",
+			body:       `{"reason": "testing"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeBadRequest,
+		},
+		{
+			name:       "missingDuration",
+			path:       "/admin/users/" + user.ID.String() + "/suspension",
+			body:       `{"reason": "testing"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   apierror.CodeValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(t, http.MethodPost, tt.path, modToken, tt.body)
+			checkProblem(t, w, tt.wantStatus, tt.wantCode)
+		})
+	}
+}
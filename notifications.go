@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+const defaultNotificationsLimit = 50
+
+type notificationResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	Kind      string          `json:"kind"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	ReadAt    *time.Time      `json:"read_at"`
+}
+
+var errNotificationNotFound = apierror.NotFound("notification not found")
+
+func newNotificationResponse(n database.Notification) notificationResponse {
+	return notificationResponse{
+		ID:        n.ID,
+		Kind:      n.Kind,
+		Payload:   n.Payload,
+		CreatedAt: n.CreatedAt,
+		ReadAt:    nullTimePtr(n.ReadAt),
+	}
+}
+
+// notify puts a message in a user's inbox. Pass a NotificationRepository
+// bound to the transaction making the change it describes.
+func notify(ctx context.Context, q store.NotificationRepository, userId uuid.UUID, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  userId,
+		Kind:    kind,
+		Payload: data,
+	})
+	return err
+}
+
+func (cfg *apiConfig) listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultNotificationsLimit
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			respondWithError(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "limit",
+				Code:    "positive_integer",
+				Message: "limit must be a positive integer",
+			}))
+			return
+		}
+	}
+
+	notifications, err := cfg.store.Notifications().ListNotifications(r.Context(), database.ListNotificationsParams{
+		UserID:   principal(r).UserID,
+		RowLimit: int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	resp := make([]notificationResponse, 0, len(notifications))
+	for _, n := range notifications {
+		resp = append(resp, newNotificationResponse(n))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) readNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	notificationId, err := uuid.Parse(r.PathValue("notificationId"))
+	if err != nil {
+		respondWithError(w, r, apierror.BadRequest("notification id is not in UUID format"))
+		return
+	}
+
+	n, err := cfg.store.Notifications().MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationId,
+		UserID: principal(r).UserID,
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errNotificationNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newNotificationResponse(n))
+}
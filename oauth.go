@@ -0,0 +1,623 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/oauth"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+// consentPage is the static page, served from the file server, where users
+// log in and approve an authorization request.
+const consentPage = "/app/oauth/consent.html"
+
+type oauthClientParams struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1,max=10"`
+	// Confidential clients get a secret and must authenticate with it at the
+	// token endpoint. Public clients, such as mobile and single-page apps,
+	// rely on PKCE alone.
+	Confidential bool `json:"confidential"`
+}
+
+type oauthClientResponse struct {
+	ClientID     uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+	// ClientSecret is only sent when a confidential client is registered.
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// introspectionResponse is the RFC 7662 response. Only Active is sent for
+// tokens that are not active.
+type introspectionResponse struct {
+	Active    bool      `json:"active"`
+	Scope     string    `json:"scope,omitempty"`
+	ClientID  uuid.UUID `json:"client_id,omitzero"`
+	Subject   uuid.UUID `json:"sub,omitzero"`
+	TokenType string    `json:"token_type,omitempty"`
+	ExpiresAt int64     `json:"exp,omitempty"`
+	IssuedAt  int64     `json:"iat,omitempty"`
+}
+
+var errOAuthClientNotFound = apierror.NotFound("OAuth client not found")
+
+func newOAuthClientResponse(c database.OAuthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ClientID:     c.ID,
+		Name:         c.Name,
+		RedirectURIs: strings.Fields(c.RedirectUris),
+		Confidential: c.SecretHash.Valid,
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+func (cfg *apiConfig) createOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	params := oauthClientParams{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		if err := oauth.ValidateRedirectURI(uri); err != nil {
+			respondWithError(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "redirect_uris",
+				Code:    "redirect_uri",
+				Message: err.Error(),
+			}))
+			return
+		}
+	}
+
+	var secret string
+	var secretHash sql.NullString
+	if params.Confidential {
+		var err error
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, r, apierror.Internal(err))
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.store.OAuth().CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		UserID:       principal(r).UserID,
+		Name:         params.Name,
+		SecretHash:   secretHash,
+		RedirectUris: strings.Join(slices.Compact(params.RedirectURIs), " "),
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	resp := newOAuthClientResponse(client)
+	resp.ClientSecret = secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) listOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	clients, err := cfg.store.OAuth().ListOAuthClients(r.Context(), principal(r).UserID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	resp := make([]oauthClientResponse, 0, len(clients))
+	for _, c := range clients {
+		resp = append(resp, newOAuthClientResponse(c))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) deleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	clientId, err := uuid.Parse(r.PathValue("clientId"))
+	if err != nil {
+		respondWithError(w, r, apierror.BadRequest("malformed client id"))
+		return
+	}
+
+	err = cfg.store.OAuth().DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+		ID:     clientId,
+		UserID: principal(r).UserID,
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errOAuthClientNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publicOAuthClientHandler returns what the consent page shows about a
+// client, so the name a user approves comes from Chirpy rather than the
+// link they followed.
+func (cfg *apiConfig) publicOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	clientId, err := uuid.Parse(r.PathValue("clientId"))
+	if err != nil {
+		respondWithError(w, r, apierror.BadRequest("malformed client id"))
+		return
+	}
+	client, err := cfg.store.OAuth().GetOAuthClient(r.Context(), clientId)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errOAuthClientNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, struct {
+		ClientID uuid.UUID `json:"client_id"`
+		Name     string    `json:"name"`
+	}{client.ID, client.Name})
+}
+
+// authorizationRequest is a validated request to the authorization endpoint.
+type authorizationRequest struct {
+	client      database.OAuthClient
+	redirectURI string
+	scopes      []string
+	state       string
+	challenge   string
+}
+
+// parseAuthorizationRequest validates an authorization request. An unknown
+// client or unregistered redirect URI is an *apierror.Error, shown to the
+// user, since redirecting would hand the response to whoever crafted the
+// link. Other problems are an *oauth.Error to send back to the client's
+// redirect URI, which req then holds.
+func (cfg *apiConfig) parseAuthorizationRequest(ctx context.Context, params url.Values) (req authorizationRequest, err error) {
+	clientId, err := uuid.Parse(params.Get("client_id"))
+	if err != nil {
+		return req, apierror.BadRequest("client_id is missing or malformed")
+	}
+	req.client, err = cfg.store.OAuth().GetOAuthClient(ctx, clientId)
+	if errors.Is(err, store.ErrNotFound) {
+		return req, errOAuthClientNotFound.Wrap(err)
+	}
+	if err != nil {
+		return req, err
+	}
+
+	registered := strings.Fields(req.client.RedirectUris)
+	req.redirectURI = params.Get("redirect_uri")
+	if req.redirectURI == "" && len(registered) == 1 {
+		req.redirectURI = registered[0]
+	}
+	if !slices.Contains(registered, req.redirectURI) {
+		return req, apierror.BadRequest("redirect_uri is not registered for this client")
+	}
+	req.state = params.Get("state")
+
+	if params.Get("response_type") != "code" {
+		return req, oauth.NewError(oauth.ErrUnsupportedResponseType, "response_type must be code")
+	}
+	if req.scopes, err = oauth.ParseScope(params.Get("scope"), auth.GrantableScopes); err != nil {
+		return req, err
+	}
+	if err := oauth.ValidateChallenge(params.Get("code_challenge"), params.Get("code_challenge_method")); err != nil {
+		return req, err
+	}
+	req.challenge = params.Get("code_challenge")
+	return req, nil
+}
+
+// authorizeHandler is the authorization endpoint. It checks the request and
+// sends the user to the consent page, which posts back to
+// approveAuthorizationHandler.
+func (cfg *apiConfig) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := cfg.parseAuthorizationRequest(r.Context(), r.URL.Query())
+	if err != nil {
+		cfg.failAuthorization(w, r, req, err)
+		return
+	}
+	http.Redirect(w, r, consentPage+"?"+r.URL.RawQuery, http.StatusFound)
+}
+
+// approveAuthorizationHandler handles the consent form. The user's password
+// is part of the submission, which also makes a forged cross-site post
+// useless.
+func (cfg *apiConfig) approveAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		respondWithError(w, r, apierror.BadRequest("malformed form body"))
+		return
+	}
+	form := r.PostForm
+
+	req, err := cfg.parseAuthorizationRequest(r.Context(), form)
+	if err != nil {
+		cfg.failAuthorization(w, r, req, err)
+		return
+	}
+	if form.Get("decision") != "approve" {
+		cfg.failAuthorization(w, r, req, oauth.NewError(oauth.ErrAccessDenied, "the user denied the request"))
+		return
+	}
+
+	user, err := cfg.checkCredentials(r.Context(), form.Get("email"), form.Get("password"))
+	if errors.Is(err, errIncorrectCredentials) {
+		// Back to the consent page to try again, keeping the request.
+		retry := url.Values{}
+		for _, key := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method"} {
+			if v := form.Get(key); v != "" {
+				retry.Set(key, v)
+			}
+		}
+		retry.Set("login_failed", "1")
+		recordError(w, err.Error())
+		http.Redirect(w, r, consentPage+"?"+retry.Encode(), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		cfg.failAuthorization(w, r, req, err)
+		return
+	}
+	logUser(r, user.ID)
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		cfg.failAuthorization(w, r, req, err)
+		return
+	}
+	_, err = cfg.store.OAuth().CreateOAuthAuthorizationCode(r.Context(), database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      req.client.ID,
+		UserID:        user.ID,
+		RedirectUri:   req.redirectURI,
+		Scopes:        strings.Join(req.scopes, " "),
+		CodeChallenge: req.challenge,
+	})
+	if err != nil {
+		cfg.failAuthorization(w, r, req, err)
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+	http.Redirect(w, r, oauth.RedirectURL(req.redirectURI, params), http.StatusSeeOther)
+}
+
+// failAuthorization reports an authorization endpoint error: to the client
+// through its redirect URI when there is a trusted one, otherwise to the
+// user.
+func (cfg *apiConfig) failAuthorization(w http.ResponseWriter, r *http.Request, req authorizationRequest, err error) {
+	var apiErr *apierror.Error
+	if req.redirectURI == "" || (errors.As(err, &apiErr) && apiErr.Status < http.StatusInternalServerError) {
+		respondWithError(w, r, err)
+		return
+	}
+
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		oauthErr = oauth.NewError(oauth.ErrServerError, "")
+	}
+	recordError(w, err.Error())
+	params := url.Values{"error": {oauthErr.Code}}
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+	status := http.StatusFound
+	if r.Method == http.MethodPost {
+		status = http.StatusSeeOther
+	}
+	http.Redirect(w, r, oauth.RedirectURL(req.redirectURI, params), status)
+}
+
+// authenticateClient identifies the client calling a token endpoint, from
+// HTTP Basic credentials or client_id and client_secret form fields.
+// Confidential clients must present their secret.
+func (cfg *apiConfig) authenticateClient(r *http.Request) (database.OAuthClient, error) {
+	rawID, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 form-encodes both before Basic encoding.
+		rawID, _ = url.QueryUnescape(rawID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		rawID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	errInvalidClient := oauth.NewError(oauth.ErrInvalidClient, "client authentication failed")
+	clientId, err := uuid.Parse(rawID)
+	if err != nil {
+		return database.OAuthClient{}, errInvalidClient
+	}
+	client, err := cfg.store.OAuth().GetOAuthClient(r.Context(), clientId)
+	if errors.Is(err, store.ErrNotFound) {
+		return database.OAuthClient{}, errInvalidClient
+	}
+	if err != nil {
+		return database.OAuthClient{}, err
+	}
+	if client.SecretHash.Valid &&
+		subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OAuthClient{}, errInvalidClient
+	}
+	return client, nil
+}
+
+// parseTokenRequest reads the form body of a token endpoint request and
+// authenticates the client making it.
+func (cfg *apiConfig) parseTokenRequest(w http.ResponseWriter, r *http.Request) (database.OAuthClient, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		return database.OAuthClient{}, oauth.NewError(oauth.ErrInvalidRequest, "malformed form body")
+	}
+	return cfg.authenticateClient(r)
+}
+
+func (cfg *apiConfig) oauthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	client, err := cfg.parseTokenRequest(w, r)
+	if err != nil {
+		respondWithOAuthError(w, r, err)
+		return
+	}
+
+	var resp oauthTokenResponse
+	switch grant := r.PostForm.Get("grant_type"); grant {
+	case "authorization_code":
+		resp, err = cfg.exchangeAuthorizationCode(r, client)
+	case "refresh_token":
+		resp, err = cfg.refreshOAuthToken(r, client)
+	case "":
+		err = oauth.NewError(oauth.ErrInvalidRequest, "grant_type is required")
+	default:
+		err = oauth.NewError(oauth.ErrUnsupportedGrantType, "unsupported grant_type "+grant)
+	}
+	if err != nil {
+		respondWithOAuthError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) exchangeAuthorizationCode(r *http.Request, client database.OAuthClient) (oauthTokenResponse, error) {
+	form := r.PostForm
+	errInvalidGrant := oauth.NewError(oauth.ErrInvalidGrant, "authorization code is invalid, expired or already used")
+
+	code, err := cfg.store.OAuth().ConsumeOAuthAuthorizationCode(r.Context(), auth.HashToken(form.Get("code")))
+	if errors.Is(err, store.ErrNotFound) {
+		return oauthTokenResponse{}, errInvalidGrant
+	}
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+	if code.ClientID != client.ID || time.Now().UTC().After(code.ExpiresAt) {
+		return oauthTokenResponse{}, errInvalidGrant
+	}
+	if uri := form.Get("redirect_uri"); uri != "" && uri != code.RedirectUri {
+		return oauthTokenResponse{}, oauth.NewError(oauth.ErrInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	if !oauth.VerifyChallenge(form.Get("code_verifier"), code.CodeChallenge) {
+		return oauthTokenResponse{}, oauth.NewError(oauth.ErrInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	scopes := strings.Fields(code.Scopes)
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+	_, err = cfg.store.RefreshTokens().CreateToken(r.Context(), database.CreateTokenParams{
+		Token:    refreshToken,
+		UserID:   code.UserID,
+		ClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+		Scopes:   sql.NullString{String: code.Scopes, Valid: true},
+	})
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+
+	resp, err := cfg.issueOAuthAccessToken(code.UserID, refreshToken, scopes)
+	resp.RefreshToken = refreshToken
+	return resp, err
+}
+
+func (cfg *apiConfig) refreshOAuthToken(r *http.Request, client database.OAuthClient) (oauthTokenResponse, error) {
+	errInvalidGrant := oauth.NewError(oauth.ErrInvalidGrant, "refresh token is invalid, expired or revoked")
+
+	token, err := cfg.store.RefreshTokens().GetToken(r.Context(), r.PostForm.Get("refresh_token"))
+	if errors.Is(err, store.ErrNotFound) {
+		return oauthTokenResponse{}, errInvalidGrant
+	}
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+	if token.ClientID.UUID != client.ID || token.RevokedAt.Valid || time.Now().UTC().After(token.ExpiresAt) {
+		return oauthTokenResponse{}, errInvalidGrant
+	}
+	user, err := cfg.store.Users().GetUserByID(r.Context(), token.UserID)
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+	if err := checkStanding(user); err != nil {
+		return oauthTokenResponse{}, oauth.NewError(oauth.ErrInvalidGrant, "the user's account is suspended or banned")
+	}
+
+	// A client may ask for fewer scopes than it was granted, never more.
+	scopes := strings.Fields(token.Scopes.String)
+	if requested := r.PostForm.Get("scope"); requested != "" {
+		narrowed, err := oauth.ParseScope(requested, scopes)
+		if err != nil {
+			return oauthTokenResponse{}, err
+		}
+		scopes = narrowed
+	}
+	return cfg.issueOAuthAccessToken(token.UserID, token.Token, scopes)
+}
+
+// issueOAuthAccessToken issues an access token in the session of
+// refreshToken.
+func (cfg *apiConfig) issueOAuthAccessToken(userID uuid.UUID, refreshToken string, scopes []string) (oauthTokenResponse, error) {
+	accessToken, err := auth.MakeSessionJWT(userID, auth.SessionID(refreshToken), cfg.serverSecret, cfg.accessTokenTTL, scopes...)
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+	return oauthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(cfg.accessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// oauthIntrospectHandler implements RFC 7662 for confidential clients.
+// Access tokens are reported whichever client they were issued to; refresh
+// tokens only to the client that holds them.
+func (cfg *apiConfig) oauthIntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	client, err := cfg.parseTokenRequest(w, r)
+	if err == nil && !client.SecretHash.Valid {
+		err = oauth.NewError(oauth.ErrUnauthorizedClient, "only confidential clients may introspect tokens")
+	}
+	if err != nil {
+		respondWithOAuthError(w, r, err)
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		respondWithOAuthError(w, r, oauth.NewError(oauth.ErrInvalidRequest, "token is required"))
+		return
+	}
+
+	resp, err := cfg.introspect(r.Context(), client, token)
+	if err != nil {
+		respondWithOAuthError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) introspect(ctx context.Context, client database.OAuthClient, token string) (introspectionResponse, error) {
+	if access, err := auth.ParseJWT(token, cfg.serverSecret); err == nil {
+		user, err := cfg.store.Users().GetUserByID(ctx, access.UserID)
+		if errors.Is(err, store.ErrNotFound) {
+			return introspectionResponse{}, nil
+		}
+		if err != nil {
+			return introspectionResponse{}, err
+		}
+		if checkStanding(user) != nil {
+			return introspectionResponse{}, nil
+		}
+		return introspectionResponse{
+			Active:    true,
+			Scope:     strings.Join(access.Scopes, " "),
+			Subject:   access.UserID,
+			TokenType: "Bearer",
+			ExpiresAt: access.ExpiresAt.Unix(),
+			IssuedAt:  access.IssuedAt.Unix(),
+		}, nil
+	}
+
+	refresh, err := cfg.store.RefreshTokens().GetToken(ctx, token)
+	if errors.Is(err, store.ErrNotFound) {
+		return introspectionResponse{}, nil
+	}
+	if err != nil {
+		return introspectionResponse{}, err
+	}
+	if refresh.ClientID.UUID != client.ID || refresh.RevokedAt.Valid || time.Now().UTC().After(refresh.ExpiresAt) {
+		return introspectionResponse{}, nil
+	}
+	return introspectionResponse{
+		Active:    true,
+		Scope:     refresh.Scopes.String,
+		ClientID:  client.ID,
+		Subject:   refresh.UserID,
+		ExpiresAt: refresh.ExpiresAt.Unix(),
+		IssuedAt:  refresh.CreatedAt.Unix(),
+	}, nil
+}
+
+// oauthRevokeHandler implements RFC 7009. Only refresh tokens can be
+// revoked; access tokens are stateless and run out after ACCESS_TOKEN_TTL.
+// Unknown tokens, and other clients' tokens, succeed without effect.
+func (cfg *apiConfig) oauthRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	client, err := cfg.parseTokenRequest(w, r)
+	if err != nil {
+		respondWithOAuthError(w, r, err)
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		respondWithOAuthError(w, r, oauth.NewError(oauth.ErrInvalidRequest, "token is required"))
+		return
+	}
+	if _, err := auth.ParseJWT(token, cfg.serverSecret); err == nil {
+		respondWithOAuthError(w, r, oauth.NewError(oauth.ErrUnsupportedTokenType, "access tokens cannot be revoked; they expire on their own"))
+		return
+	}
+
+	refresh, err := cfg.store.RefreshTokens().GetToken(r.Context(), token)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		respondWithOAuthError(w, r, err)
+		return
+	}
+	if err == nil && refresh.ClientID.UUID == client.ID && !refresh.RevokedAt.Valid {
+		if _, err := cfg.store.RefreshTokens().RevokeToken(r.Context(), token); err != nil {
+			respondWithOAuthError(w, r, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// respondWithOAuthError writes the RFC 6749 section 5.2 error body the
+// token endpoints use instead of problem details. Errors that are not an
+// *oauth.Error are internal and go through respondWithError.
+func respondWithOAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		respondWithError(w, r, err)
+		return
+	}
+	recordError(w, oauthErr.Error())
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == oauth.ErrInvalidClient {
+		status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+	}
+	body, _ := json.Marshal(oauthErr)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(body)
+}
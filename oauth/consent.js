@@ -0,0 +1,35 @@
+// Fills in the consent form from the authorization request that
+// GET /oauth/authorize has already validated and passed along.
+const scopeDescriptions = {
+  "chirps:read": "Read chirps, including ones visible only to you",
+  "chirps:write": "Post and delete chirps as you",
+  "profile:write": "Change your email and password",
+};
+
+const params = new URLSearchParams(window.location.search);
+const form = document.querySelector("form");
+for (const input of form.querySelectorAll("input[type=hidden]")) {
+  input.value = params.get(input.name) ?? "";
+}
+
+for (const scope of (params.get("scope") ?? "").split(" ").filter(Boolean)) {
+  const item = document.createElement("li");
+  item.textContent = scopeDescriptions[scope] ?? scope;
+  document.getElementById("scopes").append(item);
+}
+
+try {
+  document.getElementById("redirect-host").textContent = new URL(params.get("redirect_uri")).host;
+} catch {
+  // No redirect_uri: the client has a single registered one.
+  document.getElementById("redirect-host").textContent = "the application";
+}
+
+document.getElementById("login-failed").hidden = params.get("login_failed") !== "1";
+
+fetch(`/oauth/clients/${encodeURIComponent(params.get("client_id") ?? "")}`)
+  .then((resp) => (resp.ok ? resp.json() : Promise.reject(resp.status)))
+  .then((client) => {
+    document.getElementById("client-name").textContent = client.name;
+  })
+  .catch(() => {});
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/oauth"
+	"github.com/d-shames3/chirpy/internal/rbac"
+)
+
+const (
+	testRedirectURI = "https://client.example/callback"
+	// testVerifier is the code_verifier from RFC 7636 appendix B.
+	testVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+// newTestOAuthClient registers a confidential client for testRedirectURI.
+func newTestOAuthClient(t *testing.T, ts *testServer) oauthClientResponse {
+	t.Helper()
+	owner := ts.newUser(t, "developer@example.com", rbac.RoleUser)
+	w := ts.do(t, http.MethodPost, "/api/oauth/clients", ts.token(t, owner.ID),
+		`{"name": "Reader", "redirect_uris": ["`+testRedirectURI+`"], "confidential": true}`)
+	client := decodeResponse[oauthClientResponse](t, w, http.StatusCreated)
+	if client.ClientSecret == "" || !client.Confidential {
+		t.Fatalf("client = %+v, want a confidential client with a secret", client)
+	}
+	return client
+}
+
+// authorizationParams is an authorization request for chirps:read with a
+// challenge for testVerifier.
+func authorizationParams(client oauthClientResponse) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ClientID.String()},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {auth.ScopeChirpsRead},
+		"state":                 {"xyz"},
+		"code_challenge":        {oauth.Challenge(testVerifier)},
+		"code_challenge_method": {oauth.MethodS256},
+	}
+}
+
+// postForm sends a form to path, with HTTP Basic credentials when user isn't
+// empty.
+func (ts *testServer) postForm(t *testing.T, path string, form url.Values, user, password string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if user != "" {
+		r.SetBasicAuth(user, password)
+	}
+	return ts.serve(r)
+}
+
+// approve submits the consent form for params and returns where the user is
+// redirected.
+func approve(t *testing.T, ts *testServer, params url.Values, email, password, decision string) *url.URL {
+	t.Helper()
+	form := url.Values{}
+	for k, v := range params {
+		form[k] = v
+	}
+	form.Set("email", email)
+	form.Set("password", password)
+	form.Set("decision", decision)
+
+	w := ts.postForm(t, "/oauth/authorize", form, "", "")
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusSeeOther, w.Body)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+// authorizationCode runs the authorization request for params as
+// user@example.com and returns the code.
+func authorizationCode(t *testing.T, ts *testServer, params url.Values) string {
+	t.Helper()
+	location := approve(t, ts, params, "user@example.com", testPassword, "approve")
+	if got := location.Scheme + "://" + location.Host + location.Path; got != testRedirectURI {
+		t.Fatalf("redirected to %s, want %s", location, testRedirectURI)
+	}
+	if state := location.Query().Get("state"); state != "xyz" {
+		t.Errorf("state = %q, want xyz", state)
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		t.Fatalf("redirected to %s, want a code", location)
+	}
+	return code
+}
+
+func checkOAuthError(t *testing.T, w *httptest.ResponseRecorder, wantStatus int, wantCode string) {
+	t.Helper()
+	body := decodeResponse[oauth.Error](t, w, wantStatus)
+	if body.Code != wantCode {
+		t.Errorf("error = %+v, want %q", body, wantCode)
+	}
+}
+
+func TestOAuthAuthorize(t *testing.T) {
+	ts := newTestServer(t)
+	client := newTestOAuthClient(t, ts)
+	ts.newUser(t, "user@example.com", rbac.RoleUser)
+
+	t.Run("redirectsToConsentPage", func(t *testing.T) {
+		params := authorizationParams(client)
+		w := ts.do(t, http.MethodGet, "/oauth/authorize?"+params.Encode(), "", "")
+		if w.Code != http.StatusFound {
+			t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusFound, w.Body)
+		}
+		if want := consentPage + "?" + params.Encode(); w.Header().Get("Location") != want {
+			t.Errorf("Location = %q, want %q", w.Header().Get("Location"), want)
+		}
+	})
+
+	t.Run("missingChallengeRedirectsError", func(t *testing.T) {
+		params := authorizationParams(client)
+		params.Del("code_challenge")
+		w := ts.do(t, http.MethodGet, "/oauth/authorize?"+params.Encode(), "", "")
+		location, err := url.Parse(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusFound || location.Query().Get("error") != oauth.ErrInvalidRequest {
+			t.Errorf("status = %d, Location = %s, want an invalid_request redirect", w.Code, location)
+		}
+	})
+
+	t.Run("unregisteredRedirectURIShownToUser", func(t *testing.T) {
+		params := authorizationParams(client)
+		params.Set("redirect_uri", "https://evil.example/callback")
+		w := ts.do(t, http.MethodGet, "/oauth/authorize?"+params.Encode(), "", "")
+		checkProblem(t, w, http.StatusBadRequest, apierror.CodeBadRequest)
+	})
+
+	t.Run("wrongPasswordBackToConsentPage", func(t *testing.T) {
+		location := approve(t, ts, authorizationParams(client), "user@example.com", "wrong password", "approve")
+		if location.Path != consentPage || location.Query().Get("login_failed") != "1" || location.Query().Get("password") != "" {
+			t.Errorf("redirected to %s, want the consent page with login_failed and no password", location)
+		}
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		location := approve(t, ts, authorizationParams(client), "user@example.com", testPassword, "deny")
+		if location.Query().Get("error") != oauth.ErrAccessDenied || location.Query().Get("code") != "" {
+			t.Errorf("redirected to %s, want access_denied", location)
+		}
+	})
+}
+
+func TestOAuthTokenFlow(t *testing.T) {
+	ts := newTestServer(t)
+	client := newTestOAuthClient(t, ts)
+	user := ts.newUser(t, "user@example.com", rbac.RoleUser)
+	clientID := client.ClientID.String()
+
+	exchange := func(code, verifier string) url.Values {
+		return url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {testRedirectURI},
+			"code_verifier": {verifier},
+		}
+	}
+
+	t.Run("wrongVerifierUsesUpCode", func(t *testing.T) {
+		code := authorizationCode(t, ts, authorizationParams(client))
+		wrong := strings.Repeat("a", 43)
+		w := ts.postForm(t, "/oauth/token", exchange(code, wrong), clientID, client.ClientSecret)
+		checkOAuthError(t, w, http.StatusBadRequest, oauth.ErrInvalidGrant)
+
+		w = ts.postForm(t, "/oauth/token", exchange(code, testVerifier), clientID, client.ClientSecret)
+		checkOAuthError(t, w, http.StatusBadRequest, oauth.ErrInvalidGrant)
+	})
+
+	code := authorizationCode(t, ts, authorizationParams(client))
+
+	t.Run("wrongClientSecret", func(t *testing.T) {
+		w := ts.postForm(t, "/oauth/token", exchange(code, testVerifier), clientID, "wrong")
+		checkOAuthError(t, w, http.StatusUnauthorized, oauth.ErrInvalidClient)
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Error("missing WWW-Authenticate header")
+		}
+	})
+
+	w := ts.postForm(t, "/oauth/token", exchange(code, testVerifier), clientID, client.ClientSecret)
+	tokens := decodeResponse[oauthTokenResponse](t, w, http.StatusOK)
+	if tokens.TokenType != "Bearer" || tokens.Scope != auth.ScopeChirpsRead || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("token response = %+v", tokens)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", cc)
+	}
+
+	t.Run("accessTokenLimitedToGrantedScope", func(t *testing.T) {
+		checkStatus(t, ts.do(t, http.MethodGet, "/api/notifications", tokens.AccessToken, ""), http.StatusOK)
+		w := ts.do(t, http.MethodPost, "/api/chirps", tokens.AccessToken, `{"body": "hello"}`)
+		checkProblem(t, w, http.StatusForbidden, apierror.CodeInsufficientScope)
+	})
+
+	t.Run("refreshTokenRejectedAtAPIRefresh", func(t *testing.T) {
+		w := ts.do(t, http.MethodPost, "/api/refresh", tokens.RefreshToken, "")
+		checkProblem(t, w, http.StatusUnauthorized, apierror.CodeUnauthenticated)
+	})
+
+	introspect := func(t *testing.T, token string) introspectionResponse {
+		t.Helper()
+		w := ts.postForm(t, "/oauth/introspect", url.Values{"token": {token}}, clientID, client.ClientSecret)
+		return decodeResponse[introspectionResponse](t, w, http.StatusOK)
+	}
+
+	t.Run("introspectAccessToken", func(t *testing.T) {
+		got := introspect(t, tokens.AccessToken)
+		if !got.Active || got.Scope != auth.ScopeChirpsRead || got.Subject != user.ID || got.TokenType != "Bearer" {
+			t.Errorf("introspection = %+v", got)
+		}
+	})
+
+	t.Run("introspectRefreshToken", func(t *testing.T) {
+		got := introspect(t, tokens.RefreshToken)
+		if !got.Active || got.ClientID != client.ClientID || got.Subject != user.ID {
+			t.Errorf("introspection = %+v", got)
+		}
+	})
+
+	t.Run("introspectUnknownToken", func(t *testing.T) {
+		if got := introspect(t, "unknown"); got.Active {
+			t.Errorf("introspection = %+v, want inactive", got)
+		}
+	})
+
+	t.Run("refreshGrant", func(t *testing.T) {
+		form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}}
+		w := ts.postForm(t, "/oauth/token", form, clientID, client.ClientSecret)
+		got := decodeResponse[oauthTokenResponse](t, w, http.StatusOK)
+		if got.AccessToken == "" || got.Scope != auth.ScopeChirpsRead {
+			t.Errorf("token response = %+v", got)
+		}
+	})
+
+	t.Run("revokeAccessTokenUnsupported", func(t *testing.T) {
+		w := ts.postForm(t, "/oauth/revoke", url.Values{"token": {tokens.AccessToken}}, clientID, client.ClientSecret)
+		checkOAuthError(t, w, http.StatusBadRequest, oauth.ErrUnsupportedTokenType)
+	})
+
+	t.Run("revokeRefreshToken", func(t *testing.T) {
+		w := ts.postForm(t, "/oauth/revoke", url.Values{"token": {tokens.RefreshToken}}, clientID, client.ClientSecret)
+		checkStatus(t, w, http.StatusOK)
+		if got := introspect(t, tokens.RefreshToken); got.Active {
+			t.Errorf("introspection = %+v, want inactive", got)
+		}
+		form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}}
+		w = ts.postForm(t, "/oauth/token", form, clientID, client.ClientSecret)
+		checkOAuthError(t, w, http.StatusBadRequest, oauth.ErrInvalidGrant)
+	})
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
-	"encoding/json"
 	"net/http"
 
+	"github.com/d-shames3/chirpy/internal/apierror"
 	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/events"
+	"github.com/d-shames3/chirpy/internal/metrics"
+	"github.com/d-shames3/chirpy/internal/store"
 	"github.com/google/uuid"
 )
 
+const polkaProvider = "polka"
+
 type polkaPaidUserData struct {
-	Event   string               `json:"event"`
+	Event   string               `json:"event" validate:"required"`
 	Payload polkaPaidUserPayload `json:"data"`
 }
 
@@ -20,35 +25,45 @@ type polkaPaidUserPayload struct {
 func (cfg *apiConfig) polkaPaidUserWebhookHandler(w http.ResponseWriter, r *http.Request) {
 	polkaKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		cfg.metrics.Webhook(polkaProvider, metrics.WebhookUnauthorized)
+		respondWithError(w, r, apierror.Unauthenticated(err.Error()))
 		return
 	}
 
 	if cfg.apiKey != polkaKey {
-		w.WriteHeader(http.StatusUnauthorized)
+		cfg.metrics.Webhook(polkaProvider, metrics.WebhookUnauthorized)
+		respondWithError(w, r, apierror.Unauthenticated("invalid API key"))
 		return
 	}
 
 	var rawWebhook polkaPaidUserData
-	defer r.Body.Close()
-	decoder := json.NewDecoder(r.Body)
-	if err = decoder.Decode(&rawWebhook); err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+	if err = decodeJSONBody(w, r, &rawWebhook, false); err != nil {
+		cfg.metrics.Webhook(polkaProvider, metrics.WebhookInvalid)
+		respondWithError(w, r, err)
 		return
 	}
 
 	if rawWebhook.Event != "user.upgraded" {
+		cfg.metrics.Webhook(polkaProvider, metrics.WebhookIgnored)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	err = cfg.db.UpdateUserChirpyRedStatus(r.Context(), rawWebhook.Payload.UserID)
+	userId := rawWebhook.Payload.UserID
+	err = cfg.store.WithTx(r.Context(), func(tx store.Store) error {
+		if err := tx.Users().UpdateUserChirpyRedStatus(r.Context(), userId); err != nil {
+			return err
+		}
+		return events.Record(r.Context(), tx.Outbox(), events.AggregateUser, userId, events.UserUpgraded, rawWebhook.Payload)
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		cfg.metrics.Webhook(polkaProvider, metrics.WebhookFailed)
+		respondWithError(w, r, err)
 		return
 	}
 
+	cfg.metrics.Webhook(polkaProvider, metrics.WebhookProcessed)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNoContent)
 }
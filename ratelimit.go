@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/ratelimit"
+)
+
+// rateLimitPolicy is the limit applied to a group of routes. Routes sharing a
+// policy share its buckets.
+type rateLimitPolicy struct {
+	name string
+	// byUser keys the bucket on the authenticated user when there is one,
+	// and on the client IP otherwise.
+	byUser bool
+	limit  ratelimit.Limit
+	// red replaces limit for Chirpy Red users when set.
+	red ratelimit.Limit
+}
+
+var (
+	loginPolicy = rateLimitPolicy{
+		name:  "login",
+		limit: ratelimit.Limit{Requests: 10, Window: time.Minute},
+	}
+	signupPolicy = rateLimitPolicy{
+		name:  "signup",
+		limit: ratelimit.Limit{Requests: 10, Window: time.Hour},
+	}
+	tokenPolicy = rateLimitPolicy{
+		name:  "tokens",
+		limit: ratelimit.Limit{Requests: 30, Window: time.Minute},
+	}
+	writePolicy = rateLimitPolicy{
+		name:   "write",
+		byUser: true,
+		limit:  ratelimit.Limit{Requests: 20, Window: time.Minute},
+		red:    ratelimit.Limit{Requests: 60, Window: time.Minute},
+	}
+	readPolicy = rateLimitPolicy{
+		name:   "read",
+		byUser: true,
+		limit:  ratelimit.Limit{Requests: 120, Window: time.Minute},
+		red:    ratelimit.Limit{Requests: 600, Window: time.Minute},
+	}
+	// authPolicy runs before credentials are checked, so requests with bad
+	// tokens are limited too. It is keyed by IP and set well above the
+	// per-user policies behind it so it only bites on floods.
+	authPolicy = rateLimitPolicy{
+		name:  "auth",
+		limit: ratelimit.Limit{Requests: 1200, Window: time.Minute},
+	}
+)
+
+var errRateLimited = apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "too many requests, retry later")
+
+// rateLimit applies policy p to next. When the limiter backend fails the
+// request is let through rather than turning an outage into a 429 storm.
+func (cfg *apiConfig) rateLimit(p rateLimitPolicy, next http.HandlerFunc) http.HandlerFunc {
+	if cfg.limiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, limit := cfg.rateLimitKey(r, p)
+		res, err := cfg.limiter.Allow(r.Context(), p.name+":"+key, limit)
+		if err != nil {
+			slog.WarnContext(r.Context(), "rate limiter unavailable, allowing request", "policy", p.name, "error", err)
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds())))
+		if !res.Allowed {
+			h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			cfg.metrics.RateLimited(p.name)
+			respondWithError(w, r, errRateLimited)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateLimitKey picks the bucket key and limit for r. Per-user policies rely
+// on the auth middleware running first to identify the user.
+func (cfg *apiConfig) rateLimitKey(r *http.Request, p rateLimitPolicy) (string, ratelimit.Limit) {
+	if caller, ok := auth.PrincipalFrom(r.Context()); ok && p.byUser {
+		if caller.Tier == auth.TierRed && p.red.Requests > 0 {
+			return "user:" + caller.UserID.String(), p.red
+		}
+		return "user:" + caller.UserID.String(), p.limit
+	}
+	return "ip:" + ratelimit.ClientIP(r, cfg.trustedProxies).String(), p.limit
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
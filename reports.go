@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/events"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	resolutionDismiss     = "dismiss"
+	resolutionHideChirp   = "hide_chirp"
+	resolutionRemoveChirp = "remove_chirp"
+	resolutionWarnAuthor  = "warn_author"
+)
+
+const (
+	caseStatusOpen     = "open"
+	caseStatusClaimed  = "claimed"
+	caseStatusResolved = "resolved"
+)
+
+const defaultReportCasesLimit = 100
+
+type reportParams struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam harassment hate violence sexual misinformation other"`
+	Details string `json:"details" validate:"max=500"`
+}
+
+type resolveReportParams struct {
+	Action string `json:"action" validate:"required,oneof=dismiss hide_chirp remove_chirp warn_author"`
+	Note   string `json:"note" validate:"max=500"`
+}
+
+type reportResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Resolution *string    `json:"resolution"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
+
+type reportCaseResponse struct {
+	ID             uuid.UUID        `json:"id"`
+	ChirpID        *uuid.UUID       `json:"chirp_id"`
+	AuthorID       uuid.UUID        `json:"author_id"`
+	Status         string           `json:"status"`
+	ReportCount    int32            `json:"report_count"`
+	ClaimedBy      *uuid.UUID       `json:"claimed_by"`
+	ClaimedAt      *time.Time       `json:"claimed_at"`
+	Resolution     *string          `json:"resolution"`
+	FlagReason     string           `json:"flag_reason,omitempty"`
+	ResolutionNote string           `json:"resolution_note,omitempty"`
+	ResolvedBy     *uuid.UUID       `json:"resolved_by"`
+	ResolvedAt     *time.Time       `json:"resolved_at"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	Chirp          *chirpResponse   `json:"chirp,omitempty"`
+	Reports        []reportResponse `json:"reports,omitempty"`
+}
+
+type reportResolvedEvent struct {
+	ReportID   uuid.UUID  `json:"report_id"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	CaseID     uuid.UUID  `json:"case_id"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	Resolution string     `json:"resolution"`
+}
+
+var (
+	errMalformedReportCaseID = apierror.BadRequest("report case id is not in UUID format")
+	errReportCaseNotFound    = apierror.NotFound("report case not found")
+	errReportOwnChirp        = apierror.Forbidden("you can't report your own chirp")
+	errAlreadyReported       = apierror.Conflict("you have already reported this chirp")
+	errReportCaseResolved    = apierror.Conflict("report case is already resolved")
+	errReportCaseClaimed     = apierror.Conflict("report case is claimed by another moderator")
+	errReportCaseNotClaimed  = apierror.Conflict("report case is not claimed by you")
+)
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func newReportResponse(report database.Report) reportResponse {
+	return reportResponse{
+		ID:         report.ID,
+		ChirpID:    nullUUIDPtr(report.ChirpID),
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+		Resolution: nullStringPtr(report.Resolution),
+		CreatedAt:  report.CreatedAt,
+		ResolvedAt: nullTimePtr(report.ResolvedAt),
+	}
+}
+
+func newReportCaseResponse(c database.ReportCase) reportCaseResponse {
+	status := caseStatusOpen
+	switch {
+	case c.ResolvedAt.Valid:
+		status = caseStatusResolved
+	case c.ClaimedBy.Valid:
+		status = caseStatusClaimed
+	}
+	return reportCaseResponse{
+		ID:             c.ID,
+		ChirpID:        nullUUIDPtr(c.ChirpID),
+		AuthorID:       c.AuthorID,
+		Status:         status,
+		ReportCount:    c.ReportCount,
+		ClaimedBy:      nullUUIDPtr(c.ClaimedBy),
+		ClaimedAt:      nullTimePtr(c.ClaimedAt),
+		Resolution:     nullStringPtr(c.Resolution),
+		FlagReason:     c.FlagReason,
+		ResolutionNote: c.ResolutionNote,
+		ResolvedBy:     nullUUIDPtr(c.ResolvedBy),
+		ResolvedAt:     nullTimePtr(c.ResolvedAt),
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+	}
+}
+
+// reportChirpHandler files the caller's report in the chirp's open case,
+// opening one if needed, and hides the chirp once the case reaches the
+// report threshold.
+func (cfg *apiConfig) reportChirpHandler(w http.ResponseWriter, r *http.Request) {
+	chirpId, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, r, errMalformedChirpID)
+		return
+	}
+	params := reportParams{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	chirp, err := cfg.store.Chirps().GetChirp(r.Context(), chirpId)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errChirpNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	visible, err := cfg.chirpVisible(r, chirp)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	if !visible {
+		respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	reporterId := principal(r).UserID
+	if reporterId == chirp.UserID {
+		respondWithError(w, r, errReportOwnChirp)
+		return
+	}
+
+	var report database.Report
+	err = cfg.store.WithTx(r.Context(), func(tx store.Store) error {
+		c, err := tx.Reports().OpenReportCase(r.Context(), database.OpenReportCaseParams{
+			ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			AuthorID: chirp.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		report, err = tx.Reports().CreateReport(r.Context(), database.CreateReportParams{
+			CaseID:     c.ID,
+			ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			ReporterID: reporterId,
+			Reason:     params.Reason,
+			Details:    params.Details,
+		})
+		if errors.Is(err, store.ErrDuplicate) {
+			return errAlreadyReported.Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+		c, err = tx.Reports().IncrementReportCount(r.Context(), c.ID)
+		if err != nil {
+			return err
+		}
+		// The case only takes credit for hiding a chirp that was on view, so
+		// dismissing it can't undo an earlier moderator's hide.
+		if cfg.reportHideThreshold > 0 && int(c.ReportCount) >= cfg.reportHideThreshold && !chirp.HiddenAt.Valid && !c.HidChirp {
+			if err := tx.Chirps().HideChirp(r.Context(), chirp.ID); err != nil {
+				return err
+			}
+			_, err = tx.Reports().MarkReportCaseHidChirp(r.Context(), c.ID)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newReportResponse(report))
+}
+
+func (cfg *apiConfig) listOwnReportsHandler(w http.ResponseWriter, r *http.Request) {
+	reports, err := cfg.store.Reports().ListReporterReports(r.Context(), principal(r).UserID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	resp := make([]reportResponse, 0, len(reports))
+	for _, report := range reports {
+		resp = append(resp, newReportResponse(report))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) listReportCasesHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", caseStatusOpen, caseStatusResolved:
+	default:
+		respondWithError(w, r, apierror.Validation(apierror.FieldError{
+			Field:   "status",
+			Code:    "one_of",
+			Message: "status must be one of open or resolved",
+		}))
+		return
+	}
+
+	limit := defaultReportCasesLimit
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			respondWithError(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "limit",
+				Code:    "positive_integer",
+				Message: "limit must be a positive integer",
+			}))
+			return
+		}
+	}
+
+	var (
+		cases []database.ReportCase
+		err   error
+	)
+	if status == caseStatusResolved {
+		cases, err = cfg.store.Reports().ListResolvedReportCases(r.Context(), int32(limit))
+	} else {
+		cases, err = cfg.store.Reports().ListOpenReportCases(r.Context(), int32(limit))
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	resp := make([]reportCaseResponse, 0, len(cases))
+	for _, c := range cases {
+		resp = append(resp, newReportCaseResponse(c))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) getReportCaseHandler(w http.ResponseWriter, r *http.Request) {
+	caseId, err := uuid.Parse(r.PathValue("caseId"))
+	if err != nil {
+		respondWithError(w, r, errMalformedReportCaseID)
+		return
+	}
+	c, err := cfg.store.Reports().GetReportCase(r.Context(), caseId)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errReportCaseNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.respondWithReportCase(w, r, c)
+}
+
+func (cfg *apiConfig) claimReportCaseHandler(w http.ResponseWriter, r *http.Request) {
+	caseId, err := uuid.Parse(r.PathValue("caseId"))
+	if err != nil {
+		respondWithError(w, r, errMalformedReportCaseID)
+		return
+	}
+	c, err := cfg.store.Reports().ClaimReportCase(r.Context(), database.ClaimReportCaseParams{
+		ModeratorID: uuid.NullUUID{UUID: principal(r).UserID, Valid: true},
+		ID:          caseId,
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		err = cfg.reportCaseConflict(r, caseId)
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.respondWithReportCase(w, r, c)
+}
+
+func (cfg *apiConfig) releaseReportCaseHandler(w http.ResponseWriter, r *http.Request) {
+	caseId, err := uuid.Parse(r.PathValue("caseId"))
+	if err != nil {
+		respondWithError(w, r, errMalformedReportCaseID)
+		return
+	}
+	c, err := cfg.store.Reports().ReleaseReportCase(r.Context(), database.ReleaseReportCaseParams{
+		ID:          caseId,
+		ModeratorID: uuid.NullUUID{UUID: principal(r).UserID, Valid: true},
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		err = cfg.reportCaseConflict(r, caseId)
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.respondWithReportCase(w, r, c)
+}
+
+// resolveReportCaseHandler closes a case that is unclaimed or claimed by the
+// caller, acts on the chirp and its author, and tells each reporter the
+// outcome: a report.resolved notification in their inbox and a
+// report.resolved event in the outbox.
+func (cfg *apiConfig) resolveReportCaseHandler(w http.ResponseWriter, r *http.Request) {
+	caseId, err := uuid.Parse(r.PathValue("caseId"))
+	if err != nil {
+		respondWithError(w, r, errMalformedReportCaseID)
+		return
+	}
+	params := resolveReportParams{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	if params.Action == resolutionWarnAuthor && params.Note == "" {
+		respondWithError(w, r, apierror.Validation(apierror.FieldError{
+			Field:   "note",
+			Code:    "required",
+			Message: "note is required when warning the author",
+		}))
+		return
+	}
+
+	moderatorId := principal(r).UserID
+	resolution := sql.NullString{String: params.Action, Valid: true}
+	var c database.ReportCase
+	err = cfg.store.WithTx(r.Context(), func(tx store.Store) error {
+		var err error
+		c, err = tx.Reports().ResolveReportCase(r.Context(), database.ResolveReportCaseParams{
+			Resolution:     resolution,
+			ResolutionNote: params.Note,
+			ModeratorID:    uuid.NullUUID{UUID: moderatorId, Valid: true},
+			ID:             caseId,
+		})
+		if err != nil {
+			return err
+		}
+		if err := applyResolution(r.Context(), tx, c, moderatorId); err != nil {
+			return err
+		}
+
+		reports, err := tx.Reports().ResolveReports(r.Context(), database.ResolveReportsParams{
+			Resolution: resolution,
+			CaseID:     c.ID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, report := range reports {
+			payload := reportResolvedEvent{
+				ReportID:   report.ID,
+				ReporterID: report.ReporterID,
+				CaseID:     c.ID,
+				ChirpID:    nullUUIDPtr(c.ChirpID),
+				Resolution: params.Action,
+			}
+			if err := notify(r.Context(), tx.Notifications(), report.ReporterID, events.ReportResolved, payload); err != nil {
+				return err
+			}
+			if err := events.Record(r.Context(), tx.Outbox(), events.AggregateReport, report.ID, events.ReportResolved, payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		err = cfg.reportCaseConflict(r, caseId)
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.respondWithReportCase(w, r, c)
+}
+
+// applyResolution carries out a resolved case's decision. Dismissing the
+// case puts the chirp back on view if this case hid it; a chirp hidden by
+// anything else stays hidden.
+func applyResolution(ctx context.Context, tx store.Store, c database.ReportCase, moderatorId uuid.UUID) error {
+	if c.Resolution.String == resolutionWarnAuthor {
+		_, err := tx.Moderation().CreateModerationAction(ctx, database.CreateModerationActionParams{
+			UserID:      c.AuthorID,
+			ModeratorID: uuid.NullUUID{UUID: moderatorId, Valid: true},
+			Action:      actionWarn,
+			Reason:      c.ResolutionNote,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// The author may have deleted the chirp since it was reported.
+	if !c.ChirpID.Valid {
+		return nil
+	}
+
+	switch c.Resolution.String {
+	case resolutionHideChirp:
+		return tx.Chirps().HideChirp(ctx, c.ChirpID.UUID)
+	case resolutionRemoveChirp:
+		if err := tx.Chirps().DeleteChirp(ctx, c.ChirpID.UUID); err != nil {
+			return err
+		}
+		payload := chirpDeletedEvent{ID: c.ChirpID.UUID, UserID: c.AuthorID}
+		return events.Record(ctx, tx.Outbox(), events.AggregateChirp, c.ChirpID.UUID, events.ChirpDeleted, payload)
+	case resolutionDismiss:
+		if c.HidChirp {
+			return tx.Chirps().UnhideChirp(ctx, c.ChirpID.UUID)
+		}
+	}
+	return nil
+}
+
+// reportCaseConflict explains why a claim, release or resolution matched no
+// case.
+func (cfg *apiConfig) reportCaseConflict(r *http.Request, caseId uuid.UUID) error {
+	c, err := cfg.store.Reports().GetReportCase(r.Context(), caseId)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return errReportCaseNotFound.Wrap(err)
+	case err != nil:
+		return err
+	case c.ResolvedAt.Valid:
+		return errReportCaseResolved
+	case c.ClaimedBy.Valid && c.ClaimedBy.UUID != principal(r).UserID:
+		return errReportCaseClaimed
+	default:
+		return errReportCaseNotClaimed
+	}
+}
+
+// respondWithReportCase writes the case along with the reported chirp, if it
+// still exists, and the individual reports.
+func (cfg *apiConfig) respondWithReportCase(w http.ResponseWriter, r *http.Request, c database.ReportCase) {
+	resp := newReportCaseResponse(c)
+	if c.ChirpID.Valid {
+		chirp, err := cfg.store.Chirps().GetChirp(r.Context(), c.ChirpID.UUID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			respondWithError(w, r, err)
+			return
+		}
+		if err == nil {
+			resp.Chirp = &chirpResponse{
+				ID:        chirp.ID,
+				CreatedAt: chirp.CreatedAt,
+				UpdatedAt: chirp.UpdatedAt,
+				Body:      chirp.Body,
+				UserID:    chirp.UserID,
+			}
+		}
+	}
+
+	reports, err := cfg.store.Reports().ListCaseReports(r.Context(), c.ID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	resp.Reports = make([]reportResponse, 0, len(reports))
+	for _, report := range reports {
+		resp.Reports = append(resp.Reports, newReportResponse(report))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
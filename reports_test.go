@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/events"
+	"github.com/d-shames3/chirpy/internal/rbac"
+	"github.com/google/uuid"
+)
+
+// postTestChirp posts body as the holder of token and returns the chirp's
+// path.
+func postTestChirp(t *testing.T, ts *testServer, token, body string) string {
+	t.Helper()
+	w := ts.do(t, http.MethodPost, "/api/chirps", token, `{"body": "`+body+`"}`)
+	chirp := decodeResponse[chirpResponse](t, w, http.StatusCreated)
+	return "/api/chirps/" + chirp.ID.String()
+}
+
+// onlyOpenCase returns the single case in the open queue.
+func onlyOpenCase(t *testing.T, ts *testServer, token string) reportCaseResponse {
+	t.Helper()
+	cases := decodeResponse[[]reportCaseResponse](t, ts.do(t, http.MethodGet, "/admin/reports", token, ""), http.StatusOK)
+	if len(cases) != 1 {
+		t.Fatalf("got %d open cases, want 1", len(cases))
+	}
+	return cases[0]
+}
+
+func TestReportLifecycle(t *testing.T) {
+	ts := newTestServer(t)
+	author := ts.token(t, ts.newUser(t, "author@example.com", rbac.RoleUser).ID)
+	reporter1 := ts.token(t, ts.newUser(t, "reporter1@example.com", rbac.RoleUser).ID)
+	reporter2 := ts.token(t, ts.newUser(t, "reporter2@example.com", rbac.RoleUser).ID)
+	moderator1 := ts.token(t, ts.newUser(t, "moderator1@example.com", rbac.RoleModerator).ID)
+	moderator2 := ts.token(t, ts.newUser(t, "moderator2@example.com", rbac.RoleModerator).ID)
+	chirpPath := postTestChirp(t, ts, author, "buy my course")
+	report := `{"reason": "spam"}`
+
+	t.Run("open", func(t *testing.T) {
+		w := ts.do(t, http.MethodPost, chirpPath+"/report", author, report)
+		checkProblem(t, w, http.StatusForbidden, apierror.CodeForbidden)
+
+		w = ts.do(t, http.MethodPost, chirpPath+"/report", reporter1, report)
+		got := decodeResponse[reportResponse](t, w, http.StatusCreated)
+		if got.Reason != "spam" || got.Resolution != nil {
+			t.Errorf("report = %+v", got)
+		}
+		w = ts.do(t, http.MethodPost, chirpPath+"/report", reporter1, report)
+		checkProblem(t, w, http.StatusConflict, apierror.CodeConflict)
+		checkStatus(t, ts.do(t, http.MethodGet, chirpPath, "", ""), http.StatusOK)
+
+		// The second report reaches the threshold and hides the chirp from
+		// everyone but its author.
+		w = ts.do(t, http.MethodPost, chirpPath+"/report", reporter2, report)
+		checkStatus(t, w, http.StatusCreated)
+		checkStatus(t, ts.do(t, http.MethodGet, chirpPath, "", ""), http.StatusNotFound)
+		checkStatus(t, ts.do(t, http.MethodGet, chirpPath, author, ""), http.StatusOK)
+
+		c := onlyOpenCase(t, ts, moderator1)
+		if c.Status != caseStatusOpen || c.ReportCount != 2 {
+			t.Errorf("case = %+v, want open with 2 reports", c)
+		}
+	})
+
+	casePath := "/admin/reports/" + onlyOpenCase(t, ts, moderator1).ID.String()
+
+	t.Run("claim", func(t *testing.T) {
+		w := ts.do(t, http.MethodPost, casePath+"/claim", moderator1, "")
+		got := decodeResponse[reportCaseResponse](t, w, http.StatusOK)
+		if got.Status != caseStatusClaimed || got.ClaimedBy == nil || len(got.Reports) != 2 || got.Chirp == nil {
+			t.Errorf("case = %+v, want claimed with its chirp and 2 reports", got)
+		}
+
+		w = ts.do(t, http.MethodPost, casePath+"/claim", moderator2, "")
+		checkProblem(t, w, http.StatusConflict, apierror.CodeConflict)
+		w = ts.do(t, http.MethodPost, casePath+"/resolve", moderator2, `{"action": "dismiss"}`)
+		checkProblem(t, w, http.StatusConflict, apierror.CodeConflict)
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		w := ts.do(t, http.MethodPost, casePath+"/resolve", moderator1, `{"action": "warn_author"}`)
+		checkProblem(t, w, http.StatusUnprocessableEntity, apierror.CodeValidation)
+
+		w = ts.do(t, http.MethodPost, casePath+"/resolve", moderator1, `{"action": "dismiss", "note": "not spam"}`)
+		got := decodeResponse[reportCaseResponse](t, w, http.StatusOK)
+		if got.Status != caseStatusResolved || got.Resolution == nil || *got.Resolution != resolutionDismiss {
+			t.Fatalf("case = %+v, want dismissed", got)
+		}
+		for _, r := range got.Reports {
+			if r.Resolution == nil || *r.Resolution != resolutionDismiss {
+				t.Errorf("report = %+v, want dismissed", r)
+			}
+		}
+
+		// The case hid the chirp, so dismissing it shows the chirp again.
+		checkStatus(t, ts.do(t, http.MethodGet, chirpPath, "", ""), http.StatusOK)
+
+		w = ts.do(t, http.MethodGet, "/api/notifications", reporter1, "")
+		notifications := decodeResponse[[]notificationResponse](t, w, http.StatusOK)
+		if len(notifications) != 1 || notifications[0].Kind != events.ReportResolved {
+			t.Errorf("notifications = %+v, want one %s", notifications, events.ReportResolved)
+		}
+
+		w = ts.do(t, http.MethodPost, casePath+"/resolve", moderator1, `{"action": "dismiss"}`)
+		checkProblem(t, w, http.StatusConflict, apierror.CodeConflict)
+	})
+
+	t.Run("reportAgainAfterResolution", func(t *testing.T) {
+		w := ts.do(t, http.MethodPost, chirpPath+"/report", reporter1, report)
+		checkStatus(t, w, http.StatusCreated)
+		if c := onlyOpenCase(t, ts, moderator1); "/admin/reports/"+c.ID.String() == casePath || c.ReportCount != 1 {
+			t.Errorf("case = %+v, want a new case with 1 report", c)
+		}
+	})
+}
+
+func TestResolutionOnlyUnhidesItsOwnHide(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := t.Context()
+	author := ts.newUser(t, "author@example.com", rbac.RoleUser)
+	authorToken := ts.token(t, author.ID)
+	reporter1 := ts.token(t, ts.newUser(t, "reporter1@example.com", rbac.RoleUser).ID)
+	reporter2 := ts.token(t, ts.newUser(t, "reporter2@example.com", rbac.RoleUser).ID)
+	moderator := ts.token(t, ts.newUser(t, "moderator@example.com", rbac.RoleModerator).ID)
+
+	resolve := func(t *testing.T, body string) {
+		t.Helper()
+		c := onlyOpenCase(t, ts, moderator)
+		checkStatus(t, ts.do(t, http.MethodPost, "/admin/reports/"+c.ID.String()+"/resolve", moderator, body), http.StatusOK)
+	}
+
+	t.Run("warnAuthorKeepsHide", func(t *testing.T) {
+		chirpPath := postTestChirp(t, ts, authorToken, "buy my course")
+		for _, token := range []string{reporter1, reporter2} {
+			checkStatus(t, ts.do(t, http.MethodPost, chirpPath+"/report", token, `{"reason": "spam"}`), http.StatusCreated)
+		}
+		resolve(t, `{"action": "warn_author", "note": "no advertising"}`)
+		checkStatus(t, ts.do(t, http.MethodGet, chirpPath, "", ""), http.StatusNotFound)
+	})
+
+	t.Run("dismissKeepsEarlierHide", func(t *testing.T) {
+		chirp, err := ts.store.Chirps().CreateChirp(ctx, database.CreateChirpParams{UserID: author.ID, Body: "buy my other course"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Hidden chirps can't be reported through the API, so the earlier
+		// hide and the case that finds it are set up in the store.
+		if err := ts.store.Chirps().HideChirp(ctx, chirp.ID); err != nil {
+			t.Fatal(err)
+		}
+		_, err = ts.store.Reports().OpenReportCase(ctx, database.OpenReportCaseParams{
+			ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			AuthorID: author.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resolve(t, `{"action": "dismiss"}`)
+		checkStatus(t, ts.do(t, http.MethodGet, "/api/chirps/"+chirp.ID.String(), "", ""), http.StatusNotFound)
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/config"
+	"github.com/d-shames3/chirpy/internal/database"
+)
+
+// Browser sessions keep their tokens in cookies the page's scripts can't
+// read. Because browsers attach cookies to requests other sites trigger,
+// state-changing requests authenticated by cookie must also echo the CSRF
+// cookie in the X-CSRF-Token header, which only Chirpy's own pages can read.
+const (
+	accessCookie  = "chirpy_access"
+	refreshCookie = "chirpy_refresh"
+	csrfCookie    = "chirpy_csrf"
+	csrfHeader    = "X-CSRF-Token"
+	// The refresh cookie is only needed by /api/refresh and /api/revoke.
+	refreshCookiePath = "/api/"
+)
+
+var errInvalidCSRFToken = apierror.New(http.StatusForbidden, apierror.CodeInvalidCSRFToken,
+	"missing or incorrect "+csrfHeader+" header")
+
+// sessionCookie builds a session cookie. It is marked Secure except on the
+// dev platform, where browsers wouldn't send it back to http://localhost.
+func (cfg *apiConfig) sessionCookie(name, value, path string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		HttpOnly: name != csrfCookie,
+		Secure:   cfg.platform != config.PlatformDev,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+func (cfg *apiConfig) setAccessCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, cfg.sessionCookie(accessCookie, token, "/", time.Now().Add(cfg.accessTokenTTL)))
+}
+
+// startSession sets the cookies for a browser session and returns its CSRF
+// token.
+func (cfg *apiConfig) startSession(w http.ResponseWriter, accessToken string, refresh database.RefreshToken) (string, error) {
+	csrfToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	cfg.setAccessCookie(w, accessToken)
+	http.SetCookie(w, cfg.sessionCookie(refreshCookie, refresh.Token, refreshCookiePath, refresh.ExpiresAt))
+	http.SetCookie(w, cfg.sessionCookie(csrfCookie, csrfToken, "/", refresh.ExpiresAt))
+	return csrfToken, nil
+}
+
+func (cfg *apiConfig) endSession(w http.ResponseWriter) {
+	for _, c := range []*http.Cookie{
+		cfg.sessionCookie(accessCookie, "", "/", time.Time{}),
+		cfg.sessionCookie(refreshCookie, "", refreshCookiePath, time.Time{}),
+		cfg.sessionCookie(csrfCookie, "", "/", time.Time{}),
+	} {
+		c.MaxAge = -1
+		http.SetCookie(w, c)
+	}
+}
+
+// checkCSRF enforces the double-submit check on requests authenticated by
+// a session cookie. Safe methods don't change anything and are let through.
+func checkCSRF(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		return errInvalidCSRFToken
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.Header.Get(csrfHeader))) != 1 {
+		return errInvalidCSRFToken
+	}
+	return nil
+}
+
+// sessionToken returns the token held in cookie, once the request's CSRF
+// token checks out. ok is false when there is no such cookie.
+func sessionToken(r *http.Request, cookie string) (token string, ok bool, err error) {
+	c, err := r.Cookie(cookie)
+	if err != nil || c.Value == "" {
+		return "", false, nil
+	}
+	if err := checkCSRF(r); err != nil {
+		return "", false, err
+	}
+	return c.Value, true, nil
+}
+
+// requestRefreshToken returns the refresh token a request to /api/refresh or
+// /api/revoke presents, and whether it came from a session cookie.
+func requestRefreshToken(r *http.Request) (token string, fromCookie bool, err error) {
+	if r.Header.Get("Authorization") == "" {
+		token, fromCookie, err = sessionToken(r, refreshCookie)
+		if fromCookie || err != nil {
+			return token, fromCookie, err
+		}
+	}
+	token, err = auth.GetBearerToken(r.Header)
+	if err != nil {
+		return "", false, apierror.Unauthenticated(err.Error())
+	}
+	return token, false, nil
+}
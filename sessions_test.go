@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/config"
+	"github.com/d-shames3/chirpy/internal/rbac"
+)
+
+// startTestSession logs email in with use_cookies and returns the session
+// cookies and CSRF token.
+func startTestSession(t *testing.T, ts *testServer, email string) ([]*http.Cookie, string) {
+	t.Helper()
+	w := ts.do(t, http.MethodPost, "/api/login", "", `{"email": "`+email+`", "password": "`+testPassword+`", "use_cookies": true}`)
+	user := decodeResponse[userData](t, w, http.StatusOK)
+	if user.Token != "" || user.RefreshToken != "" || user.CSRFToken == "" {
+		t.Fatalf("login response = %+v, want only a CSRF token", user)
+	}
+	return w.Result().Cookies(), user.CSRFToken
+}
+
+func TestSessionCSRF(t *testing.T) {
+	ts := newTestServer(t)
+	ts.newUser(t, "user@example.com", rbac.RoleUser)
+	cookies, csrfToken := startTestSession(t, ts, "user@example.com")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		csrf       string
+		bearer     bool
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		{
+			name:       "safeMethodWithoutHeader",
+			method:     http.MethodGet,
+			path:       "/api/notifications",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missingHeader",
+			method:     http.MethodPost,
+			path:       "/api/chirps",
+			body:       `{"body": "hello"}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeInvalidCSRFToken,
+		},
+		{
+			name:       "mismatchedHeader",
+			method:     http.MethodPost,
+			path:       "/api/chirps",
+			body:       `{"body": "hello"}`,
+			csrf:       csrfToken + "x",
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeInvalidCSRFToken,
+		},
+		{
+			name:       "matchingHeader",
+			method:     http.MethodPost,
+			path:       "/api/chirps",
+			body:       `{"body": "hello"}`,
+			csrf:       csrfToken,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "refreshMissingHeader",
+			method:     http.MethodPost,
+			path:       "/api/refresh",
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierror.CodeInvalidCSRFToken,
+		},
+		{
+			name:       "refreshMatchingHeader",
+			method:     http.MethodPost,
+			path:       "/api/refresh",
+			csrf:       csrfToken,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			// The header takes precedence, and cross-site requests can't set
+			// it, so the cookies are ignored.
+			name:       "bearerTokenIgnoresCookies",
+			method:     http.MethodPost,
+			path:       "/api/chirps",
+			body:       `{"body": "hello again"}`,
+			bearer:     true,
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			for _, c := range cookies {
+				r.AddCookie(c)
+			}
+			if tt.csrf != "" {
+				r.Header.Set(csrfHeader, tt.csrf)
+			}
+			if tt.bearer {
+				user, err := ts.store.Users().GetUser(t.Context(), "user@example.com")
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.Header.Set("Authorization", "Bearer "+ts.token(t, user.ID))
+			}
+
+			w := ts.serve(r)
+			if tt.wantCode == "" {
+				checkStatus(t, w, tt.wantStatus)
+				return
+			}
+			checkProblem(t, w, tt.wantStatus, tt.wantCode)
+		})
+	}
+}
+
+func TestSessionCookieSecure(t *testing.T) {
+	tests := []struct {
+		platform   string
+		wantSecure bool
+	}{
+		{platform: config.PlatformDev, wantSecure: false},
+		{platform: config.PlatformProd, wantSecure: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			ts := newTestServer(t)
+			ts.cfg.platform = tt.platform
+			ts.newUser(t, "user@example.com", rbac.RoleUser)
+
+			cookies, _ := startTestSession(t, ts, "user@example.com")
+			if len(cookies) != 3 {
+				t.Fatalf("got %d cookies, want 3", len(cookies))
+			}
+			for _, c := range cookies {
+				if c.Secure != tt.wantSecure {
+					t.Errorf("cookie %s Secure = %v, want %v", c.Name, c.Secure, tt.wantSecure)
+				}
+				if wantHTTPOnly := c.Name != csrfCookie; c.HttpOnly != wantHTTPOnly {
+					t.Errorf("cookie %s HttpOnly = %v, want %v", c.Name, c.HttpOnly, wantHTTPOnly)
+				}
+			}
+		})
+	}
+}
@@ -11,13 +11,17 @@ RETURNING *;
 
 -- name: GetChirps :many
 SELECT
-    id,
-    user_id,
-    body,
-    created_at,
-    updated_at
+    chirps.id,
+    chirps.user_id,
+    chirps.body,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.hidden_at
 FROM chirps
-ORDER BY created_at;
+JOIN users ON users.id = chirps.user_id
+WHERE (users.shadowbanned_at IS NULL AND chirps.hidden_at IS NULL)
+    OR chirps.user_id = sqlc.narg(viewer_id)
+ORDER BY chirps.created_at;
 
 -- name: GetChirp :one
 SELECT
@@ -25,7 +29,8 @@ SELECT
     user_id,
     body,
     created_at,
-    updated_at
+    updated_at,
+    hidden_at
 FROM chirps
 WHERE id = $1;
 
@@ -35,12 +40,40 @@ FROM chirps
 WHERE id = $1;
 
 -- name: GetChirpsByAuthor :many
+SELECT
+    chirps.id,
+    chirps.user_id,
+    chirps.body,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.hidden_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = sqlc.arg(user_id)
+    AND ((users.shadowbanned_at IS NULL AND chirps.hidden_at IS NULL)
+        OR chirps.user_id = sqlc.narg(viewer_id))
+ORDER BY chirps.created_at;
+
+-- name: GetRecentChirpsByAuthor :many
 SELECT
     id,
     user_id,
     body,
     created_at,
-    updated_at
+    updated_at,
+    hidden_at
 FROM chirps
-WHERE user_id = $1
-ORDER BY created_at;
+WHERE user_id = sqlc.arg(user_id)
+    AND created_at > now() - make_interval(secs => sqlc.arg(window_seconds)::int)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, now())
+WHERE id = $1;
+
+-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1;
@@ -0,0 +1,103 @@
+-- name: CreateJob :one
+INSERT INTO jobs (id, kind, payload, status, attempts, max_attempts, run_at, unique_key, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    'pending',
+    0,
+    $3,
+    $4,
+    $5,
+    now(),
+    now()
+)
+ON CONFLICT (unique_key) DO NOTHING
+RETURNING *;
+
+-- name: ClaimJob :one
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, locked_at = now(), updated_at = now()
+WHERE id = (
+    SELECT id
+    FROM jobs
+    WHERE status = 'pending' AND run_at <= now()
+    ORDER BY run_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteJob :exec
+UPDATE jobs
+SET status = 'succeeded', locked_at = NULL, last_error = NULL, updated_at = now()
+WHERE id = $1;
+
+-- name: RetryJobLater :exec
+UPDATE jobs
+SET status = 'pending', run_at = $2, last_error = $3, locked_at = NULL, updated_at = now()
+WHERE id = $1;
+
+-- name: KillJob :exec
+UPDATE jobs
+SET status = 'dead', last_error = $2, locked_at = NULL, updated_at = now()
+WHERE id = $1;
+
+-- name: RequeueJob :one
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = now(), locked_at = NULL, updated_at = now()
+WHERE id = $1 AND status <> 'running'
+RETURNING *;
+
+-- name: ReleaseStaleJobs :execrows
+-- Releases jobs whose worker stopped responding. The claim already counted
+-- the attempt, so a job that keeps taking its worker down is marked dead once
+-- it reaches max_attempts instead of being claimed forever.
+UPDATE jobs
+SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+    last_error = 'lock expired before the job finished',
+    locked_at = NULL,
+    updated_at = now()
+WHERE status = 'running' AND locked_at < $1;
+
+-- name: DeleteSucceededJobs :execrows
+-- Deletes jobs that succeeded more than retention_seconds ago.
+DELETE FROM jobs
+WHERE status = 'succeeded'
+    AND updated_at < now() - make_interval(secs => sqlc.arg(retention_seconds)::int);
+
+-- name: GetJob :one
+SELECT
+    id,
+    kind,
+    payload,
+    status,
+    attempts,
+    max_attempts,
+    run_at,
+    unique_key,
+    locked_at,
+    last_error,
+    created_at,
+    updated_at
+FROM jobs
+WHERE id = $1;
+
+-- name: ListJobs :many
+SELECT
+    id,
+    kind,
+    payload,
+    status,
+    attempts,
+    max_attempts,
+    run_at,
+    unique_key,
+    locked_at,
+    last_error,
+    created_at,
+    updated_at
+FROM jobs
+WHERE sqlc.narg('status')::varchar IS NULL OR status = sqlc.narg('status')
+ORDER BY created_at DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,73 @@
+-- name: SuspendUser :one
+UPDATE users
+SET
+    suspended_until = now() + make_interval(hours => sqlc.arg(hours)::int),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET
+    suspended_until = NULL,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: BanUser :one
+UPDATE users
+SET
+    banned_at = now(),
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnbanUser :one
+UPDATE users
+SET
+    banned_at = NULL,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ShadowbanUser :one
+UPDATE users
+SET
+    shadowbanned_at = now(),
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnshadowbanUser :one
+UPDATE users
+SET
+    shadowbanned_at = NULL,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, user_id, moderator_id, action, reason, expires_at, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    now()
+)
+RETURNING *;
+
+-- name: ListModerationActions :many
+SELECT
+    id,
+    user_id,
+    moderator_id,
+    action,
+    reason,
+    expires_at,
+    created_at
+FROM moderation_actions
+WHERE user_id = $1
+ORDER BY created_at DESC;
@@ -0,0 +1,23 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, user_id, kind, payload, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    now()
+)
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT *
+FROM notifications
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: MarkNotificationRead :one
+UPDATE notifications
+SET read_at = COALESCE(read_at, now())
+WHERE id = $1 AND user_id = $2
+RETURNING *;
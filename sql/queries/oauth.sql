@@ -0,0 +1,64 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, user_id, name, secret_hash, redirect_uris, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    now()
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT
+    id,
+    user_id,
+    name,
+    secret_hash,
+    redirect_uris,
+    created_at
+FROM oauth_clients
+WHERE id = $1;
+
+-- name: ListOAuthClients :many
+SELECT
+    id,
+    user_id,
+    name,
+    secret_hash,
+    redirect_uris,
+    created_at
+FROM oauth_clients
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE
+FROM oauth_clients
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    now(),
+    now() + interval '10 minutes'
+)
+RETURNING *;
+
+-- name: ConsumeOAuthAuthorizationCode :one
+DELETE
+FROM oauth_authorization_codes
+WHERE code_hash = $1
+RETURNING *;
+
+-- name: DeleteExpiredOAuthAuthorizationCodes :execrows
+DELETE
+FROM oauth_authorization_codes
+WHERE expires_at < now();
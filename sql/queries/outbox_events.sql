@@ -0,0 +1,66 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (aggregate_type, aggregate_id, event_type, payload, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    now()
+)
+RETURNING *;
+
+-- name: ClaimOutboxEvents :many
+-- Claims up to batch_size pending events, oldest first, until claim_seconds
+-- from now. Aggregates with an event that is already claimed or waiting to
+-- be retried are skipped whole, so their later events can't overtake it.
+UPDATE outbox_events
+SET available_at = now() + make_interval(secs => sqlc.arg(claim_seconds)::int)
+WHERE id IN (
+    SELECT e.id
+    FROM outbox_events e
+    WHERE e.dispatched_at IS NULL
+        AND e.dead_at IS NULL
+        AND NOT EXISTS (
+            SELECT 1
+            FROM outbox_events blocking
+            WHERE blocking.aggregate_id = e.aggregate_id
+                AND blocking.dispatched_at IS NULL
+                AND blocking.dead_at IS NULL
+                AND blocking.available_at > now()
+        )
+    ORDER BY e.id
+    LIMIT sqlc.arg(batch_size)
+)
+RETURNING *;
+
+-- name: DeleteSettledOutboxEvents :execrows
+-- Deletes events that were dispatched or given up on more than
+-- retention_seconds ago.
+DELETE FROM outbox_events
+WHERE dispatched_at < now() - make_interval(secs => sqlc.arg(retention_seconds)::int)
+    OR dead_at < now() - make_interval(secs => sqlc.arg(retention_seconds)::int);
+
+-- name: MarkOutboxEventDispatched :exec
+UPDATE outbox_events
+SET dispatched_at = now(), attempts = attempts + 1, available_at = NULL, last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :one
+-- Once an event has used up max_attempts it is dead: it stays in the table
+-- with its last error but is no longer retried, and the aggregate's later
+-- events go ahead.
+UPDATE outbox_events
+SET attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    available_at = now() + make_interval(secs => sqlc.arg(retry_seconds)::int),
+    dead_at = CASE WHEN attempts + 1 >= max_attempts THEN now() END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ReleaseOutboxEvent :exec
+UPDATE outbox_events
+SET available_at = NULL
+WHERE id = $1;
+
+-- name: TryOutboxDispatchLock :one
+SELECT pg_try_advisory_xact_lock($1);
@@ -0,0 +1,50 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    sqlc.arg(user_id),
+    sqlc.arg(name),
+    sqlc.arg(token_hash),
+    sqlc.arg(scopes),
+    now(),
+    now() + make_interval(days => sqlc.narg(expires_in_days)::int)
+)
+RETURNING *;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT
+    id,
+    user_id,
+    name,
+    token_hash,
+    scopes,
+    created_at,
+    expires_at,
+    last_used_at
+FROM personal_access_tokens
+WHERE token_hash = $1;
+
+-- name: ListPersonalAccessTokens :many
+SELECT
+    id,
+    user_id,
+    name,
+    token_hash,
+    scopes,
+    created_at,
+    expires_at,
+    last_used_at
+FROM personal_access_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeletePersonalAccessToken :execrows
+DELETE
+FROM personal_access_tokens
+WHERE id = $1 AND user_id = $2;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = now()
+WHERE id = $1
+    AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
@@ -0,0 +1,24 @@
+-- name: CreateRateLimitBucket :exec
+-- Starts a bucket for key at full burst unless it already has one, so that
+-- TakeRateLimitToken always finds a row to lock.
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at, full_at)
+VALUES (sqlc.arg(key), sqlc.arg(burst)::float8, true, now(), now())
+ON CONFLICT (key) DO NOTHING;
+
+-- name: TakeRateLimitToken :one
+-- Refills the bucket for the time since it was last used and takes a token if
+-- a whole one is available. The SET expressions read the row as locked by the
+-- UPDATE, so concurrent requests for the same key, on any instance, take
+-- their tokens one after another. Returns no rows if the bucket is missing.
+UPDATE rate_limit_buckets
+SET
+    tokens = LEAST(sqlc.arg(burst)::float8, tokens + EXTRACT(EPOCH FROM now() - updated_at) * sqlc.arg(rate_per_second)::float8) - (LEAST(sqlc.arg(burst)::float8, tokens + EXTRACT(EPOCH FROM now() - updated_at) * sqlc.arg(rate_per_second)::float8) >= 1)::int,
+    allowed = LEAST(sqlc.arg(burst)::float8, tokens + EXTRACT(EPOCH FROM now() - updated_at) * sqlc.arg(rate_per_second)::float8) >= 1,
+    updated_at = now(),
+    full_at = now() + make_interval(secs => (sqlc.arg(burst)::float8 - LEAST(sqlc.arg(burst)::float8, tokens + EXTRACT(EPOCH FROM now() - updated_at) * sqlc.arg(rate_per_second)::float8) + (LEAST(sqlc.arg(burst)::float8, tokens + EXTRACT(EPOCH FROM now() - updated_at) * sqlc.arg(rate_per_second)::float8) >= 1)::int) / sqlc.arg(rate_per_second)::float8)
+WHERE key = sqlc.arg(key)
+RETURNING tokens, allowed;
+
+-- name: DeleteFullRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE full_at < now();
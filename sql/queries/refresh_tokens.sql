@@ -1,12 +1,14 @@
 -- name: CreateToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes)
 VALUES (
     $1,
     now(),
     now(),
     $2,
     now() + interval '60 days',
-    NULL
+    NULL,
+    $3,
+    $4
 )
 RETURNING *;
 
@@ -17,7 +19,9 @@ SELECT
     updated_at,
     user_id,
     expires_at,
-    revoked_at
+    revoked_at,
+    client_id,
+    scopes
 FROM refresh_tokens
 WHERE token = $1;
 
@@ -26,3 +30,13 @@ UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
 WHERE token = $1
 RETURNING *;
+
+-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: DeleteStaleTokens :execrows
+DELETE
+FROM refresh_tokens
+WHERE expires_at < now() OR revoked_at IS NOT NULL;
@@ -0,0 +1,128 @@
+-- name: ClaimReportCase :one
+UPDATE report_cases
+SET
+    claimed_by = sqlc.arg(moderator_id),
+    claimed_at = now(),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+    AND resolved_at IS NULL
+    AND (claimed_by IS NULL OR claimed_by = sqlc.arg(moderator_id))
+RETURNING *;
+
+-- name: CreateReport :one
+INSERT INTO reports (id, case_id, chirp_id, reporter_id, reason, details, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    now()
+)
+RETURNING *;
+
+-- name: CreateReportCase :one
+INSERT INTO report_cases (id, chirp_id, author_id, flag_reason, hid_chirp, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    now(),
+    now()
+)
+RETURNING *;
+
+-- name: GetReportCase :one
+SELECT *
+FROM report_cases
+WHERE id = $1;
+
+-- name: IncrementReportCount :one
+UPDATE report_cases
+SET
+    report_count = report_count + 1,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListCaseReports :many
+SELECT *
+FROM reports
+WHERE case_id = $1
+ORDER BY created_at;
+
+-- name: ListOpenReportCases :many
+SELECT *
+FROM report_cases
+WHERE resolved_at IS NULL
+ORDER BY report_count DESC, created_at
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListReporterReports :many
+SELECT *
+FROM reports
+WHERE reporter_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListResolvedReportCases :many
+SELECT *
+FROM report_cases
+WHERE resolved_at IS NOT NULL
+ORDER BY resolved_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: MarkReportCaseHidChirp :one
+UPDATE report_cases
+SET
+    hid_chirp = true,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: OpenReportCase :one
+INSERT INTO report_cases (id, chirp_id, author_id, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    now(),
+    now()
+)
+ON CONFLICT (chirp_id) WHERE resolved_at IS NULL
+DO UPDATE SET updated_at = report_cases.updated_at
+RETURNING *;
+
+-- name: ReleaseReportCase :one
+UPDATE report_cases
+SET
+    claimed_by = NULL,
+    claimed_at = NULL,
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+    AND resolved_at IS NULL
+    AND claimed_by = sqlc.arg(moderator_id)
+RETURNING *;
+
+-- name: ResolveReportCase :one
+UPDATE report_cases
+SET
+    resolution = sqlc.arg(resolution),
+    resolution_note = sqlc.arg(resolution_note),
+    resolved_by = sqlc.arg(moderator_id),
+    resolved_at = now(),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+    AND resolved_at IS NULL
+    AND (claimed_by IS NULL OR claimed_by = sqlc.arg(moderator_id))
+RETURNING *;
+
+-- name: ResolveReports :many
+UPDATE reports
+SET
+    resolution = $1,
+    resolved_at = now()
+WHERE case_id = $2
+RETURNING *;
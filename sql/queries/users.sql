@@ -19,10 +19,29 @@ SELECT
     updated_at,
     email,
     hashed_password,
-    is_chirpy_red
+    is_chirpy_red,
+    role,
+    suspended_until,
+    banned_at,
+    shadowbanned_at
 FROM users
 WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT
+    id,
+    created_at,
+    updated_at,
+    email,
+    hashed_password,
+    is_chirpy_red,
+    role,
+    suspended_until,
+    banned_at,
+    shadowbanned_at
+FROM users
+WHERE id = $1;
+
 -- name: UpdateUserCreds :one
 UPDATE users
 SET 
@@ -36,3 +55,11 @@ RETURNING *;
 UPDATE users
 SET is_chirpy_red = true
 WHERE id = $1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET
+    role = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING *;
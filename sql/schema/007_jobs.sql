@@ -0,0 +1,20 @@
+-- +goose up
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY,
+    kind VARCHAR NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at TIMESTAMP NOT NULL,
+    unique_key VARCHAR UNIQUE,
+    locked_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX jobs_runnable_idx ON jobs (run_at) WHERE status = 'pending';
+
+-- +goose down
+DROP TABLE jobs;
@@ -0,0 +1,13 @@
+-- +goose up
+CREATE TABLE rate_limit_buckets (
+    key VARCHAR PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    full_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_full_at_idx ON rate_limit_buckets (full_at);
+
+-- +goose down
+DROP TABLE rate_limit_buckets;
@@ -0,0 +1,26 @@
+-- +goose up
+ALTER TABLE users
+    ADD COLUMN suspended_until TIMESTAMP,
+    ADD COLUMN banned_at TIMESTAMP,
+    ADD COLUMN shadowbanned_at TIMESTAMP;
+
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL
+        CHECK (action IN ('suspend', 'unsuspend', 'ban', 'unban', 'shadowban', 'unshadowban')),
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX moderation_actions_user_id_idx ON moderation_actions (user_id);
+
+-- +goose down
+DROP TABLE moderation_actions;
+
+ALTER TABLE users
+    DROP COLUMN shadowbanned_at,
+    DROP COLUMN banned_at,
+    DROP COLUMN suspended_until;
@@ -0,0 +1,55 @@
+-- +goose up
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+ALTER TABLE moderation_actions
+    DROP CONSTRAINT moderation_actions_action_check,
+    ADD CONSTRAINT moderation_actions_action_check
+        CHECK (action IN ('suspend', 'unsuspend', 'ban', 'unban', 'shadowban', 'unshadowban', 'warn'));
+
+CREATE TABLE report_cases (
+    id UUID PRIMARY KEY,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    report_count INTEGER NOT NULL DEFAULT 0,
+    claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMP,
+    resolution TEXT
+        CHECK (resolution IN ('dismiss', 'hide_chirp', 'remove_chirp', 'warn_author')),
+    resolution_note TEXT NOT NULL DEFAULT '',
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Reports on a chirp collect in its one open case.
+CREATE UNIQUE INDEX report_cases_open_chirp_idx ON report_cases (chirp_id) WHERE resolved_at IS NULL;
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    case_id UUID NOT NULL REFERENCES report_cases(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL
+        CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    resolution TEXT,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    UNIQUE (chirp_id, reporter_id)
+);
+
+CREATE INDEX reports_case_id_idx ON reports (case_id);
+CREATE INDEX reports_reporter_id_idx ON reports (reporter_id);
+
+-- +goose down
+DROP TABLE reports;
+DROP TABLE report_cases;
+
+DELETE FROM moderation_actions WHERE action = 'warn';
+ALTER TABLE moderation_actions
+    DROP CONSTRAINT moderation_actions_action_check,
+    ADD CONSTRAINT moderation_actions_action_check
+        CHECK (action IN ('suspend', 'unsuspend', 'ban', 'unban', 'shadowban', 'unshadowban'));
+
+ALTER TABLE chirps DROP COLUMN hidden_at;
@@ -0,0 +1,5 @@
+-- +goose up
+CREATE INDEX chirps_user_id_created_at_idx ON chirps (user_id, created_at);
+
+-- +goose down
+DROP INDEX chirps_user_id_created_at_idx;
@@ -0,0 +1,19 @@
+-- +goose up
+ALTER TABLE outbox_events
+    ADD COLUMN max_attempts INTEGER NOT NULL DEFAULT 10,
+    ADD COLUMN available_at TIMESTAMP,
+    ADD COLUMN dead_at TIMESTAMP;
+
+DROP INDEX outbox_events_pending_idx;
+CREATE INDEX outbox_events_pending_idx ON outbox_events (id) WHERE dispatched_at IS NULL AND dead_at IS NULL;
+CREATE INDEX outbox_events_pending_aggregate_idx ON outbox_events (aggregate_id, available_at) WHERE dispatched_at IS NULL AND dead_at IS NULL;
+
+-- +goose down
+DROP INDEX outbox_events_pending_aggregate_idx;
+DROP INDEX outbox_events_pending_idx;
+CREATE INDEX outbox_events_pending_idx ON outbox_events (id) WHERE dispatched_at IS NULL;
+
+ALTER TABLE outbox_events
+    DROP COLUMN dead_at,
+    DROP COLUMN available_at,
+    DROP COLUMN max_attempts;
@@ -0,0 +1,18 @@
+-- +goose up
+-- A user reports a chirp once per case rather than once ever, so they can
+-- report it again after a moderator has resolved their earlier report.
+ALTER TABLE reports
+    DROP CONSTRAINT reports_chirp_id_reporter_id_key,
+    ADD CONSTRAINT reports_case_id_reporter_id_key UNIQUE (case_id, reporter_id);
+
+-- +goose down
+-- Only each user's first report on a chirp survives the old constraint.
+DELETE FROM reports r
+USING reports earlier
+WHERE earlier.chirp_id = r.chirp_id
+    AND earlier.reporter_id = r.reporter_id
+    AND earlier.created_at < r.created_at;
+
+ALTER TABLE reports
+    DROP CONSTRAINT reports_case_id_reporter_id_key,
+    ADD CONSTRAINT reports_chirp_id_reporter_id_key UNIQUE (chirp_id, reporter_id);
@@ -0,0 +1,79 @@
+-- name: CreateChirp :one
+INSERT INTO chirps (id, user_id, body, created_at, updated_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING *;
+
+-- name: GetChirps :many
+SELECT
+    chirps.id,
+    chirps.user_id,
+    chirps.body,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.hidden_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE (users.shadowbanned_at IS NULL AND chirps.hidden_at IS NULL)
+    OR chirps.user_id = sqlc.narg(viewer_id)
+ORDER BY chirps.created_at;
+
+-- name: GetChirp :one
+SELECT
+    id,
+    user_id,
+    body,
+    created_at,
+    updated_at,
+    hidden_at
+FROM chirps
+WHERE id = ?;
+
+-- name: DeleteChirp :exec
+DELETE
+FROM chirps
+WHERE id = ?;
+
+-- name: GetChirpsByAuthor :many
+SELECT
+    chirps.id,
+    chirps.user_id,
+    chirps.body,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.hidden_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = sqlc.arg(user_id)
+    AND ((users.shadowbanned_at IS NULL AND chirps.hidden_at IS NULL)
+        OR chirps.user_id = sqlc.narg(viewer_id))
+ORDER BY chirps.created_at;
+
+-- name: GetRecentChirpsByAuthor :many
+SELECT
+    id,
+    user_id,
+    body,
+    created_at,
+    updated_at,
+    hidden_at
+FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+    AND created_at > strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || CAST(sqlc.arg(window_seconds) AS INTEGER) || ' seconds')
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+WHERE id = ?;
+
+-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = ?;
@@ -0,0 +1,73 @@
+-- name: SuspendUser :one
+UPDATE users
+SET
+    suspended_until = strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || CAST(sqlc.arg(hours) AS INTEGER) || ' hours'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET
+    suspended_until = NULL,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: BanUser :one
+UPDATE users
+SET
+    banned_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: UnbanUser :one
+UPDATE users
+SET
+    banned_at = NULL,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: ShadowbanUser :one
+UPDATE users
+SET
+    shadowbanned_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: UnshadowbanUser :one
+UPDATE users
+SET
+    shadowbanned_at = NULL,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, user_id, moderator_id, action, reason, expires_at, created_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING *;
+
+-- name: ListModerationActions :many
+SELECT
+    id,
+    user_id,
+    moderator_id,
+    action,
+    reason,
+    expires_at,
+    created_at
+FROM moderation_actions
+WHERE user_id = ?
+ORDER BY created_at DESC;
@@ -0,0 +1,23 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, user_id, kind, payload, created_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT *
+FROM notifications
+WHERE user_id = ?
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: MarkNotificationRead :one
+UPDATE notifications
+SET read_at = COALESCE(read_at, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+WHERE id = ? AND user_id = ?
+RETURNING *;
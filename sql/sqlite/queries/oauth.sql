@@ -0,0 +1,64 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, user_id, name, secret_hash, redirect_uris, created_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT
+    id,
+    user_id,
+    name,
+    secret_hash,
+    redirect_uris,
+    created_at
+FROM oauth_clients
+WHERE id = ?;
+
+-- name: ListOAuthClients :many
+SELECT
+    id,
+    user_id,
+    name,
+    secret_hash,
+    redirect_uris,
+    created_at
+FROM oauth_clients
+WHERE user_id = ?
+ORDER BY created_at DESC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE
+FROM oauth_clients
+WHERE id = ? AND user_id = ?;
+
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, created_at, expires_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now', '+10 minutes')
+)
+RETURNING *;
+
+-- name: ConsumeOAuthAuthorizationCode :one
+DELETE
+FROM oauth_authorization_codes
+WHERE code_hash = ?
+RETURNING *;
+
+-- name: DeleteExpiredOAuthAuthorizationCodes :execrows
+DELETE
+FROM oauth_authorization_codes
+WHERE expires_at < strftime('%Y-%m-%d %H:%M:%f', 'now');
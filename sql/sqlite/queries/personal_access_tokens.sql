@@ -0,0 +1,50 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(user_id),
+    sqlc.arg(name),
+    sqlc.arg(token_hash),
+    sqlc.arg(scopes),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || CAST(sqlc.narg(expires_in_days) AS INTEGER) || ' days')
+)
+RETURNING *;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT
+    id,
+    user_id,
+    name,
+    token_hash,
+    scopes,
+    created_at,
+    expires_at,
+    last_used_at
+FROM personal_access_tokens
+WHERE token_hash = ?;
+
+-- name: ListPersonalAccessTokens :many
+SELECT
+    id,
+    user_id,
+    name,
+    token_hash,
+    scopes,
+    created_at,
+    expires_at,
+    last_used_at
+FROM personal_access_tokens
+WHERE user_id = ?
+ORDER BY created_at DESC;
+
+-- name: DeletePersonalAccessToken :execrows
+DELETE
+FROM personal_access_tokens
+WHERE id = ? AND user_id = ?;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+    AND (last_used_at IS NULL OR last_used_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-1 minute'));
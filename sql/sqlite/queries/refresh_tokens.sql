@@ -0,0 +1,42 @@
+-- name: CreateToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes)
+VALUES (
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now', '+60 days'),
+    NULL,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetToken :one
+SELECT
+    token,
+    created_at,
+    updated_at,
+    user_id,
+    expires_at,
+    revoked_at,
+    client_id,
+    scopes
+FROM refresh_tokens
+WHERE token = ?;
+
+-- name: RevokeToken :one
+UPDATE refresh_tokens
+SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE token = ?
+RETURNING *;
+
+-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE user_id = ? AND revoked_at IS NULL;
+
+-- name: DeleteStaleTokens :execrows
+DELETE
+FROM refresh_tokens
+WHERE expires_at < strftime('%Y-%m-%d %H:%M:%f', 'now') OR revoked_at IS NOT NULL;
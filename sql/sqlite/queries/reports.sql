@@ -0,0 +1,128 @@
+-- name: ClaimReportCase :one
+UPDATE report_cases
+SET
+    claimed_by = sqlc.arg(moderator_id),
+    claimed_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = sqlc.arg(id)
+    AND resolved_at IS NULL
+    AND (claimed_by IS NULL OR claimed_by = sqlc.arg(moderator_id))
+RETURNING *;
+
+-- name: CreateReport :one
+INSERT INTO reports (id, case_id, chirp_id, reporter_id, reason, details, created_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING *;
+
+-- name: CreateReportCase :one
+INSERT INTO report_cases (id, chirp_id, author_id, flag_reason, hid_chirp, created_at, updated_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING *;
+
+-- name: GetReportCase :one
+SELECT *
+FROM report_cases
+WHERE id = ?;
+
+-- name: IncrementReportCount :one
+UPDATE report_cases
+SET
+    report_count = report_count + 1,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: ListCaseReports :many
+SELECT *
+FROM reports
+WHERE case_id = ?
+ORDER BY created_at;
+
+-- name: ListOpenReportCases :many
+SELECT *
+FROM report_cases
+WHERE resolved_at IS NULL
+ORDER BY report_count DESC, created_at
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListReporterReports :many
+SELECT *
+FROM reports
+WHERE reporter_id = ?
+ORDER BY created_at DESC;
+
+-- name: ListResolvedReportCases :many
+SELECT *
+FROM report_cases
+WHERE resolved_at IS NOT NULL
+ORDER BY resolved_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: MarkReportCaseHidChirp :one
+UPDATE report_cases
+SET
+    hid_chirp = true,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: OpenReportCase :one
+INSERT INTO report_cases (id, chirp_id, author_id, created_at, updated_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+ON CONFLICT (chirp_id) WHERE resolved_at IS NULL
+DO UPDATE SET updated_at = report_cases.updated_at
+RETURNING *;
+
+-- name: ReleaseReportCase :one
+UPDATE report_cases
+SET
+    claimed_by = NULL,
+    claimed_at = NULL,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = sqlc.arg(id)
+    AND resolved_at IS NULL
+    AND claimed_by = sqlc.arg(moderator_id)
+RETURNING *;
+
+-- name: ResolveReportCase :one
+UPDATE report_cases
+SET
+    resolution = sqlc.arg(resolution),
+    resolution_note = sqlc.arg(resolution_note),
+    resolved_by = sqlc.arg(moderator_id),
+    resolved_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = sqlc.arg(id)
+    AND resolved_at IS NULL
+    AND (claimed_by IS NULL OR claimed_by = sqlc.arg(moderator_id))
+RETURNING *;
+
+-- name: ResolveReports :many
+UPDATE reports
+SET
+    resolution = ?,
+    resolved_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE case_id = ?
+RETURNING *;
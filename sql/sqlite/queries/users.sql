@@ -0,0 +1,65 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+VALUES (
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: DeleteUsers :exec
+DELETE FROM users;
+
+-- name: GetUser :one
+SELECT
+    id,
+    created_at,
+    updated_at,
+    email,
+    hashed_password,
+    is_chirpy_red,
+    role,
+    suspended_until,
+    banned_at,
+    shadowbanned_at
+FROM users
+WHERE email = ?;
+
+-- name: GetUserByID :one
+SELECT
+    id,
+    created_at,
+    updated_at,
+    email,
+    hashed_password,
+    is_chirpy_red,
+    role,
+    suspended_until,
+    banned_at,
+    shadowbanned_at
+FROM users
+WHERE id = ?;
+
+-- name: UpdateUserCreds :one
+UPDATE users
+SET
+    email = ?,
+    hashed_password = ?,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: UpdateUserChirpyRedStatus :exec
+UPDATE users
+SET is_chirpy_red = true
+WHERE id = ?;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET
+    role = ?,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
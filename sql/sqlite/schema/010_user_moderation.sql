@@ -0,0 +1,24 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN banned_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN shadowbanned_at TIMESTAMP;
+
+CREATE TABLE moderation_actions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    moderator_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL
+        CHECK (action IN ('suspend', 'unsuspend', 'ban', 'unban', 'shadowban', 'unshadowban')),
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX moderation_actions_user_id_idx ON moderation_actions (user_id);
+
+-- +goose down
+DROP TABLE moderation_actions;
+
+ALTER TABLE users DROP COLUMN shadowbanned_at;
+ALTER TABLE users DROP COLUMN banned_at;
+ALTER TABLE users DROP COLUMN suspended_until;
@@ -0,0 +1,76 @@
+-- +goose up
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+-- SQLite can't alter a check constraint, so the table is rebuilt to allow
+-- warnings.
+CREATE TABLE moderation_actions_new (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    moderator_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL
+        CHECK (action IN ('suspend', 'unsuspend', 'ban', 'unban', 'shadowban', 'unshadowban', 'warn')),
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+INSERT INTO moderation_actions_new SELECT * FROM moderation_actions;
+DROP TABLE moderation_actions;
+ALTER TABLE moderation_actions_new RENAME TO moderation_actions;
+CREATE INDEX moderation_actions_user_id_idx ON moderation_actions (user_id);
+
+CREATE TABLE report_cases (
+    id TEXT PRIMARY KEY,
+    chirp_id TEXT REFERENCES chirps(id) ON DELETE SET NULL,
+    author_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    report_count INTEGER NOT NULL DEFAULT 0,
+    claimed_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMP,
+    resolution TEXT
+        CHECK (resolution IN ('dismiss', 'hide_chirp', 'remove_chirp', 'warn_author')),
+    resolution_note TEXT NOT NULL DEFAULT '',
+    resolved_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Reports on a chirp collect in its one open case.
+CREATE UNIQUE INDEX report_cases_open_chirp_idx ON report_cases (chirp_id) WHERE resolved_at IS NULL;
+
+CREATE TABLE reports (
+    id TEXT PRIMARY KEY,
+    case_id TEXT NOT NULL REFERENCES report_cases(id) ON DELETE CASCADE,
+    chirp_id TEXT REFERENCES chirps(id) ON DELETE SET NULL,
+    reporter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL
+        CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    resolution TEXT,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    UNIQUE (chirp_id, reporter_id)
+);
+
+CREATE INDEX reports_case_id_idx ON reports (case_id);
+CREATE INDEX reports_reporter_id_idx ON reports (reporter_id);
+
+-- +goose down
+DROP TABLE reports;
+DROP TABLE report_cases;
+
+CREATE TABLE moderation_actions_old (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    moderator_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL
+        CHECK (action IN ('suspend', 'unsuspend', 'ban', 'unban', 'shadowban', 'unshadowban')),
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+INSERT INTO moderation_actions_old SELECT * FROM moderation_actions WHERE action <> 'warn';
+DROP TABLE moderation_actions;
+ALTER TABLE moderation_actions_old RENAME TO moderation_actions;
+CREATE INDEX moderation_actions_user_id_idx ON moderation_actions (user_id);
+
+ALTER TABLE chirps DROP COLUMN hidden_at;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE report_cases ADD COLUMN flag_reason TEXT NOT NULL DEFAULT '';
+
+-- +goose down
+ALTER TABLE report_cases DROP COLUMN flag_reason;
@@ -0,0 +1,13 @@
+-- +goose up
+-- Records whether a case hid its chirp, so dismissing the case only puts back
+-- chirps it hid itself. Open cases on hidden chirps are assumed to have hidden
+-- them, which is what dismissing them used to undo.
+ALTER TABLE report_cases ADD COLUMN hid_chirp BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE report_cases
+SET hid_chirp = true
+WHERE resolved_at IS NULL
+    AND chirp_id IN (SELECT id FROM chirps WHERE hidden_at IS NOT NULL);
+
+-- +goose down
+ALTER TABLE report_cases DROP COLUMN hid_chirp;
@@ -0,0 +1,43 @@
+-- +goose up
+-- A user reports a chirp once per case rather than once ever, so they can
+-- report it again after a moderator has resolved their earlier report.
+-- SQLite can't drop a table constraint, so the table is rebuilt.
+CREATE TABLE reports_new (
+    id TEXT PRIMARY KEY,
+    case_id TEXT NOT NULL REFERENCES report_cases(id) ON DELETE CASCADE,
+    chirp_id TEXT REFERENCES chirps(id) ON DELETE SET NULL,
+    reporter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL
+        CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    resolution TEXT,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    UNIQUE (case_id, reporter_id)
+);
+INSERT INTO reports_new SELECT * FROM reports;
+DROP TABLE reports;
+ALTER TABLE reports_new RENAME TO reports;
+CREATE INDEX reports_case_id_idx ON reports (case_id);
+CREATE INDEX reports_reporter_id_idx ON reports (reporter_id);
+
+-- +goose down
+CREATE TABLE reports_old (
+    id TEXT PRIMARY KEY,
+    case_id TEXT NOT NULL REFERENCES report_cases(id) ON DELETE CASCADE,
+    chirp_id TEXT REFERENCES chirps(id) ON DELETE SET NULL,
+    reporter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL
+        CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    resolution TEXT,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    UNIQUE (chirp_id, reporter_id)
+);
+-- Only each user's first report on a chirp survives the old constraint.
+INSERT OR IGNORE INTO reports_old SELECT * FROM reports ORDER BY created_at;
+DROP TABLE reports;
+ALTER TABLE reports_old RENAME TO reports;
+CREATE INDEX reports_case_id_idx ON reports (case_id);
+CREATE INDEX reports_reporter_id_idx ON reports (reporter_id);
@@ -0,0 +1,7 @@
+// Package schema embeds the SQLite migrations so the binary can apply them.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/d-shames3/chirpy/internal/apierror"
+	"github.com/d-shames3/chirpy/internal/auth"
+	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+type personalAccessTokenParams struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// ExpiresInDays of zero means the token never expires.
+	ExpiresInDays int `json:"expires_in_days" validate:"min=0,max=365"`
+}
+
+type personalAccessTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Token is only sent when the token is created.
+	Token string `json:"token,omitempty"`
+}
+
+var errTokenNotFound = apierror.NotFound("token not found")
+
+func newPersonalAccessTokenResponse(t database.PersonalAccessToken) personalAccessTokenResponse {
+	resp := personalAccessTokenResponse{
+		ID:        t.ID,
+		Name:      t.Name,
+		Scopes:    strings.Fields(t.Scopes),
+		CreatedAt: t.CreatedAt,
+	}
+	if t.ExpiresAt.Valid {
+		resp.ExpiresAt = &t.ExpiresAt.Time
+	}
+	if t.LastUsedAt.Valid {
+		resp.LastUsedAt = &t.LastUsedAt.Time
+	}
+	return resp
+}
+
+func (cfg *apiConfig) createPersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	params := personalAccessTokenParams{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	for _, scope := range params.Scopes {
+		if !slices.Contains(auth.GrantableScopes, scope) {
+			respondWithError(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "scopes",
+				Code:    "one_of",
+				Message: "scopes must each be one of " + strings.Join(auth.GrantableScopes, ", "),
+			}))
+			return
+		}
+	}
+	slices.Sort(params.Scopes)
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithError(w, r, apierror.Internal(err))
+		return
+	}
+
+	pat, err := cfg.store.PersonalAccessTokens().CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:        principal(r).UserID,
+		Name:          params.Name,
+		TokenHash:     auth.HashToken(token),
+		Scopes:        strings.Join(slices.Compact(params.Scopes), " "),
+		ExpiresInDays: sql.NullInt32{Int32: int32(params.ExpiresInDays), Valid: params.ExpiresInDays > 0},
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	resp := newPersonalAccessTokenResponse(pat)
+	resp.Token = token
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) listPersonalAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	pats, err := cfg.store.PersonalAccessTokens().ListPersonalAccessTokens(r.Context(), principal(r).UserID)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	resp := make([]personalAccessTokenResponse, 0, len(pats))
+	for _, pat := range pats {
+		resp = append(resp, newPersonalAccessTokenResponse(pat))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) deletePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	tokenId, err := uuid.Parse(r.PathValue("tokenId"))
+	if err != nil {
+		respondWithError(w, r, apierror.BadRequest("malformed token id"))
+		return
+	}
+
+	err = cfg.store.PersonalAccessTokens().DeletePersonalAccessToken(r.Context(), database.DeletePersonalAccessTokenParams{
+		ID:     tokenId,
+		UserID: principal(r).UserID,
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errTokenNotFound.Wrap(err))
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,18 +1,34 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/d-shames3/chirpy/internal/apierror"
 	"github.com/d-shames3/chirpy/internal/auth"
 	"github.com/d-shames3/chirpy/internal/database"
+	"github.com/d-shames3/chirpy/internal/events"
+	"github.com/d-shames3/chirpy/internal/metrics"
+	"github.com/d-shames3/chirpy/internal/store"
 	"github.com/google/uuid"
 )
 
 type userParams struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// loginParams only requires the fields so accounts created before the
+// password rules existed can still log in.
+type loginParams struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	// UseCookies starts a browser session: the tokens are set as cookies
+	// instead of being returned.
+	UseCookies bool `json:"use_cookies"`
 }
 
 type userData struct {
@@ -21,36 +37,37 @@ type userData struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	Role         string    `json:"role"`
 	Token        string    `json:"token,omitempty"`
 	RefreshToken string    `json:"refresh_token,omitempty"`
+	CSRFToken    string    `json:"csrf_token,omitempty"`
 }
 
-const defaultExpiresinSeconds = 3600
+var (
+	errInvalidJSON          = apierror.BadRequest("request body is not valid JSON")
+	errInvalidToken         = apierror.Unauthenticated("invalid or expired access token")
+	errInvalidRefreshToken  = apierror.Unauthenticated("invalid refresh token")
+	errIncorrectCredentials = apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Incorrect email or password")
+	errEmailTaken           = apierror.Conflict("email is already registered")
+)
+
+type userCreatedEvent struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
 
 func (cfg *apiConfig) updateUserCredsHandler(w http.ResponseWriter, r *http.Request) {
 	userParams := userParams{}
-	defer r.Body.Close()
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&userParams); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if err := decodeJSON(w, r, &userParams); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
-		return
-	}
+	userId := principal(r).UserID
 
-	userId, err := auth.ValidateJWT(token, cfg.serverSecret)
+	hashedPassword, err := auth.HashPassword(r.Context(), userParams.Password)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
-		return
-	}
-
-	hashedPassword, err := auth.HashPassword(userParams.Password)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, apierror.Internal(err))
 		return
 	}
 
@@ -60,9 +77,13 @@ func (cfg *apiConfig) updateUserCredsHandler(w http.ResponseWriter, r *http.Requ
 		ID:             userId,
 	}
 
-	user, err := cfg.db.UpdateUserCreds(r.Context(), updateUserCredsParams)
+	user, err := cfg.store.Users().UpdateUserCreds(r.Context(), updateUserCredsParams)
+	if errors.Is(err, store.ErrDuplicate) {
+		respondWithError(w, r, errEmailTaken.Wrap(err))
+		return
+	}
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -72,21 +93,31 @@ func (cfg *apiConfig) updateUserCredsHandler(w http.ResponseWriter, r *http.Requ
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		IsChirpyRed: user.IsChirpyRed,
+		Role:        user.Role,
 	}
 
 	respondWithJSON(w, http.StatusOK, userData)
 }
 
 func (cfg *apiConfig) revokeTokenHandler(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
+	token, fromCookie, err := requestRefreshToken(r)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
+	if fromCookie {
+		// Logging out of a browser session always clears its cookies, even
+		// if the token was already gone.
+		cfg.endSession(w)
+	}
 
-	_, err = cfg.db.RevokeToken(r.Context(), token)
+	_, err = cfg.store.RefreshTokens().RevokeToken(r.Context(), token)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errInvalidRefreshToken.Wrap(err))
+		return
+	}
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -95,31 +126,58 @@ func (cfg *apiConfig) revokeTokenHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (cfg *apiConfig) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
+	token, fromCookie, err := requestRefreshToken(r)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
-	refreshTokenData, err := cfg.db.GetToken(r.Context(), token)
+	refreshTokenData, err := cfg.store.RefreshTokens().GetToken(r.Context(), token)
+	if errors.Is(err, store.ErrNotFound) {
+		respondWithError(w, r, errInvalidRefreshToken.Wrap(err))
+		return
+	}
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
 	if refreshTokenData.RevokedAt.Valid {
-		respondWithError(w, http.StatusUnauthorized, "Refresh token has been revoked")
+		respondWithError(w, r, apierror.Unauthenticated("Refresh token has been revoked"))
 		return
 	}
 
 	if time.Now().UTC().After(refreshTokenData.ExpiresAt) {
-		respondWithError(w, http.StatusUnauthorized, "Refresh token is expired")
+		respondWithError(w, r, apierror.Unauthenticated("Refresh token is expired"))
 		return
 	}
 
-	authToken, err := auth.MakeJWT(refreshTokenData.UserID, cfg.serverSecret, time.Duration(defaultExpiresinSeconds)*time.Second)
+	// Tokens issued to OAuth clients carry scopes and are refreshed at
+	// /oauth/token, never exchanged here for an unrestricted token.
+	if refreshTokenData.ClientID.Valid {
+		respondWithError(w, r, errInvalidRefreshToken)
+		return
+	}
+
+	user, err := cfg.store.Users().GetUserByID(r.Context(), refreshTokenData.UserID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, err)
+		return
+	}
+	if err := checkStanding(user); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	authToken, err := auth.MakeSessionJWT(refreshTokenData.UserID, auth.SessionID(token), cfg.serverSecret, cfg.accessTokenTTL)
+	if err != nil {
+		respondWithError(w, r, apierror.Internal(err))
+		return
+	}
+
+	if fromCookie {
+		cfg.setAccessCookie(w, authToken)
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
@@ -131,40 +189,27 @@ func (cfg *apiConfig) refreshTokenHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
-	userParams := userParams{}
-	defer r.Body.Close()
-	decoder := json.NewDecoder((r.Body))
-	if err := decoder.Decode(&userParams); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	user, err := cfg.db.GetUser(r.Context(), userParams.Email)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+	userParams := loginParams{}
+	if err := decodeJSON(w, r, &userParams); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	match, err := auth.CheckPasswordHash(userParams.Password, user.HashedPassword)
+	user, err := cfg.checkCredentials(r.Context(), userParams.Email, userParams.Password)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
-	if !match {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
-		return
-	}
-
-	authToken, err := auth.MakeJWT(user.ID, cfg.serverSecret, time.Duration(defaultExpiresinSeconds)*time.Second)
+	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		respondWithError(w, r, apierror.Internal(err))
 		return
 	}
 
-	refreshToken, err := auth.MakeRefreshToken()
+	authToken, err := auth.MakeSessionJWT(user.ID, auth.SessionID(refreshToken), cfg.serverSecret, cfg.accessTokenTTL)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		respondWithError(w, r, apierror.Internal(err))
 		return
 	}
 
@@ -173,9 +218,9 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		Token:  refreshToken,
 	}
 
-	refreshTokenData, err := cfg.db.CreateToken(r.Context(), createTokenParams)
+	refreshTokenData, err := cfg.store.RefreshTokens().CreateToken(r.Context(), createTokenParams)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -185,24 +230,60 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:    user.UpdatedAt,
 		Email:        user.Email,
 		IsChirpyRed:  user.IsChirpyRed,
+		Role:         user.Role,
 		Token:        authToken,
 		RefreshToken: refreshTokenData.Token,
 	}
+	if userParams.UseCookies {
+		userData.CSRFToken, err = cfg.startSession(w, authToken, refreshTokenData)
+		if err != nil {
+			respondWithError(w, r, apierror.Internal(err))
+			return
+		}
+		userData.Token, userData.RefreshToken = "", ""
+	}
 	respondWithJSON(w, http.StatusOK, userData)
 }
 
+// checkCredentials returns the user with email if password is theirs, and
+// errIncorrectCredentials otherwise.
+func (cfg *apiConfig) checkCredentials(ctx context.Context, email, password string) (database.User, error) {
+	user, err := cfg.store.Users().GetUser(ctx, email)
+	if errors.Is(err, store.ErrNotFound) {
+		cfg.metrics.Login(metrics.LoginFailure)
+		return database.User{}, errIncorrectCredentials.Wrap(err)
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+
+	match, err := auth.CheckPasswordHash(ctx, password, user.HashedPassword)
+	if err != nil {
+		return database.User{}, apierror.Internal(err)
+	}
+	if !match {
+		cfg.metrics.Login(metrics.LoginFailure)
+		return database.User{}, errIncorrectCredentials
+	}
+	if err := checkStanding(user); err != nil {
+		cfg.metrics.Login(metrics.LoginFailure)
+		return database.User{}, err
+	}
+	cfg.metrics.Login(metrics.LoginSuccess)
+	return user, nil
+}
+
 func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request) {
 	userParams := userParams{}
-	defer r.Body.Close()
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&userParams); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if err := decodeJSON(w, r, &userParams); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(userParams.Password)
+	hashedPassword, err := auth.HashPassword(r.Context(), userParams.Password)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, apierror.Internal(err))
+		return
 	}
 
 	createUserParams := database.CreateUserParams{
@@ -210,18 +291,33 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		HashedPassword: hashedPassword,
 	}
 
-	user, err := cfg.db.CreateUser(r.Context(), createUserParams)
+	var user database.User
+	err = cfg.store.WithTx(r.Context(), func(tx store.Store) error {
+		user, err = tx.Users().CreateUser(r.Context(), createUserParams)
+		if err != nil {
+			return err
+		}
+		payload := userCreatedEvent{ID: user.ID, Email: user.Email}
+		return events.Record(r.Context(), tx.Outbox(), events.AggregateUser, user.ID, events.UserCreated, payload)
+	})
+	if errors.Is(err, store.ErrDuplicate) {
+		respondWithError(w, r, errEmailTaken.Wrap(err))
+		return
+	}
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, err)
+		return
 	}
 
 	jsonUser, err := json.Marshal(user)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, apierror.Internal(err))
+		return
 	}
 	userData := userData{}
 	if err := json.Unmarshal(jsonUser, &userData); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, apierror.Internal(err))
+		return
 	}
 
 	respondWithJSON(w, http.StatusCreated, userData)